@@ -1,6 +1,9 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"log/slog"
 	"os"
@@ -8,9 +11,16 @@ import (
 	"github.com/abdulazizax/udevslab-lesson3/internal/config"
 	"github.com/abdulazizax/udevslab-lesson3/internal/http/app"
 	"github.com/abdulazizax/udevslab-lesson3/internal/http/handler"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/rates"
 	"github.com/abdulazizax/udevslab-lesson3/internal/service"
 	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
 	mongo "github.com/abdulazizax/udevslab-lesson3/internal/storage/mongodb"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/mongodb/migrations"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/postgres"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/sqlite"
+	"github.com/abdulazizax/udevslab-lesson3/internal/tax"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
 )
 
 func Run() error {
@@ -32,18 +42,34 @@ func Run() error {
 	// Initialize structured logger with JSON format
 	logger := slog.New(slog.NewJSONHandler(logFile, nil))
 
-	// Initialize MongoDB connection
-	db, err := mongo.ConnectDB(cfg)
+	// Initialize storage layer with the configured driver
+	storage, err := newStorage(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Load the exchange rates prices are converted with
+	exchangeRates, err := newExchangeRates(cfg, logger)
 	if err != nil {
-		logger.Error("Error while connecting to MongoDB", slog.String("err", err.Error()))
 		return err
 	}
 
-	// Initialize storage layer with MongoDB and Redis
-	storage := storage.New(db, cfg, logger)
+	// Load the tax rates orders are taxed with
+	taxRates, err := newTaxRates(cfg, logger)
+	if err != nil {
+		return err
+	}
 
 	// Initialize service layer
-	service := service.NewService(logger, storage)
+	service := service.NewService(logger, storage, exchangeRates, taxRates, cfg)
+
+	// Make sure there is an admin who can hand out the staff and admin roles
+	if cfg.Auth.AdminEmail != "" {
+		if err := service.UserService.EnsureAdmin(context.Background(), cfg.Auth.AdminEmail, cfg.Auth.AdminPassword); err != nil {
+			logger.Error("Error while creating the admin account", slog.String("err", err.Error()))
+			return err
+		}
+	}
 
 	// Initialize HTTP handler
 	handler := handler.NewHandler(logger, service, cfg)
@@ -51,3 +77,137 @@ func Run() error {
 	// Start the HTTP server
 	return app.Run(handler, logger, cfg)
 }
+
+// newStorage connects the storage driver selected in the configuration
+func newStorage(cfg *config.Config, logger *slog.Logger) (storage.StorageI, error) {
+	switch cfg.Storage.Driver {
+	case config.StorageMemory:
+		logger.Warn("Using the in-memory storage, all data is lost on restart")
+		return storage.NewMemory(cfg, logger), nil
+
+	case config.StoragePostgres:
+		// Connecting also brings the schema up to date
+		db, err := postgres.ConnectDB(cfg)
+		if err != nil {
+			logger.Error("Error while connecting to PostgreSQL", slog.String("err", err.Error()))
+			return nil, err
+		}
+		return storage.NewPostgres(db, cfg, logger), nil
+
+	case config.StorageSQLite:
+		// Creates the database file and its schema on first start
+		db, err := sqlite.ConnectDB(cfg)
+		if err != nil {
+			logger.Error("Error while opening the SQLite database", slog.String("err", err.Error()))
+			return nil, err
+		}
+		return storage.NewSQLite(db, cfg, logger), nil
+	}
+
+	// Initialize MongoDB connection
+	db, err := mongo.ConnectDB(cfg)
+	if err != nil {
+		logger.Error("Error while connecting to MongoDB", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	// Orders, carts and promotions change several documents at once, which only transactions keep consistent
+	if err := checkTransactions(context.Background(), db, cfg, logger); err != nil {
+		return nil, err
+	}
+
+	// The repositories expect the documents in the shape of the latest migration
+	if err := checkMigrations(context.Background(), db, logger); err != nil {
+		return nil, err
+	}
+
+	// Install the collection validators and build the indexes, including the TTL index removing abandoned carts
+	if err := mongo.EnsureSchema(context.Background(), db, logger); err != nil {
+		logger.Error("Error while preparing the MongoDB collections", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	return storage.New(db, cfg, logger), nil
+}
+
+// checkTransactions refuses to start on a standalone MongoDB server, which can't run transactions,
+// unless MONGO_ALLOW_NO_TX opts in to running the units of work without them.
+func checkTransactions(ctx context.Context, db *mongodriver.Database, cfg *config.Config, logger *slog.Logger) error {
+	supported, err := mongo.SupportsTransactions(ctx, db)
+	if err != nil {
+		logger.Error("Error while checking MongoDB transaction support", slog.String("err", err.Error()))
+		return err
+	}
+	if supported || cfg.MongoDb.AllowNoTransactions {
+		return nil
+	}
+
+	logger.Error("MongoDB is a standalone server without transactions, run it as a replica set or set MONGO_ALLOW_NO_TX")
+	return errors.New("MongoDB doesn't support transactions, run it as a replica set or set MONGO_ALLOW_NO_TX=true to do without them")
+}
+
+// checkMigrations refuses to start on a MongoDB database with pending migrations, see the migrate command.
+// A fresh database starts out at the latest migration.
+func checkMigrations(ctx context.Context, db *mongodriver.Database, logger *slog.Logger) error {
+	migrator := migrations.New(db, logger)
+	if _, err := migrator.Baseline(ctx); err != nil {
+		logger.Error("Error while checking the MongoDB migrations", slog.String("err", err.Error()))
+		return err
+	}
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		logger.Error("Error while checking the MongoDB migrations", slog.String("err", err.Error()))
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	versions := make([]int, len(pending))
+	for i, migration := range pending {
+		versions[i] = migration.Version
+	}
+	logger.Error("MongoDB migrations are pending, run the migrate command first", slog.Any("versions", versions))
+	return fmt.Errorf("%d MongoDB migrations are pending, apply them with the migrate command first", len(pending))
+}
+
+// newExchangeRates loads the configured exchange rates file, watching it for changes when a reload interval is set
+func newExchangeRates(cfg *config.Config, logger *slog.Logger) (rates.ExchangeRateProvider, error) {
+	if cfg.Exchange.RatesFile == "" {
+		logger.Warn("No exchange rates file configured, prices can't be converted between currencies")
+		return rates.NewStatic(models.DefaultCurrency, nil), nil
+	}
+
+	if cfg.Exchange.ReloadInterval > 0 {
+		// The rates are watched for as long as the API runs
+		watcher, err := rates.WatchFile(context.Background(), cfg.Exchange.RatesFile, cfg.Exchange.ReloadInterval, logger)
+		if err != nil {
+			logger.Error("Error while loading the exchange rates", slog.String("err", err.Error()))
+			return nil, err
+		}
+		return watcher, nil
+	}
+
+	static, err := rates.LoadFile(cfg.Exchange.RatesFile)
+	if err != nil {
+		logger.Error("Error while loading the exchange rates", slog.String("err", err.Error()))
+		return nil, err
+	}
+	return static, nil
+}
+
+// newTaxRates loads the configured tax rates file, without one orders are not taxed
+func newTaxRates(cfg *config.Config, logger *slog.Logger) (*tax.Rates, error) {
+	if cfg.Tax.RatesFile == "" {
+		logger.Warn("No tax rates file configured, orders are not taxed")
+		return tax.None(), nil
+	}
+
+	rates, err := tax.LoadFile(cfg.Tax.RatesFile)
+	if err != nil {
+		logger.Error("Error while loading the tax rates", slog.String("err", err.Error()))
+		return nil, err
+	}
+	return rates, nil
+}
@@ -2,10 +2,20 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/abdulazizax/udevslab-lesson3/cmd/api"
+	"github.com/abdulazizax/udevslab-lesson3/cmd/migrate"
 )
 
 func main() {
+	// "migrate" manages the MongoDB migrations instead of starting the API
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Run(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	log.Fatal(api.Run())
 }
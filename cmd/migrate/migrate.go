@@ -0,0 +1,146 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	mongo "github.com/abdulazizax/udevslab-lesson3/internal/storage/mongodb"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/mongodb/migrations"
+)
+
+const usage = `Usage: migrate <command> [flags]
+
+Manages the migrations of the MongoDB collections.
+
+Commands:
+  status   list the migrations and whether they have been applied
+  up       apply all pending migrations
+  down     revert the last applied migrations, one unless -steps says otherwise
+
+Flags:
+`
+
+// Run runs the migrate subcommand with the arguments following "migrate"
+func Run(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only list the migrations up or down would run")
+	steps := flags.Int("steps", 1, "number of migrations down reverts")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	// Flags may come before or after the command
+	if err := flags.Parse(args); err != nil {
+		return ignoreHelp(err)
+	}
+	command := flags.Arg(0)
+	if command != "" {
+		if err := flags.Parse(flags.Args()[1:]); err != nil {
+			return ignoreHelp(err)
+		}
+	}
+	switch {
+	case command != "status" && command != "up" && command != "down":
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	case flags.NArg() > 0:
+		flags.Usage()
+		return fmt.Errorf("unexpected arguments %v", flags.Args())
+	}
+	if *steps < 1 {
+		return fmt.Errorf("-steps must be at least 1")
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		return err
+	}
+	if cfg.Storage.Driver != config.StorageMongoDB {
+		return fmt.Errorf("migrations only apply to the %s storage driver, the %s driver prepares its schema on startup", config.StorageMongoDB, cfg.Storage.Driver)
+	}
+
+	// Progress goes to the terminal rather than the application log
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	db, err := mongo.ConnectDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Client().Disconnect(context.Background())
+
+	ctx := context.Background()
+	migrator := migrations.New(db, logger)
+
+	switch command {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(os.Stdout, statuses)
+		return nil
+
+	case "up":
+		applied, err := migrator.Up(ctx, *dryRun)
+		if err != nil {
+			return err
+		}
+		printMigrations(os.Stdout, applied, "apply", "Applied", *dryRun)
+		return nil
+
+	default: // down
+		reverted, err := migrator.Down(ctx, *steps, *dryRun)
+		if err != nil {
+			return err
+		}
+		printMigrations(os.Stdout, reverted, "revert", "Reverted", *dryRun)
+		return nil
+	}
+}
+
+// ignoreHelp treats asking for the usage as success
+func ignoreHelp(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+func printStatus(w io.Writer, statuses []migrations.Status) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tAPPLIED\tDESCRIPTION")
+	for _, s := range statuses {
+		applied := "pending"
+		if s.Applied {
+			applied = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, applied, s.Description)
+	}
+	tw.Flush()
+}
+
+// printMigrations lists the migrations up or down went through, or would go through in a dry run
+func printMigrations(w io.Writer, list []migrations.Migration, verb, done string, dryRun bool) {
+	if len(list) == 0 {
+		fmt.Fprintf(w, "No migrations to %s\n", verb)
+		return
+	}
+
+	if dryRun {
+		fmt.Fprintf(w, "Dry run, would %s:\n", verb)
+	} else {
+		fmt.Fprintf(w, "%s:\n", done)
+	}
+	for _, m := range list {
+		fmt.Fprintf(w, "  %d  %s\n", m.Version, m.Description)
+	}
+}
@@ -0,0 +1,135 @@
+// Package errs defines the domain errors shared by the storage, service and HTTP layers.
+//
+// Every error is classified by one of the sentinel kinds below, so callers branch on
+// errors.Is(err, errs.ErrNotFound) instead of comparing messages.
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error kinds
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidID    = errors.New("invalid id")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+
+	// ErrPreconditionFailed reports a conditional write whose expected version is no longer the stored one
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Error is a domain error of a given kind with a message that is safe to show to API clients
+type Error struct {
+	Kind    error
+	Message string
+	Err     error        // Underlying cause, if any
+	Fields  []FieldError // Invalid fields of an ErrValidation error, if it was caused by any
+}
+
+// FieldError tells which rule a single field of a request broke
+type FieldError struct {
+	Field   string // JSON path of the field, such as items[0].quantity
+	Rule    string // Name of the rule, such as required or gt
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap exposes both the kind and the cause to errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// NotFound creates an ErrNotFound error
+func NotFound(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+// InvalidID creates an ErrInvalidID error wrapping the parse failure
+func InvalidID(message string, err error) error {
+	return &Error{Kind: ErrInvalidID, Message: message, Err: err}
+}
+
+// Conflict creates an ErrConflict error
+func Conflict(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+// Validation creates an ErrValidation error
+func Validation(message string) error {
+	return &Error{Kind: ErrValidation, Message: message}
+}
+
+// InvalidFields creates an ErrValidation error listing the fields that broke their rules,
+// its message joins the messages of the fields
+func InvalidFields(fields []FieldError) error {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	return &Error{Kind: ErrValidation, Message: strings.Join(messages, "; "), Fields: fields}
+}
+
+// FieldErrors returns the invalid fields listed by err, nil when it doesn't list any
+func FieldErrors(err error) []FieldError {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+	return nil
+}
+
+// Unauthorized creates an ErrUnauthorized error
+func Unauthorized(message string) error {
+	return &Error{Kind: ErrUnauthorized, Message: message}
+}
+
+// Forbidden creates an ErrForbidden error
+func Forbidden(message string) error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+// PreconditionFailed creates an ErrPreconditionFailed error
+func PreconditionFailed(message string) error {
+	return &Error{Kind: ErrPreconditionFailed, Message: message}
+}
+
+// InsufficientStockError is returned when a product does not have enough units in stock to reserve
+type InsufficientStockError struct {
+	ProductID string
+	Requested int
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock for product %s: requested %d", e.ProductID, e.Requested)
+}
+
+// Unwrap classifies running out of stock as a conflict
+func (e *InsufficientStockError) Unwrap() error {
+	return ErrConflict
+}
+
+// InvalidTransitionError is returned when an order can't move from its current status to the requested one
+type InvalidTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("invalid order status transition from %q to %q", e.From, e.To)
+}
+
+// Unwrap classifies a forbidden transition as a conflict with the current state of the order
+func (e *InvalidTransitionError) Unwrap() error {
+	return ErrConflict
+}
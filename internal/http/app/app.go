@@ -28,6 +28,7 @@ import (
 	"github.com/abdulazizax/udevslab-lesson3/internal/config"
 	_ "github.com/abdulazizax/udevslab-lesson3/internal/http/app/docs"
 	"github.com/abdulazizax/udevslab-lesson3/internal/http/handler"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
@@ -44,6 +45,7 @@ func Run(handler *handler.Handler, logger *slog.Logger, config *config.Config) e
 	corsConfig.AllowHeaders = []string{"*"}
 	corsConfig.AllowBrowserExtensions = true
 	corsConfig.AllowMethods = []string{"*"}
+	corsConfig.ExposeHeaders = []string{"ETag"} // Read by clients to send If-Match with their updates
 	router.Use(cors.New(corsConfig))
 
 	url := ginSwagger.URL("/swagger/doc.json")
@@ -52,13 +54,15 @@ func Run(handler *handler.Handler, logger *slog.Logger, config *config.Config) e
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
+	// Permission middlewares for the route groups below
+	authenticated := handler.AuthHandler.Authenticate
+	staffOnly := handler.AuthHandler.RequireRoles(models.RoleAdmin, models.RoleStaff)
+	adminOnly := handler.AuthHandler.RequireRoles(models.RoleAdmin)
+
 	productRoutes := router.Group("/products")
 	{
-		productRoutes.POST("", handler.ProductHandler.CreateProduct)
 		productRoutes.GET("", handler.ProductHandler.ListProducts)
 		productRoutes.GET(":id", handler.ProductHandler.GetProduct)
-		productRoutes.PUT(":id", handler.ProductHandler.UpdateProduct)
-		productRoutes.DELETE(":id", handler.ProductHandler.DeleteProduct)
 
 		searchProductRoutes := productRoutes.Group("/search")
 		{
@@ -66,16 +70,78 @@ func Run(handler *handler.Handler, logger *slog.Logger, config *config.Config) e
 			searchProductRoutes.GET("/price", handler.ProductHandler.ExactSearchProductsByPrice)
 			searchProductRoutes.GET("/price-range", handler.ProductHandler.SearchProductsByPriceRange)
 		}
+
+		manageProductRoutes := productRoutes.Group("", authenticated, staffOnly)
+		{
+			manageProductRoutes.POST("", handler.ProductHandler.CreateProduct)
+			manageProductRoutes.PUT(":id", handler.ProductHandler.UpdateProduct)
+			manageProductRoutes.PATCH(":id", handler.ProductHandler.PatchProduct)
+			manageProductRoutes.DELETE(":id", handler.ProductHandler.DeleteProduct)
+		}
+	}
+
+	authRoutes := router.Group("/auth")
+	{
+		authRoutes.POST("/login", handler.AuthHandler.Login)
+		authRoutes.POST("/refresh", handler.AuthHandler.Refresh)
 	}
 
-	orderRoutes := router.Group("/orders")
+	// Customers may place orders and list, read and cancel their own, ownership is checked by OrderService
+	orderRoutes := router.Group("/orders", authenticated)
 	{
 		orderRoutes.POST("", handler.OrderHandler.CreateOrder)
 		orderRoutes.GET("", handler.OrderHandler.ListOrders)
 		orderRoutes.GET(":id", handler.OrderHandler.GetOrder)
-		orderRoutes.PUT(":id", handler.OrderHandler.UpdateOrder)
-		orderRoutes.DELETE(":id", handler.OrderHandler.DeleteOrder)
-		orderRoutes.GET("/range", handler.OrderHandler.ListOrdersByDateRange)
+		orderRoutes.POST(":id/transitions", handler.OrderHandler.TransitionOrder)
+
+		manageOrderRoutes := orderRoutes.Group("", staffOnly)
+		{
+			manageOrderRoutes.PUT(":id", handler.OrderHandler.UpdateOrder)
+			manageOrderRoutes.PATCH(":id", handler.OrderHandler.PatchOrder)
+			manageOrderRoutes.DELETE(":id", handler.OrderHandler.DeleteOrder)
+			manageOrderRoutes.GET("/range", handler.OrderHandler.ListOrdersByDateRange)
+			manageOrderRoutes.GET("/with-users", handler.OrderHandler.ListOrdersWithUsers)
+		}
+	}
+
+	// Every user has a single cart, identified by the access token
+	cartRoutes := router.Group("/cart", authenticated)
+	{
+		cartRoutes.GET("", handler.CartHandler.GetCart)
+		cartRoutes.DELETE("", handler.CartHandler.ClearCart)
+		cartRoutes.POST("/items", handler.CartHandler.AddItem)
+		cartRoutes.PUT("/items/:product_id", handler.CartHandler.UpdateItem)
+		cartRoutes.DELETE("/items/:product_id", handler.CartHandler.RemoveItem)
+		cartRoutes.POST("/checkout", handler.CartHandler.Checkout)
+	}
+
+	// Staff manage the promotions, customers redeem them with a coupon code when they place an order
+	promotionRoutes := router.Group("/promotions", authenticated, staffOnly)
+	{
+		promotionRoutes.POST("", handler.PromotionHandler.CreatePromotion)
+		promotionRoutes.GET("", handler.PromotionHandler.ListPromotions)
+		promotionRoutes.GET(":id", handler.PromotionHandler.GetPromotion)
+		promotionRoutes.DELETE(":id", handler.PromotionHandler.DeletePromotion)
+	}
+
+	userRoutes := router.Group("/users")
+	{
+		userRoutes.POST("", handler.UserHandler.CreateUser)
+
+		protectedUserRoutes := userRoutes.Group("", authenticated)
+		{
+			protectedUserRoutes.GET("", staffOnly, handler.UserHandler.ListUsers)
+			protectedUserRoutes.GET(":id", handler.UserHandler.GetUser)
+			protectedUserRoutes.PUT(":id", adminOnly, handler.UserHandler.UpdateUser)
+			protectedUserRoutes.DELETE(":id", adminOnly, handler.UserHandler.DeleteUser)
+		}
+	}
+
+	reportRoutes := router.Group("/reports", authenticated, staffOnly)
+	{
+		reportRoutes.GET("/summary", handler.ReportHandler.GetReport)
+		reportRoutes.GET("/top-products", handler.ReportHandler.GetTopProducts)
+		reportRoutes.GET("/daily", handler.ReportHandler.GetDailyOrderAggregates)
 	}
 
 	return router.Run(config.Server.Port)
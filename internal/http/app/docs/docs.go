@@ -24,40 +24,47 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/orders": {
-            "get": {
-                "description": "Retrieve a list of all orders in the database with pagination",
+        "/auth/login": {
+            "post": {
+                "description": "Exchange email and password for an access and a refresh token",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Auth"
                 ],
-                "summary": "List all orders",
+                "summary": "Log in",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Page size",
-                        "name": "page_size",
-                        "in": "query"
+                        "description": "User credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.LoginRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of orders",
+                        "description": "Issued tokens",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Order"
-                            }
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "500": {
@@ -67,9 +74,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/auth/refresh": {
             "post": {
-                "description": "Create a new order and return the created order's ID",
+                "description": "Exchange a refresh token for a new access and refresh token",
                 "consumes": [
                     "application/json"
                 ],
@@ -77,35 +86,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Auth"
                 ],
-                "summary": "Create a new order",
+                "summary": "Refresh tokens",
                 "parameters": [
                     {
-                        "description": "Order information",
-                        "name": "order",
+                        "description": "Refresh token",
+                        "name": "token",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderCreate"
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.RefreshRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Order ID",
+                    "200": {
+                        "description": "Issued tokens",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.TokenPair"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
@@ -113,68 +128,62 @@ const docTemplate = `{
                 }
             }
         },
-        "/orders/range": {
+        "/cart": {
             "get": {
-                "description": "Retrieve a paginated list of orders filtered by a specific date range and sorted by the creation date in ascending or descending order.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the cart of the authenticated user with the current product prices and availability",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Cart"
                 ],
-                "summary": "List orders within a specific date range",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Sort order (-1: descending, 1: ascending)",
-                        "name": "order",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number (1-based index)",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Number of orders per page",
-                        "name": "page_size",
-                        "in": "query"
+                "summary": "Get the cart",
+                "responses": {
+                    "200": {
+                        "description": "Cart",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Cart"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "default": "2000-01-01",
-                        "description": "Start date in format (YYYY-MM-DD)",
-                        "name": "start_date",
-                        "in": "query",
-                        "required": true
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
                     },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
                     {
-                        "type": "string",
-                        "default": "2026-01-01",
-                        "description": "End date in format (YYYY-MM-DD)",
-                        "name": "end_date",
-                        "in": "query",
-                        "required": true
+                        "BearerAuth": []
                     }
                 ],
+                "description": "Remove every product from the cart of the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Cart"
+                ],
+                "summary": "Empty the cart",
                 "responses": {
-                    "200": {
-                        "description": "Paginated list of orders",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Order"
-                            }
-                        }
+                    "204": {
+                        "description": "Cart emptied"
                     },
-                    "400": {
-                        "description": "Bad request (invalid parameters)",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
@@ -188,54 +197,88 @@ const docTemplate = `{
                 }
             }
         },
-        "/orders/{order_id}": {
-            "get": {
-                "description": "Fetch a single order by its ID",
+        "/cart/checkout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Turn the cart into a pending order at the current prices, reserving the stock of every product, and empty the cart.\nThe body is optional, its coupon code applies a promotion to the order and its region picks the tax rates.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Cart"
                 ],
-                "summary": "Get an order by ID",
+                "summary": "Check out the cart",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Order ID",
-                        "name": "order_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Coupon code and tax region",
+                        "name": "checkout",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.CartCheckout"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Order details",
+                    "201": {
+                        "description": "Order ID",
                         "schema": {
-                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Order"
+                            "$ref": "#/definitions/gin.H"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Cart is empty",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "404": {
-                        "description": "Order Not Found",
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Insufficient stock or promotion used up",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid coupon code or region",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Update the details of an existing order",
+            }
+        },
+        "/cart/items": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add units of a product to the cart, on top of the units already in it. The product must have enough units in stock",
                 "consumes": [
                     "application/json"
                 ],
@@ -243,110 +286,219 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Cart"
                 ],
-                "summary": "Update an order",
+                "summary": "Add a product to the cart",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Order ID",
-                        "name": "order_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Order fields to update",
-                        "name": "updates",
+                        "description": "Product and quantity",
+                        "name": "item",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderUpdate"
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.CartItemCreate"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Order updated successfully",
+                        "description": "Updated cart",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Cart"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "404": {
-                        "description": "Order Not Found",
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Insufficient stock",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete an order by its ID",
+            }
+        },
+        "/cart/items/{product_id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the number of units of a product in the cart. The product must have enough units in stock",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Cart"
                 ],
-                "summary": "Delete an order",
+                "summary": "Change the quantity of a product in the cart",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Order ID",
-                        "name": "order_id",
+                        "description": "Product ID",
+                        "name": "product_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "New quantity",
+                        "name": "item",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.CartItemUpdate"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Order deleted successfully",
+                        "description": "Updated cart",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Cart"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "404": {
-                        "description": "Order Not Found",
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Insufficient stock",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     }
                 }
-            }
-        },
-        "/products": {
-            "get": {
-                "description": "Retrieve a list of all products in the database",
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Take a product out of the cart of the authenticated user",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "Cart"
                 ],
-                "summary": "List all products",
+                "summary": "Remove a product from the cart",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated cart",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Cart"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Product is not in the cart",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a list of orders with pagination. Staff see every order, customers only their own",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "List all orders",
                 "parameters": [
                     {
                         "type": "integer",
@@ -361,16 +513,25 @@ const docTemplate = `{
                         "description": "Page size",
                         "name": "page_size",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from next_cursor or prev_cursor of another page, can't be combined with page",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of products",
+                        "description": "List of orders",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Product"
-                            }
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_Order"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "500": {
@@ -382,7 +543,12 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Creates a new product in the database",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new order with one or more lines for the authenticated user and return the created order's ID. The current product prices are captured on every line and the stock of all lines is reserved, or none of it when a product runs short\nThe order is taxed at the rates of its region, the default tax region unless one is given. Exclusive regions add the tax to the total, inclusive ones take it out of the prices",
                 "consumes": [
                     "application/json"
                 ],
@@ -390,29 +556,59 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "Orders"
                 ],
-                "summary": "Create a new product",
+                "summary": "Create a new order",
                 "parameters": [
                     {
-                        "description": "Product information",
-                        "name": "product",
+                        "description": "Order information",
+                        "name": "order",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.ProductCreate"
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderCreate"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Product created successfully\"// @Failure 400 {object} models.Error \"Bad request",
+                        "description": "Order ID",
                         "schema": {
                             "$ref": "#/definitions/gin.H"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "User Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Insufficient stock",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
@@ -420,103 +616,88 @@ const docTemplate = `{
                 }
             }
         },
-        "/products/search": {
+        "/orders/range": {
             "get": {
-                "description": "Search products by partial name with pagination",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a paginated list of orders filtered by a specific date range and sorted by the creation date in ascending or descending order.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "Orders"
                 ],
-                "summary": "Search products by name",
+                "summary": "List orders within a specific date range",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Search keyword",
-                        "name": "name",
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Sort order (-1: descending, 1: ascending)",
+                        "name": "order",
                         "in": "query",
                         "required": true
                     },
                     {
                         "type": "integer",
                         "default": 1,
-                        "description": "Page number",
+                        "description": "Page number (1-based index)",
                         "name": "page",
                         "in": "query"
                     },
                     {
                         "type": "integer",
                         "default": 10,
-                        "description": "Page size",
+                        "description": "Number of orders per page",
                         "name": "page_size",
                         "in": "query"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "List of products",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Product"
-                            }
-                        }
                     },
-                    "500": {
-                        "description": "Internal server error",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
-                        }
-                    }
-                }
-            }
-        },
-        "/products/search/price": {
-            "get": {
-                "description": "Retrieve products based on an exact price match with pagination",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "products"
-                ],
-                "summary": "Search products by exact price",
-                "parameters": [
                     {
-                        "type": "number",
-                        "description": "Price to search for",
-                        "name": "price",
+                        "type": "string",
+                        "default": "2000-01-01",
+                        "description": "Start date in format (YYYY-MM-DD)",
+                        "name": "start_date",
                         "in": "query",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
+                        "type": "string",
+                        "default": "2026-01-01",
+                        "description": "End date in format (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Limit of products per page",
-                        "name": "limit",
+                        "type": "string",
+                        "description": "Cursor from next_cursor or prev_cursor of another page, can't be combined with page",
+                        "name": "cursor",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of products",
+                        "description": "Paginated list of orders",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Product"
-                            }
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_Order"
                         }
                     },
                     "400": {
-                        "description": "Invalid request parameters",
+                        "description": "Bad request (invalid parameters)",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
@@ -530,39 +711,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/products/search/price-range": {
+        "/orders/with-users": {
             "get": {
-                "description": "Retrieve products based on a price range with pagination",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a paginated list of orders, newest first, together with the name of the user who placed each order",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "Orders"
                 ],
-                "summary": "Search products by price range",
+                "summary": "List orders with their users",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Order (-1: decreasing, 1: increasing)",
-                        "name": "order",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "number",
-                        "description": "Minimum price",
-                        "name": "min_price",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "number",
-                        "description": "Maximum price",
-                        "name": "max_price",
-                        "in": "query",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "default": 1,
@@ -573,23 +737,32 @@ const docTemplate = `{
                     {
                         "type": "integer",
                         "default": 10,
-                        "description": "Limit of products per page",
-                        "name": "limit",
+                        "description": "Page size",
+                        "name": "page_size",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of products",
+                        "description": "List of orders with users",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Product"
-                            }
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_OrderWithUser"
                         }
                     },
                     "400": {
-                        "description": "Invalid request parameters",
+                        "description": "Bad request (invalid parameters)",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
@@ -603,20 +776,25 @@ const docTemplate = `{
                 }
             }
         },
-        "/products/{id}": {
+        "/orders/{id}": {
             "get": {
-                "description": "Retrieve a product by its ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Fetch a single order by its ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "Orders"
                 ],
-                "summary": "Get a product by ID",
+                "summary": "Get an order by ID",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Product ID",
+                        "description": "Order ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -624,19 +802,43 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Product found",
+                        "description": "Order details",
                         "schema": {
-                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Product"
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the order, send it back in If-Match to update or delete it"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Product not found",
-                        "schema": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Order belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Order Not Found",
+                        "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
@@ -644,7 +846,12 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Update product details by its ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the lines of a pending order. Products already on the order keep their captured unit price",
                 "consumes": [
                     "application/json"
                 ],
@@ -652,48 +859,84 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "Orders"
                 ],
-                "summary": "Update a product by ID",
+                "summary": "Update an order",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Product ID",
+                        "description": "Order ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated product details",
-                        "name": "product",
+                        "description": "New order lines",
+                        "name": "updates",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.ProductUpdate"
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderUpdate"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the order as it was read, the update fails when the order changed since",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Product updated successfully",
+                        "description": "Order updated successfully",
                         "schema": {
                             "$ref": "#/definitions/gin.H"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "404": {
-                        "description": "Product not found",
+                        "description": "Order Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Insufficient stock or order is no longer pending",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "412": {
+                        "description": "Order changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
@@ -701,119 +944,2278 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete a product from the database by its ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete an order by its ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "Orders"
                 ],
-                "summary": "Delete a product by ID",
+                "summary": "Delete an order",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Product ID",
+                        "description": "Order ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the order as it was read, the delete fails when the order changed since",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Product deleted successfully",
+                    "200": {
+                        "description": "Order deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Order Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "412": {
+                        "description": "Order changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change a pending order with a JSON Merge Patch (RFC 7396). Like any array in a merge patch, items replaces all the lines. The order is only repriced when its products or quantities change",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Patch an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderPatch"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the order as it was read, the patch fails when the order changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order updated successfully",
                         "schema": {
                             "$ref": "#/definitions/gin.H"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
                     "404": {
-                        "description": "Product not found",
+                        "description": "Order Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Insufficient stock or order is no longer pending",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "412": {
+                        "description": "Order changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "415": {
+                        "description": "Body is not a merge patch",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "gin.H": {
-            "type": "object",
-            "additionalProperties": {}
         },
-        "github_com_abdulazizax_udevslab-lesson3_internal_models.Error": {
-            "type": "object",
-            "properties": {
-                "message": {
+        "/orders/{id}/transitions": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Move an order to a new status. Allowed transitions: pending -\u003e paid|cancelled, paid -\u003e shipped|cancelled|refunded, shipped -\u003e delivered, delivered -\u003e refunded. Customers may only cancel their own orders",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Change the status of an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target status",
+                        "name": "transition",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderTransition"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated order",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the order"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Transition not permitted for the caller",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Order Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Transition not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/products": {
+            "get": {
+                "description": "Retrieve a list of all products in the database.\nWith a currency every price is converted to it at the current exchange rate, rounded to the nearest minor unit,\nand every product is listed in that currency. The stored prices stay in their own currency.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "List all products",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from next_cursor or prev_cursor of another page, can't be combined with page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "UZS",
+                        "description": "ISO 4217 code of the currency to show the prices in",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of products",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or no exchange rate to the currency",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new product in the database\nA product has one price in any supported currency, it is converted to other currencies with ?currency= when read.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Create a new product",
+                "parameters": [
+                    {
+                        "description": "Product information",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.ProductCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Product created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/search": {
+            "get": {
+                "description": "Search products by partial name with pagination.\nWith a currency every price is converted to it at the current exchange rate, rounded to the nearest minor unit.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Search products by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search keyword",
+                        "name": "name",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from next_cursor or prev_cursor of another page, can't be combined with page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "UZS",
+                        "description": "ISO 4217 code of the currency to show the prices in",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of products",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or no exchange rate to the currency",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/search/price": {
+            "get": {
+                "description": "Retrieve products based on an exact price match with pagination.\nOnly the products priced in the currency are searched, prices in different currencies are never compared.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Search products by exact price",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Price to search for, with at most 2 decimal places",
+                        "name": "price",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "USD",
+                        "description": "ISO 4217 code of the currency the price is in",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Alias of page_size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from next_cursor or prev_cursor of another page, can't be combined with page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of products",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "No exchange rate for the currency",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/search/price-range": {
+            "get": {
+                "description": "Retrieve products based on a price range with pagination.\nOnly the products priced in the currency are searched, prices in different currencies are never compared.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Search products by price range",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Order (-1: decreasing, 1: increasing)",
+                        "name": "order",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum price, with at most 2 decimal places",
+                        "name": "min_price",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum price, with at most 2 decimal places",
+                        "name": "max_price",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "USD",
+                        "description": "ISO 4217 code of the currency the prices are in",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Alias of page_size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from next_cursor or prev_cursor of another page, can't be combined with page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of products",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "No exchange rate for the currency",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}": {
+            "get": {
+                "description": "Retrieve a product by its ID.\nWith a currency the price is converted to it at the current exchange rate, rounded to the nearest minor unit.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get a product by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "UZS",
+                        "description": "ISO 4217 code of the currency to show the price in",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Product"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the product, send it back in If-Match to update or delete it"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID or no exchange rate to the currency",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update product details by its ID.\nOrders taking stock don't change the ETag, the stock given here replaces whatever they left.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Update a product by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated product details",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.ProductUpdate"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the product as it was read, the update fails when the product changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "412": {
+                        "description": "Product changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a product from the database by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Delete a product by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the product as it was read, the delete fails when the product changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Product deleted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "412": {
+                        "description": "Product changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change some fields of a product with a JSON Merge Patch (RFC 7396), the fields left out keep their values. A null description clears it, the other fields can't be removed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Patch a product by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.ProductPatch"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the product as it was read, the patch fails when the product changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "412": {
+                        "description": "Product changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "415": {
+                        "description": "Body is not a merge patch",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/promotions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the promotions with pagination, oldest first, with the number of orders currently using each of them",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Promotions"
+                ],
+                "summary": "List promotions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from next_cursor or prev_cursor of another page, can't be combined with page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of promotions",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_Promotion"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a promotion redeemable with its code in any letter case. A percentage promotion takes percent off the subtotal,\na fixed_amount one takes amount off it and a buy_x_get_y one gives freeQuantity of every buyQuantity + freeQuantity units of productId.\nAny of them may require a minimum order value, be limited to a time window and to a number of uses overall and per user.\nAmounts are in the currency of the promotion, USD unless given, and converted for orders in other currencies.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Promotions"
+                ],
+                "summary": "Create a promotion",
+                "parameters": [
+                    {
+                        "description": "Promotion",
+                        "name": "promotion",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.PromotionCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Promotion ID",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Promotion code already exists",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/promotions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Promotions"
+                ],
+                "summary": "Get a promotion by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Promotion ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Promotion found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Promotion"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid promotion ID",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Promotion not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a promotion, its code can't be redeemed anymore and the orders it was applied to keep their discounts",
+                "tags": [
+                    "Promotions"
+                ],
+                "summary": "Delete a promotion by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Promotion ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Promotion deleted"
+                    },
+                    "400": {
+                        "description": "Invalid promotion ID",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Promotion not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/daily": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the number of orders and the revenue for every day and currency within a date range, gross and net of the tax together with the tax",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reports"
+                ],
+                "summary": "Get daily order statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "2000-01-01",
+                        "description": "Start date in format (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "2026-01-01",
+                        "description": "End date in format (YYYY-MM-DD), inclusive",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Daily order statistics",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderAggregate"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request (invalid parameters)",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/summary": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the total number of products, orders and the revenue per currency, gross and net of the tax together with the tax",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reports"
+                ],
+                "summary": "Get the sales summary",
+                "responses": {
+                    "200": {
+                        "description": "Sales summary",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Report"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/top-products": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the best selling products per currency ranked by units sold and revenue",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reports"
+                ],
+                "summary": "Get the top selling products",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of products to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Top products",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.TopProduct"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request (invalid parameters)",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a list of all users in the database",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List all users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from next_cursor or prev_cursor of another page, can't be combined with page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of users",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a new user in the database, the password must be at least 8 characters long",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User information",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.UserCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already registered",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a user by its ID, customers may only retrieve their own account",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get a user by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.User"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update user details by its ID, only admins may do this and assign roles",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update a user by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated user details",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.UserUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a user from the database by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete a user by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "User deleted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Error"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "gin.H": {
+            "type": "object",
+            "additionalProperties": {}
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.AppliedDiscount": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "SPRING10"
+                },
+                "promotionId": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "percentage"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.Cart": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "description": "Currency of the products in the cart",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "expiresAt": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.CartItem"
+                    }
+                },
+                "total": {
+                    "type": "number"
+                },
+                "updatedAt": {
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.CartCheckout": {
+            "type": "object",
+            "properties": {
+                "couponCode": {
+                    "description": "Code of the promotion to apply to the order",
+                    "type": "string",
+                    "example": "SPRING10"
+                },
+                "region": {
+                    "description": "Region to tax the order in, the default region of the tax rates when left out",
+                    "type": "string",
+                    "example": "UZ"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.CartItem": {
+            "type": "object",
+            "properties": {
+                "inStock": {
+                    "description": "Whether the product still has enough units for this line",
+                    "type": "boolean"
+                },
+                "lineTotal": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "productId": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "unitPrice": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.CartItemCreate": {
+            "type": "object",
+            "required": [
+                "productId"
+            ],
+            "properties": {
+                "productId": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.CartItemUpdate": {
+            "type": "object",
+            "properties": {
+                "quantity": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.Error": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.FieldError"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "items[0].quantity"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "items[0].quantity must be greater than 0"
+                },
+                "rule": {
+                    "type": "string",
+                    "example": "gt"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.Order": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "description": "ISO 4217 code of every price of the order",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "discount": {
+                    "description": "Sum of the discounts",
+                    "type": "number"
+                },
+                "discounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.AppliedDiscount"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderItem"
+                    }
+                },
+                "net": {
+                    "description": "Total less the tax",
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "statusHistory": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.StatusChange"
+                    }
+                },
+                "subtotal": {
+                    "description": "Sum of the line totals",
+                    "type": "number"
+                },
+                "tax": {
+                    "description": "Sum of the taxes",
+                    "type": "number"
+                },
+                "taxMode": {
+                    "description": "Whether the prices hold the tax or get it on top",
+                    "type": "string",
+                    "example": "inclusive"
+                },
+                "taxRegion": {
+                    "description": "Region the order is taxed in, empty without tax rates",
+                    "type": "string",
+                    "example": "UZ"
+                },
+                "taxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.TaxLine"
+                    }
+                },
+                "total": {
+                    "description": "Subtotal less the discount, plus the tax when it is exclusive",
+                    "type": "number"
+                },
+                "updatedAt": {
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Incremented by every write, see AnyVersion",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.OrderAggregate": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "net_revenue": {
+                    "description": "Revenue without the tax",
+                    "type": "number"
+                },
+                "total_orders": {
+                    "type": "integer"
+                },
+                "total_revenue": {
+                    "description": "Gross revenue, with the tax",
+                    "type": "number"
+                },
+                "total_tax": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.OrderCreate": {
+            "type": "object",
+            "properties": {
+                "couponCode": {
+                    "description": "Code of the promotion to apply",
+                    "type": "string",
+                    "example": "SPRING10"
+                },
+                "currency": {
+                    "description": "Currency the order is charged in, the currency of its first product when left out",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "items": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderItemCreate"
+                    }
+                },
+                "region": {
+                    "description": "Region to tax the order in, the default region of the tax rates when left out",
+                    "type": "string",
+                    "example": "UZ"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.OrderItem": {
+            "type": "object",
+            "properties": {
+                "exchangeRate": {
+                    "description": "Converts the product price to the currency of the order",
+                    "type": "number",
+                    "example": 0.0000790483
+                },
+                "lineTotal": {
+                    "type": "number"
+                },
+                "priceCurrency": {
+                    "description": "Currency the product was priced in",
+                    "type": "string",
+                    "example": "UZS"
+                },
+                "productId": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "taxClass": {
+                    "description": "Tax class of the product when the line was added",
+                    "type": "string",
+                    "example": "standard"
+                },
+                "unitPrice": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.OrderItemCreate": {
+            "type": "object",
+            "required": [
+                "productId"
+            ],
+            "properties": {
+                "productId": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.OrderPatch": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderItemCreate"
+                    }
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.OrderTransition": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "paid"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.OrderUpdate": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderItemCreate"
+                    }
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.OrderWithUser": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderItem"
+                    }
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "user_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_Order": {
+            "type": "object",
+            "properties": {
+                "has_next": {
+                    "type": "boolean"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Order"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "prev_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_OrderWithUser": {
+            "type": "object",
+            "properties": {
+                "has_next": {
+                    "type": "boolean"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.OrderWithUser"
+                    }
+                },
+                "next_cursor": {
                     "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "prev_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
                 }
             }
         },
-        "github_com_abdulazizax_udevslab-lesson3_internal_models.Order": {
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_Product": {
             "type": "object",
             "properties": {
-                "createdAt": {
-                    "type": "integer"
+                "has_next": {
+                    "type": "boolean"
                 },
-                "id": {
-                    "type": "string"
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Product"
+                    }
                 },
-                "productId": {
+                "next_cursor": {
                     "type": "string"
                 },
-                "quantity": {
+                "page": {
                     "type": "integer"
                 },
-                "status": {
+                "page_size": {
+                    "type": "integer"
+                },
+                "prev_cursor": {
                     "type": "string"
                 },
                 "total": {
-                    "type": "number"
-                },
-                "updatedAt": {
                     "type": "integer"
-                },
-                "userId": {
-                    "type": "string"
                 }
             }
         },
-        "github_com_abdulazizax_udevslab-lesson3_internal_models.OrderCreate": {
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_Promotion": {
             "type": "object",
             "properties": {
-                "productId": {
+                "has_next": {
+                    "type": "boolean"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Promotion"
+                    }
+                },
+                "next_cursor": {
                     "type": "string"
                 },
-                "quantity": {
+                "page": {
                     "type": "integer"
                 },
-                "status": {
-                    "type": "string"
+                "page_size": {
+                    "type": "integer"
                 },
-                "userId": {
+                "prev_cursor": {
                     "type": "string"
+                },
+                "total": {
+                    "type": "integer"
                 }
             }
         },
-        "github_com_abdulazizax_udevslab-lesson3_internal_models.OrderUpdate": {
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.Page-github_com_abdulazizax_udevslab-lesson3_internal_models_User": {
             "type": "object",
             "properties": {
-                "productId": {
+                "has_next": {
+                    "type": "boolean"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.User"
+                    }
+                },
+                "next_cursor": {
                     "type": "string"
                 },
-                "quantity": {
+                "page": {
                     "type": "integer"
                 },
-                "status": {
-                    "type": "string"
+                "page_size": {
+                    "type": "integer"
                 },
-                "userId": {
+                "prev_cursor": {
                     "type": "string"
+                },
+                "total": {
+                    "type": "integer"
                 }
             }
         },
@@ -823,6 +3225,11 @@ const docTemplate = `{
                 "createdAt": {
                     "type": "integer"
                 },
+                "currency": {
+                    "description": "ISO 4217 code of the price, the only currency it is stored in",
+                    "type": "string",
+                    "example": "USD"
+                },
                 "description": {
                     "type": "string"
                 },
@@ -838,14 +3245,27 @@ const docTemplate = `{
                 "stock": {
                     "type": "integer"
                 },
+                "taxClass": {
+                    "type": "string",
+                    "example": "standard"
+                },
                 "updatedAt": {
                     "type": "integer"
+                },
+                "version": {
+                    "description": "Incremented by every write but the stock movements of orders, see AnyVersion",
+                    "type": "integer"
                 }
             }
         },
         "github_com_abdulazizax_udevslab-lesson3_internal_models.ProductCreate": {
             "type": "object",
             "properties": {
+                "currency": {
+                    "description": "DefaultCurrency when left out",
+                    "type": "string",
+                    "example": "USD"
+                },
                 "description": {
                     "type": "string"
                 },
@@ -853,16 +3273,55 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "price": {
-                    "type": "number"
+                    "type": "number",
+                    "minimum": 0
                 },
                 "stock": {
-                    "type": "integer"
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "taxClass": {
+                    "description": "DefaultTaxClass when left out",
+                    "type": "string",
+                    "example": "standard"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.ProductPatch": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "stock": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "taxClass": {
+                    "type": "string",
+                    "example": "standard"
                 }
             }
         },
         "github_com_abdulazizax_udevslab-lesson3_internal_models.ProductUpdate": {
             "type": "object",
             "properties": {
+                "currency": {
+                    "description": "Left as it is when left out",
+                    "type": "string",
+                    "example": "USD"
+                },
                 "description": {
                     "type": "string"
                 },
@@ -870,12 +3329,352 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "price": {
-                    "type": "number"
+                    "type": "number",
+                    "minimum": 0
                 },
                 "stock": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "taxClass": {
+                    "description": "Left as it is when left out",
+                    "type": "string",
+                    "example": "standard"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.Promotion": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount off, for fixed_amount promotions",
+                    "type": "number"
+                },
+                "buyQuantity": {
+                    "description": "Units to pay for, for buy_x_get_y promotions",
+                    "type": "integer",
+                    "example": 2
+                },
+                "code": {
+                    "description": "Unique, kept in upper case",
+                    "type": "string",
+                    "example": "SPRING10"
+                },
+                "createdAt": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "description": "ISO 4217 code of the amounts",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "endsAt": {
+                    "description": "Not redeemable from then on, if set",
+                    "type": "string"
+                },
+                "freeQuantity": {
+                    "description": "Units given on top, for buy_x_get_y promotions",
+                    "type": "integer",
+                    "example": 1
+                },
+                "id": {
+                    "type": "string"
+                },
+                "minOrderValue": {
+                    "description": "Subtotal the order must reach",
+                    "type": "number"
+                },
+                "perUserLimit": {
+                    "description": "Uses by a single user, 0 for no limit",
+                    "type": "integer"
+                },
+                "percent": {
+                    "description": "Percentage off, for percentage promotions",
+                    "type": "integer",
+                    "example": 10
+                },
+                "productId": {
+                    "description": "Product of a buy_x_get_y promotion",
+                    "type": "string"
+                },
+                "startsAt": {
+                    "description": "Not redeemable before, if set",
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "percentage"
+                },
+                "updatedAt": {
+                    "type": "integer"
+                },
+                "usageCount": {
+                    "description": "Orders currently using the promotion",
+                    "type": "integer"
+                },
+                "usageLimit": {
+                    "description": "Uses by all users together, 0 for no limit",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.PromotionCreate": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "buyQuantity": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 2
+                },
+                "code": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "example": "SPRING10"
+                },
+                "currency": {
+                    "description": "DefaultCurrency when left out",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "endsAt": {
+                    "type": "string",
+                    "example": "2026-04-01T00:00:00Z"
+                },
+                "freeQuantity": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                },
+                "minOrderValue": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "perUserLimit": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "percent": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0,
+                    "example": 10
+                },
+                "productId": {
+                    "type": "string"
+                },
+                "startsAt": {
+                    "type": "string",
+                    "example": "2026-03-01T00:00:00Z"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "percentage",
+                        "fixed_amount",
+                        "buy_x_get_y"
+                    ],
+                    "example": "percentage"
+                },
+                "usageLimit": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.Report": {
+            "type": "object",
+            "properties": {
+                "revenue": {
+                    "description": "Sorted by currency",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_abdulazizax_udevslab-lesson3_internal_models.Revenue"
+                    }
+                },
+                "totalOrders": {
+                    "type": "integer"
+                },
+                "totalProducts": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.Revenue": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "netRevenue": {
+                    "description": "Revenue without the tax",
+                    "type": "number"
+                },
+                "totalOrders": {
+                    "type": "integer"
+                },
+                "totalRevenue": {
+                    "description": "Gross revenue, with the tax",
+                    "type": "number"
+                },
+                "totalTax": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.StatusChange": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.TaxLine": {
+            "type": "object",
+            "properties": {
+                "net": {
+                    "description": "Taxed amount, without the tax",
+                    "type": "number"
+                },
+                "rate": {
+                    "description": "In percent",
+                    "type": "number",
+                    "example": 12
+                },
+                "tax": {
+                    "type": "number"
+                },
+                "taxClass": {
+                    "type": "string",
+                    "example": "standard"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.TokenPair": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "description": "Lifetime of the access token in seconds",
+                    "type": "integer"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.TopProduct": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "product_id": {
+                    "type": "string"
+                },
+                "total_revenue": {
+                    "type": "number"
+                },
+                "total_sold": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.User": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "integer"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "updatedAt": {
                     "type": "integer"
                 }
             }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.UserCreate": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 8
+                }
+            }
+        },
+        "github_com_abdulazizax_udevslab-lesson3_internal_models.UserUpdate": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "admin",
+                        "staff",
+                        "customer"
+                    ],
+                    "example": "staff"
+                }
+            }
         }
     },
     "securityDefinitions": {
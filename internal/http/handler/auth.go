@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// actorKey is the gin context key holding the authenticated service.Actor
+const actorKey = "actor"
+
+// AuthHandler handles login, token refresh and the authentication of protected routes
+type AuthHandler struct {
+	logger      *slog.Logger
+	authService *service.AuthService
+}
+
+// NewAuthHandler creates a new AuthHandler
+func NewAuthHandler(logger *slog.Logger, authService *service.AuthService) *AuthHandler {
+	return &AuthHandler{
+		logger:      logger,
+		authService: authService,
+	}
+}
+
+// Login godoc
+// @Summary Log in
+// @Description Exchange email and password for an access and a refresh token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginRequest true "User credentials"
+// @Success 200 {object} models.TokenPair "Issued tokens"
+// @Failure 400 {object} models.Error "Bad request"
+// @Failure 401 {object} models.Error "Invalid credentials"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /auth/login [post]
+func (a *AuthHandler) Login(c *gin.Context) {
+	var credentials models.LoginRequest
+	if err := c.ShouldBindJSON(&credentials); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+		return
+	}
+
+	tokens, err := a.authService.Login(c, credentials.Email, credentials.Password)
+	if err != nil {
+		respondError(c, a.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access and refresh token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenPair "Issued tokens"
+// @Failure 400 {object} models.Error "Bad request"
+// @Failure 401 {object} models.Error "Invalid or expired token"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /auth/refresh [post]
+func (a *AuthHandler) Refresh(c *gin.Context) {
+	var request models.RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+		return
+	}
+
+	tokens, err := a.authService.Refresh(c, request.RefreshToken)
+	if err != nil {
+		respondError(c, a.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Authenticate is a middleware that requires a valid "Authorization: Bearer <token>" header
+// and stores the caller in the context
+func (a *AuthHandler) Authenticate(c *gin.Context) {
+	header := c.GetHeader("Authorization")
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found || token == "" {
+		respondError(c, a.logger, errs.Unauthorized("missing bearer token"))
+		return
+	}
+
+	actor, err := a.authService.ParseAccessToken(token)
+	if err != nil {
+		respondError(c, a.logger, err)
+		return
+	}
+
+	c.Set(actorKey, actor)
+	c.Next()
+}
+
+// RequireRoles returns a middleware that only lets callers with one of the given roles through.
+// It must run after Authenticate.
+func (a *AuthHandler) RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(roles, currentActor(c).Role) {
+			respondError(c, a.logger, service.ErrForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// currentActor returns the user authenticated by the Authenticate middleware
+func currentActor(c *gin.Context) service.Actor {
+	actor, _ := c.Get(actorKey)
+	current, _ := actor.(service.Actor)
+	return current
+}
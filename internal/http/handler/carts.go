@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// CartHandler handles the HTTP requests for the cart of the authenticated user
+type CartHandler struct {
+	logger      *slog.Logger
+	cartService *service.CartService
+}
+
+// NewCartHandler creates a new CartHandler
+func NewCartHandler(logger *slog.Logger, cartService *service.CartService) *CartHandler {
+	return &CartHandler{
+		logger:      logger,
+		cartService: cartService,
+	}
+}
+
+// GetCart godoc
+// @Summary Get the cart
+// @Description Retrieve the cart of the authenticated user with the current product prices and availability
+// @Tags Cart
+// @Produce json
+// @Success 200 {object} models.Cart "Cart"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /cart [get]
+func (h *CartHandler) GetCart(c *gin.Context) {
+	cart, err := h.cartService.GetCart(c, currentActor(c).UserID)
+	if err != nil {
+		respondError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// AddItem godoc
+// @Summary Add a product to the cart
+// @Description Add units of a product to the cart, on top of the units already in it. The product must have enough units in stock
+// @Tags Cart
+// @Accept json
+// @Produce json
+// @Param item body models.CartItemCreate true "Product and quantity"
+// @Success 200 {object} models.Cart "Updated cart"
+// @Failure 400 {object} models.Error "Bad request"
+// @Failure 422 {object} models.Error "Invalid fields"
+// @Failure 404 {object} models.Error "Product not found"
+// @Failure 409 {object} models.Error "Insufficient stock"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /cart/items [post]
+func (h *CartHandler) AddItem(c *gin.Context) {
+	var item models.CartItemCreate
+	if !bindJSON(c, h.logger, &item) {
+		return
+	}
+
+	cart, err := h.cartService.AddItem(c, currentActor(c).UserID, &item)
+	if err != nil {
+		respondError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// UpdateItem godoc
+// @Summary Change the quantity of a product in the cart
+// @Description Replace the number of units of a product in the cart. The product must have enough units in stock
+// @Tags Cart
+// @Accept json
+// @Produce json
+// @Param product_id path string true "Product ID"
+// @Param item body models.CartItemUpdate true "New quantity"
+// @Success 200 {object} models.Cart "Updated cart"
+// @Failure 400 {object} models.Error "Bad request"
+// @Failure 422 {object} models.Error "Invalid fields"
+// @Failure 404 {object} models.Error "Product not found"
+// @Failure 409 {object} models.Error "Insufficient stock"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /cart/items/{product_id} [put]
+func (h *CartHandler) UpdateItem(c *gin.Context) {
+	var item models.CartItemUpdate
+	if !bindJSON(c, h.logger, &item) {
+		return
+	}
+
+	cart, err := h.cartService.UpdateItem(c, currentActor(c).UserID, c.Param("product_id"), item.Quantity)
+	if err != nil {
+		respondError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// RemoveItem godoc
+// @Summary Remove a product from the cart
+// @Description Take a product out of the cart of the authenticated user
+// @Tags Cart
+// @Produce json
+// @Param product_id path string true "Product ID"
+// @Success 200 {object} models.Cart "Updated cart"
+// @Failure 400 {object} models.Error "Invalid product ID"
+// @Failure 404 {object} models.Error "Product is not in the cart"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /cart/items/{product_id} [delete]
+func (h *CartHandler) RemoveItem(c *gin.Context) {
+	cart, err := h.cartService.RemoveItem(c, currentActor(c).UserID, c.Param("product_id"))
+	if err != nil {
+		respondError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// ClearCart godoc
+// @Summary Empty the cart
+// @Description Remove every product from the cart of the authenticated user
+// @Tags Cart
+// @Produce json
+// @Success 204 "Cart emptied"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /cart [delete]
+func (h *CartHandler) ClearCart(c *gin.Context) {
+	if err := h.cartService.ClearCart(c, currentActor(c).UserID); err != nil {
+		respondError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Checkout godoc
+// @Summary Check out the cart
+// @Description Turn the cart into a pending order at the current prices, reserving the stock of every product, and empty the cart.
+// @Description The body is optional, its coupon code applies a promotion to the order and its region picks the tax rates.
+// @Tags Cart
+// @Accept json
+// @Produce json
+// @Param checkout body models.CartCheckout false "Coupon code and tax region"
+// @Success 201 {object} gin.H "Order ID"
+// @Failure 400 {object} models.Error "Cart is empty"
+// @Failure 404 {object} models.Error "Product not found"
+// @Failure 409 {object} models.Error "Insufficient stock or promotion used up"
+// @Failure 422 {object} models.Error "Invalid coupon code or region"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /cart/checkout [post]
+func (h *CartHandler) Checkout(c *gin.Context) {
+	var checkout models.CartCheckout
+	if c.Request.ContentLength != 0 && !bindJSON(c, h.logger, &checkout) {
+		return
+	}
+
+	orderID, err := h.cartService.Checkout(c, currentActor(c).UserID, &checkout)
+	if err != nil {
+		respondError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": orderID})
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// respondError aborts the request with a models.Error body and the HTTP status that matches the kind of err.
+// Errors without a kind are internal: they are logged and reported without details so nothing leaks to clients.
+func respondError(c *gin.Context, logger *slog.Logger, err error) {
+	status := statusFor(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		logger.Error("request failed", "method", c.Request.Method, "path", c.FullPath(), "error", err)
+		message = "Internal server error"
+	}
+
+	body := models.Error{Message: message}
+	for _, field := range errs.FieldErrors(err) {
+		body.Details = append(body.Details, models.FieldError{Field: field.Field, Rule: field.Rule, Message: field.Message})
+	}
+	c.AbortWithStatusJSON(status, body)
+}
+
+// statusFor maps the errs kinds to HTTP status codes. Validation errors that list invalid fields are
+// 422 Unprocessable Entity, the request was well formed but its content breaks the rules.
+func statusFor(err error) int {
+	switch {
+	case len(errs.FieldErrors(err)) > 0:
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, errs.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errs.ErrInvalidID), errors.Is(err, errs.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, errs.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, errs.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, errs.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, errs.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
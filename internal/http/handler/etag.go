@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// setETag exposes the version of a product or an order as a strong entity tag, e.g. "3"
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatch reads the version a PUT or DELETE expects from its If-Match header. Without the header,
+// or with "*", the write applies to whatever version is stored and models.AnyVersion is returned.
+// Weak tags never match a strong comparison, so they fail the precondition like a stale tag does.
+func ifMatch(c *gin.Context) (int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return models.AnyVersion, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, errs.Validation("If-Match must hold a single entity tag")
+	}
+
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, errs.PreconditionFailed("If-Match doesn't match the current entity tag")
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= models.AnyVersion {
+		return 0, errs.PreconditionFailed("If-Match doesn't match the current entity tag")
+	}
+	return version, nil
+}
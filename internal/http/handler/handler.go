@@ -8,13 +8,23 @@ import (
 )
 
 type Handler struct {
-	ProductHandler *ProductHandler
-	OrderHandler   *OrderHandler
+	AuthHandler      *AuthHandler
+	CartHandler      *CartHandler
+	ProductHandler   *ProductHandler
+	OrderHandler     *OrderHandler
+	PromotionHandler *PromotionHandler
+	ReportHandler    *ReportHandler
+	UserHandler      *UserHandler
 }
 
 func NewHandler(logger *slog.Logger, service *service.Service, cfg *config.Config) *Handler {
 	return &Handler{
-		ProductHandler: NewProductHandler(logger, service.ProductService),
-		OrderHandler:   NewOrderHandler(logger, service.OrderService),
+		AuthHandler:      NewAuthHandler(logger, service.AuthService),
+		CartHandler:      NewCartHandler(logger, service.CartService),
+		ProductHandler:   NewProductHandler(logger, service.ProductService),
+		OrderHandler:     NewOrderHandler(logger, service.OrderService),
+		PromotionHandler: NewPromotionHandler(logger, service.PromotionService),
+		ReportHandler:    NewReportHandler(logger, service.ReportService),
+		UserHandler:      NewUserHandler(logger, service.UserService),
 	}
 }
@@ -1,10 +1,8 @@
 package handler
 
 import (
-	"fmt"
 	"log/slog"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/abdulazizax/udevslab-lesson3/internal/models"
@@ -28,27 +26,30 @@ func NewOrderHandler(logger *slog.Logger, orderService *service.OrderService) *O
 
 // CreateOrder creates a new order
 // @Summary Create a new order
-// @Description Create a new order and return the created order's ID
+// @Description Create a new order with one or more lines for the authenticated user and return the created order's ID. The current product prices are captured on every line and the stock of all lines is reserved, or none of it when a product runs short
+// @Description The order is taxed at the rates of its region, the default tax region unless one is given. Exclusive regions add the tax to the total, inclusive ones take it out of the prices
 // @Tags Orders
 // @Accept  json
 // @Produce  json
 // @Param order body models.OrderCreate true "Order information"
 // @Success 201 {object} gin.H "Order ID"
 // @Failure 400 {object} models.Error "Bad Request"
+// @Failure 422 {object} models.Error "Invalid fields"
+// @Failure 404 {object} models.Error "User Not Found"
+// @Failure 409 {object} models.Error "Insufficient stock"
+// @Failure 401 {object} models.Error "Unauthorized"
 // @Failure 500 {object} models.Error "Internal Server Error"
+// @Security BearerAuth
 // @Router /orders [post]
 func (s *OrderHandler) CreateOrder(c *gin.Context) {
 	var order models.OrderCreate
-	if err := c.ShouldBindJSON(&order); err != nil {
-		s.logger.Error("failed to bind JSON", "error", err)
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid order data"})
+	if !bindJSON(c, s.logger, &order) {
 		return
 	}
 
-	orderID, err := s.orderService.CreateOrder(c, &order)
+	orderID, err := s.orderService.CreateOrder(c, currentActor(c).UserID, &order)
 	if err != nil {
-		s.logger.Error("failed to create order", "error", err)
-		c.JSON(http.StatusInternalServerError, models.Error{Message: "Failed to create order"})
+		respondError(c, s.logger, err)
 		return
 	}
 
@@ -57,39 +58,57 @@ func (s *OrderHandler) CreateOrder(c *gin.Context) {
 
 // ListOrders godoc
 // @Summary List all orders
-// @Description Retrieve a list of all orders in the database with pagination
+// @Description Retrieve a list of orders with pagination. Staff see every order, customers only their own
 // @Tags Orders
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
-// @Success 200 {array} models.Order "List of orders"
+// @Param cursor query string false "Cursor from next_cursor or prev_cursor of another page, can't be combined with page"
+// @Success 200 {object} models.Page[models.Order] "List of orders"
+// @Failure 401 {object} models.Error "Unauthorized"
 // @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
 // @Router /orders [get]
 func (o *OrderHandler) ListOrders(c *gin.Context) {
-	page := c.DefaultQuery("page", "1")
-	pageSize := c.DefaultQuery("page_size", "10")
-
-	pageInt, err := strconv.Atoi(page)
-	if err != nil || pageInt <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number."})
+	pagination, err := paginationFromQuery(c)
+	if err != nil {
+		respondError(c, o.logger, err)
 		return
 	}
 
-	pageSizeInt, err := strconv.Atoi(pageSize)
-	if err != nil || pageSizeInt <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page_size."})
+	orders, err := o.orderService.ListOrders(c, currentActor(c), pagination)
+	if err != nil {
+		respondError(c, o.logger, err)
 		return
 	}
 
-	// Create pagination parameters
-	var pagination = &models.Pagination{
-		Page:     pageInt,
-		PageSize: pageSizeInt,
+	c.JSON(http.StatusOK, orders)
+}
+
+// ListOrdersWithUsers godoc
+// @Summary List orders with their users
+// @Description Retrieve a paginated list of orders, newest first, together with the name of the user who placed each order
+// @Tags Orders
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} models.Page[models.OrderWithUser] "List of orders with users"
+// @Failure 400 {object} models.Error "Bad request (invalid parameters)"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /orders/with-users [get]
+func (o *OrderHandler) ListOrdersWithUsers(c *gin.Context) {
+	pagination, err := paginationFromQuery(c)
+	if err != nil {
+		respondError(c, o.logger, err)
+		return
 	}
 
-	orders, err := o.orderService.ListOrders(c, pagination)
+	orders, err := o.orderService.ListOrdersWithUsers(c, pagination)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		respondError(c, o.logger, err)
 		return
 	}
 
@@ -101,62 +120,108 @@ func (o *OrderHandler) ListOrders(c *gin.Context) {
 // @Description Fetch a single order by its ID
 // @Tags Orders
 // @Produce  json
-// @Param order_id path string true "Order ID"
+// @Param id path string true "Order ID"
 // @Success 200 {object} models.Order "Order details"
+// @Header 200 {string} ETag "Version of the order, send it back in If-Match to update or delete it"
 // @Failure 400 {object} models.Error "Bad Request"
 // @Failure 404 {object} models.Error "Order Not Found"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Order belongs to another user"
 // @Failure 500 {object} models.Error "Internal Server Error"
-// @Router /orders/{order_id} [get]
+// @Security BearerAuth
+// @Router /orders/{id} [get]
 func (s *OrderHandler) GetOrder(c *gin.Context) {
-	orderID := c.Param("order_id")
+	orderID := c.Param("id")
 
-	order, err := s.orderService.GetOrderByID(c, orderID)
+	order, err := s.orderService.GetOrderByID(c, currentActor(c), orderID)
 	if err != nil {
-		if err.Error() == "order not found" {
-			c.JSON(http.StatusNotFound, models.Error{Message: "Order not found"})
-		} else {
-			s.logger.Error("failed to get order", "error", err)
-			c.JSON(http.StatusInternalServerError, models.Error{Message: "Failed to fetch order"})
-		}
+		respondError(c, s.logger, err)
 		return
 	}
 
+	setETag(c, order.Version)
 	c.JSON(http.StatusOK, order)
 }
 
 // UpdateOrder updates an existing order
 // @Summary Update an order
-// @Description Update the details of an existing order
+// @Description Replace the lines of a pending order. Products already on the order keep their captured unit price
 // @Tags Orders
 // @Accept  json
 // @Produce  json
-// @Param order_id path string true "Order ID"
-// @Param updates body models.OrderUpdate true "Order fields to update"
+// @Param id path string true "Order ID"
+// @Param updates body models.OrderUpdate true "New order lines"
+// @Param If-Match header string false "ETag of the order as it was read, the update fails when the order changed since"
 // @Success 200 {object} gin.H "Order updated successfully"
 // @Failure 400 {object} models.Error "Bad Request"
+// @Failure 422 {object} models.Error "Invalid fields"
 // @Failure 404 {object} models.Error "Order Not Found"
+// @Failure 409 {object} models.Error "Insufficient stock or order is no longer pending"
+// @Failure 412 {object} models.Error "Order changed since it was read"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
 // @Failure 500 {object} models.Error "Internal Server Error"
-// @Router /orders/{order_id} [put]
+// @Security BearerAuth
+// @Router /orders/{id} [put]
 func (s *OrderHandler) UpdateOrder(c *gin.Context) {
-	orderID := c.Param("order_id")
+	orderID := c.Param("id")
 	var updates models.OrderUpdate
-	if err := c.ShouldBindJSON(&updates); err != nil {
-		s.logger.Error("failed to bind JSON", "error", err)
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid data for update"})
+	if !bindJSON(c, s.logger, &updates) {
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	err = s.orderService.UpdateOrder(c, orderID, version, &updates)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order updated successfully"})
+}
+
+// PatchOrder changes an existing order
+// @Summary Patch an order
+// @Description Change a pending order with a JSON Merge Patch (RFC 7396). Like any array in a merge patch, items replaces all the lines. The order is only repriced when its products or quantities change
+// @Tags Orders
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Order ID"
+// @Param patch body models.OrderPatch true "Fields to change"
+// @Param If-Match header string false "ETag of the order as it was read, the patch fails when the order changed since"
+// @Success 200 {object} gin.H "Order updated successfully"
+// @Failure 400 {object} models.Error "Bad Request"
+// @Failure 422 {object} models.Error "Invalid fields"
+// @Failure 404 {object} models.Error "Order Not Found"
+// @Failure 409 {object} models.Error "Insufficient stock or order is no longer pending"
+// @Failure 412 {object} models.Error "Order changed since it was read"
+// @Failure 415 {object} models.Error "Body is not a merge patch"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal Server Error"
+// @Security BearerAuth
+// @Router /orders/{id} [patch]
+func (s *OrderHandler) PatchOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	var patch models.OrderPatch
+	if !bindMergePatch(c, s.logger, &patch, nil) {
 		return
 	}
 
-	res, err := s.orderService.UpdateOrder(c, orderID, &updates)
-	if res != "" {
-		c.JSON(http.StatusNotFound, models.Error{Message: "Order updated successfully"})
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
 	}
+
+	err = s.orderService.PatchOrder(c, orderID, version, &patch)
 	if err != nil {
-		if err.Error() == "no order found to update" {
-			c.JSON(http.StatusNotFound, models.Error{Message: "Order not found"})
-		} else {
-			s.logger.Error("failed to update order", "error", err)
-			c.JSON(http.StatusInternalServerError, models.Error{Message: "Failed to update order"})
-		}
+		respondError(c, s.logger, err)
 		return
 	}
 
@@ -168,29 +233,72 @@ func (s *OrderHandler) UpdateOrder(c *gin.Context) {
 // @Description Delete an order by its ID
 // @Tags Orders
 // @Produce  json
-// @Param order_id path string true "Order ID"
+// @Param id path string true "Order ID"
+// @Param If-Match header string false "ETag of the order as it was read, the delete fails when the order changed since"
 // @Success 200 {string} string "Order deleted successfully"
 // @Failure 400 {object} models.Error "Bad Request"
 // @Failure 404 {object} models.Error "Order Not Found"
+// @Failure 412 {object} models.Error "Order changed since it was read"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
 // @Failure 500 {object} models.Error "Internal Server Error"
-// @Router /orders/{order_id} [delete]
+// @Security BearerAuth
+// @Router /orders/{id} [delete]
 func (s *OrderHandler) DeleteOrder(c *gin.Context) {
-	orderID := c.Param("order_id")
+	orderID := c.Param("id")
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
 
-	err := s.orderService.DeleteOrder(c, orderID)
+	err = s.orderService.DeleteOrder(c, orderID, version)
 	if err != nil {
-		if err.Error() == "no order found to delete" {
-			c.JSON(http.StatusNotFound, models.Error{Message: "Order not found"})
-		} else {
-			s.logger.Error("failed to delete order", "error", err)
-			c.JSON(http.StatusInternalServerError, models.Error{Message: "Failed to delete order"})
-		}
+		respondError(c, s.logger, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, "Order deleted successfully")
 }
 
+// TransitionOrder moves an order through its lifecycle
+// @Summary Change the status of an order
+// @Description Move an order to a new status. Allowed transitions: pending -> paid|cancelled, paid -> shipped|cancelled|refunded, shipped -> delivered, delivered -> refunded. Customers may only cancel their own orders
+// @Tags Orders
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Order ID"
+// @Param transition body models.OrderTransition true "Target status"
+// @Success 200 {object} models.Order "Updated order"
+// @Header 200 {string} ETag "New version of the order"
+// @Failure 400 {object} models.Error "Bad Request"
+// @Failure 403 {object} models.Error "Transition not permitted for the caller"
+// @Failure 404 {object} models.Error "Order Not Found"
+// @Failure 409 {object} models.Error "Transition not allowed"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal Server Error"
+// @Security BearerAuth
+// @Router /orders/{id}/transitions [post]
+func (s *OrderHandler) TransitionOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	var transition models.OrderTransition
+	if err := c.ShouldBindJSON(&transition); err != nil || transition.Status == "" {
+		s.logger.Error("failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid transition data"})
+		return
+	}
+
+	order, err := s.orderService.TransitionOrder(c, currentActor(c), orderID, transition.Status)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	setETag(c, order.Version)
+	c.JSON(http.StatusOK, order)
+}
+
 // ListOrders godoc
 // @Summary List orders within a specific date range
 // @Description Retrieve a paginated list of orders filtered by a specific date range and sorted by the creation date in ascending or descending order.
@@ -201,38 +309,26 @@ func (s *OrderHandler) DeleteOrder(c *gin.Context) {
 // @Param page_size query int false "Number of orders per page" default(10)
 // @Param start_date query string true "Start date in format (YYYY-MM-DD)" default(2000-01-01)
 // @Param end_date query string true "End date in format (YYYY-MM-DD)" default(2026-01-01)
-// @Success 200 {array} models.Order "Paginated list of orders"
+// @Param cursor query string false "Cursor from next_cursor or prev_cursor of another page, can't be combined with page"
+// @Success 200 {object} models.Page[models.Order] "Paginated list of orders"
 // @Failure 400 {object} models.Error "Bad request (invalid parameters)"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
 // @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
 // @Router /orders/range [get]
 func (o *OrderHandler) ListOrdersByDateRange(c *gin.Context) {
 	// Get start and end dates from query parameters
-	orderStr := c.DefaultQuery("order", "1") // Default is 1 if not provided
 	startDateStr := c.DefaultQuery("start_date", "")
 	endDateStr := c.DefaultQuery("end_date", "")
-	page := c.DefaultQuery("page", "1")
-	pageSize := c.DefaultQuery("page_size", "10")
-
-	pageInt, err := strconv.Atoi(page)
-	if err != nil || pageInt <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number."})
-		return
-	}
-
-	pageSizeInt, err := strconv.Atoi(pageSize)
-	if err != nil || pageSizeInt <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page_size."})
+	pagination, err := paginationFromQuery(c)
+	if err != nil {
+		respondError(c, o.logger, err)
 		return
 	}
 
-	// Create pagination parameters
-	var pagination = &models.Pagination{
-		Page:     pageInt,
-		PageSize: pageSizeInt,
-	}
-
-	order, err := strconv.ParseInt(orderStr, 10, 8)
-	if err != nil || order < -1 || order > 1 {
+	order, err := orderFromQuery(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid order parameter. Must be -1 or 1"})
 		return
 	}
@@ -250,13 +346,11 @@ func (o *OrderHandler) ListOrdersByDateRange(c *gin.Context) {
 	}
 
 	// Fetch orders with pagination, date range filter, and sorting by date
-	orders, err := o.orderService.ListOrdersByDateRange(c, int8(order), pagination, startDate, endDate)
+	orders, err := o.orderService.ListOrdersByDateRange(c, order, pagination, startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		respondError(c, o.logger, err)
 		return
 	}
 
-	fmt.Println(order)
-
 	c.JSON(http.StatusOK, orders)
 }
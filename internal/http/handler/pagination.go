@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// paginationFromQuery reads the ?page=1&page_size=10 query parameters, or ?cursor=...&page_size=10 to continue from a cursor.
+// Every listing pages the same way, ?limit= is accepted in place of page_size.
+func paginationFromQuery(c *gin.Context) (*models.Pagination, error) {
+	if _, ok := c.GetQuery("page"); ok && c.Query("cursor") != "" {
+		return nil, errs.Validation("page can't be combined with a cursor")
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		return nil, errs.Validation("invalid page number")
+	}
+
+	sizeParam, ok := c.GetQuery("page_size")
+	if limit, hasLimit := c.GetQuery("limit"); hasLimit {
+		if ok {
+			return nil, errs.Validation("page_size can't be combined with limit")
+		}
+		sizeParam, ok = limit, true
+	}
+	if !ok {
+		sizeParam = "10"
+	}
+	pageSize, err := strconv.Atoi(sizeParam)
+	if err != nil || pageSize <= 0 {
+		return nil, errs.Validation("invalid page_size")
+	}
+
+	return &models.Pagination{
+		Page:     page,
+		PageSize: pageSize,
+		Cursor:   c.Query("cursor"),
+	}, nil
+}
+
+// sortQuery is the ?order= query parameter of the listings sorted by a key, 1 ascending and -1 descending
+type sortQuery struct {
+	Order int8 `form:"order,default=1" binding:"oneof=1 -1"`
+}
+
+// orderFromQuery reads the direction of a sorted listing, ascending unless ?order=-1
+func orderFromQuery(c *gin.Context) (int8, error) {
+	var query sortQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		return 0, err
+	}
+	return query.Order, nil
+}
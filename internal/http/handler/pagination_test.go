@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/rates"
+	"github.com/abdulazizax/udevslab-lesson3/internal/service"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPagination(t *testing.T) {
+	exchangeRates, err := rates.Parse([]byte(`{"base": "USD", "rates": {}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := storage.NewMemory(&config.Config{Cart: config.CartConfig{TTL: time.Hour}}, testLogger)
+	for _, name := range []string{"Laptop", "Phone", "Tablet"} {
+		product := models.ProductCreate{Name: name, Price: 999, Currency: models.DefaultCurrency, TaxClass: models.DefaultTaxClass, Stock: 1}
+		if _, err := repo.ProductRepo().CreateProduct(context.Background(), &product); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewProductHandler(testLogger, service.NewProductService(testLogger, repo.ProductRepo(), exchangeRates, nil))
+	router := gin.New()
+	router.GET("/products", h.ListProducts)
+	router.GET("/products/search/price", h.ExactSearchProductsByPrice)
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantItems  int
+	}{
+		{"", http.StatusOK, 3},
+		{"page_size=2", http.StatusOK, 2},
+		{"limit=2", http.StatusOK, 2},
+		{"page=2&limit=2", http.StatusOK, 1},
+		{"page_size=2&limit=2", http.StatusBadRequest, 0},
+		{"limit=0", http.StatusBadRequest, 0},
+		{"page_size=x", http.StatusBadRequest, 0},
+		{"page=1&cursor=abc", http.StatusBadRequest, 0},
+	}
+	// Both listings page the same way, whichever parameter name their clients used
+	for _, path := range []string{"/products?", "/products/search/price?price=9.99&"} {
+		for _, tt := range tests {
+			t.Run(path+tt.query, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+tt.query, nil))
+				if w.Code != tt.wantStatus {
+					t.Fatalf("got %d %s, want %d", w.Code, w.Body, tt.wantStatus)
+				}
+				if tt.wantStatus != http.StatusOK {
+					return
+				}
+
+				var page models.Page[models.Product]
+				if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+					t.Fatal(err)
+				}
+				if len(page.Items) != tt.wantItems || page.Total != 3 {
+					t.Fatalf("got %d of %d products, want %d of 3", len(page.Items), page.Total, tt.wantItems)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// mergePatchType is the media type of a JSON Merge Patch (RFC 7396), plain JSON is accepted as well
+const mergePatchType = "application/merge-patch+json"
+
+// bindMergePatch reads a JSON Merge Patch into patch, a struct of pointers where the members missing from the
+// patch stay nil. A null member removes the field: the fields named in defaults go back to the JSON value
+// given there, the others can't be removed. The fields in the patch are checked against the rules of their
+// validate tags. It responds to the request itself and returns false when the body is not a merge patch of
+// the resource or breaks the rules.
+func bindMergePatch(c *gin.Context, logger *slog.Logger, patch any, defaults map[string]string) bool {
+	if mediaType, _, _ := mime.ParseMediaType(c.ContentType()); mediaType != mergePatchType && mediaType != gin.MIMEJSON {
+		c.JSON(http.StatusUnsupportedMediaType, models.Error{Message: "Content-Type must be " + mergePatchType})
+		return false
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.NewDecoder(c.Request.Body).Decode(&members); err != nil || members == nil {
+		c.JSON(http.StatusBadRequest, models.Error{Message: "Request body must be a JSON object"})
+		return false
+	}
+
+	for name, value := range members {
+		if !bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			continue
+		}
+		def, ok := defaults[name]
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.Error{Message: fmt.Sprintf("%s can't be removed", name)})
+			return false
+		}
+		members[name] = json.RawMessage(def)
+	}
+
+	body, err := json.Marshal(members)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+		return false
+	}
+
+	// Members the resource doesn't have would be added by the patch, which a typed resource can't hold
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patch); err != nil {
+		if invalid := decodeError(err, body, patch); invalid != nil {
+			respondError(c, logger, invalid)
+			return false
+		}
+		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid merge patch: " + strings.TrimPrefix(err.Error(), "json: ")})
+		return false
+	}
+
+	if err := validation.Struct(patch); err != nil {
+		respondError(c, logger, err)
+		return false
+	}
+	return true
+}
@@ -3,12 +3,10 @@ package handler
 import (
 	"log/slog"
 	"net/http"
-	"strconv"
 
 	"github.com/abdulazizax/udevslab-lesson3/internal/models"
 	"github.com/abdulazizax/udevslab-lesson3/internal/service"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // ProductHandler struct holds the logger and product service.
@@ -28,71 +26,60 @@ func NewProductHandler(logger *slog.Logger, productService *service.ProductServi
 // CreateProduct godoc
 // @Summary Create a new product
 // @Description Creates a new product in the database
+// @Description A product has one price in any supported currency, it is converted to other currencies with ?currency= when read.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param product body models.ProductCreate true "Product information"
-// @Success 201 {object} gin.H "Product created successfully"// @Failure 400 {object} models.Error "Bad request"
+// @Success 201 {object} gin.H "Product created successfully"
+// @Failure 400 {object} models.Error "Bad request"
+// @Failure 422 {object} models.Error "Invalid fields"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
 // @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
 // @Router /products [post]
 func (s *ProductHandler) CreateProduct(c *gin.Context) {
 	var product models.ProductCreate
-	if err := c.ShouldBindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+	if !bindJSON(c, s.logger, &product) {
 		return
 	}
 
 	productID, err := s.productService.CreateProduct(c, &product)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		respondError(c, s.logger, err)
 		return
 	}
 
-	id, err := primitive.ObjectIDFromHex(productID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, gin.H{"id": id})
+	c.JSON(http.StatusCreated, gin.H{"id": productID})
 }
 
 // ListProducts godoc
 // @Summary List all products
-// @Description Retrieve a list of all products in the database
+// @Description Retrieve a list of all products in the database.
+// @Description With a currency every price is converted to it at the current exchange rate, rounded to the nearest minor unit,
+// @Description and every product is listed in that currency. The stored prices stay in their own currency.
 // @Tags products
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
-// @Success 200 {array} models.Product "List of products"
+// @Param cursor query string false "Cursor from next_cursor or prev_cursor of another page, can't be combined with page"
+// @Param currency query string false "ISO 4217 code of the currency to show the prices in" example(UZS)
+// @Success 200 {object} models.Page[models.Product] "List of products"
+// @Failure 400 {object} models.Error "Bad request or no exchange rate to the currency"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /products [get]
 func (s *ProductHandler) ListProducts(c *gin.Context) {
-	page := c.DefaultQuery("page", "1")
-	pageSize := c.DefaultQuery("page_size", "10")
-
-	pageInt, err := strconv.Atoi(page)
-	if err != nil || pageInt <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number."})
-		return
-	}
-
-	pageSizeInt, err := strconv.Atoi(pageSize)
-	if err != nil || pageSizeInt <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page_size."})
+	pagination, err := paginationFromQuery(c)
+	if err != nil {
+		respondError(c, s.logger, err)
 		return
 	}
 
-	// Create pagination parameters
-	var pagination = &models.Pagination{
-		Page:     pageInt,
-		PageSize: pageSizeInt,
-	}
-
 	// Call the service layer to get paginated products
-	products, err := s.productService.ListProducts(c, pagination)
+	products, err := s.productService.ListProducts(c, pagination, c.Query("currency"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		respondError(c, s.logger, err)
 		return
 	}
 
@@ -102,54 +89,113 @@ func (s *ProductHandler) ListProducts(c *gin.Context) {
 
 // GetProduct godoc
 // @Summary Get a product by ID
-// @Description Retrieve a product by its ID
+// @Description Retrieve a product by its ID.
+// @Description With a currency the price is converted to it at the current exchange rate, rounded to the nearest minor unit.
 // @Tags products
 // @Produce json
 // @Param id path string true "Product ID"
+// @Param currency query string false "ISO 4217 code of the currency to show the price in" example(UZS)
 // @Success 200 {object} models.Product "Product found"
+// @Header 200 {string} ETag "Version of the product, send it back in If-Match to update or delete it"
+// @Failure 400 {object} models.Error "Invalid product ID or no exchange rate to the currency"
 // @Failure 404 {object} models.Error "Product not found"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /products/{id} [get]
 func (s *ProductHandler) GetProduct(c *gin.Context) {
 	productID := c.Param("id")
 
-	product, err := s.productService.GetProductByID(c, productID)
+	product, err := s.productService.GetProductByID(c, productID, c.Query("currency"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		respondError(c, s.logger, err)
 		return
 	}
 
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, product)
 }
 
 // UpdateProduct godoc
 // @Summary Update a product by ID
-// @Description Update product details by its ID
+// @Description Update product details by its ID.
+// @Description Orders taking stock don't change the ETag, the stock given here replaces whatever they left.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param id path string true "Product ID"
 // @Param product body models.ProductUpdate true "Updated product details"
+// @Param If-Match header string false "ETag of the product as it was read, the update fails when the product changed since"
 // @Success 200 {object} gin.H "Product updated successfully"
 // @Failure 400 {object} models.Error "Bad request"
+// @Failure 422 {object} models.Error "Invalid fields"
 // @Failure 404 {object} models.Error "Product not found"
+// @Failure 412 {object} models.Error "Product changed since it was read"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
 // @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
 // @Router /products/{id} [put]
 func (s *ProductHandler) UpdateProduct(c *gin.Context) {
 	productID := c.Param("id")
 	var product models.ProductUpdate
-	if err := c.ShouldBindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+	if !bindJSON(c, s.logger, &product) {
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	err = s.productService.UpdateProduct(c, productID, version, &product)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product updated successfully"})
+}
+
+// PatchProduct godoc
+// @Summary Patch a product by ID
+// @Description Change some fields of a product with a JSON Merge Patch (RFC 7396), the fields left out keep their values. A null description clears it, the other fields can't be removed
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param patch body models.ProductPatch true "Fields to change"
+// @Param If-Match header string false "ETag of the product as it was read, the patch fails when the product changed since"
+// @Success 200 {object} gin.H "Product updated successfully"
+// @Failure 400 {object} models.Error "Bad request"
+// @Failure 422 {object} models.Error "Invalid fields"
+// @Failure 404 {object} models.Error "Product not found"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 412 {object} models.Error "Product changed since it was read"
+// @Failure 415 {object} models.Error "Body is not a merge patch"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /products/{id} [patch]
+func (s *ProductHandler) PatchProduct(c *gin.Context) {
+	productID := c.Param("id")
+	var patch models.ProductPatch
+	if !bindMergePatch(c, s.logger, &patch, map[string]string{"description": `""`}) {
 		return
 	}
 
-	err := s.productService.UpdateProduct(c, productID, &product)
+	version, err := ifMatch(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		respondError(c, s.logger, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Poduct updated successfully"})
+	err = s.productService.PatchProduct(c, productID, version, &patch)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product updated successfully"})
 }
 
 // DeleteProduct godoc
@@ -158,16 +204,28 @@ func (s *ProductHandler) UpdateProduct(c *gin.Context) {
 // @Tags products
 // @Produce json
 // @Param id path string true "Product ID"
+// @Param If-Match header string false "ETag of the product as it was read, the delete fails when the product changed since"
 // @Success 204 {object} gin.H "Product deleted successfully"
+// @Failure 400 {object} models.Error "Invalid product ID"
 // @Failure 404 {object} models.Error "Product not found"
+// @Failure 412 {object} models.Error "Product changed since it was read"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
 // @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
 // @Router /products/{id} [delete]
 func (s *ProductHandler) DeleteProduct(c *gin.Context) {
 	productID := c.Param("id")
 
-	err := s.productService.DeleteProduct(c, productID)
+	version, err := ifMatch(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		respondError(c, s.logger, err)
+		return
+	}
+
+	err = s.productService.DeleteProduct(c, productID, version)
+	if err != nil {
+		respondError(c, s.logger, err)
 		return
 	}
 
@@ -176,13 +234,17 @@ func (s *ProductHandler) DeleteProduct(c *gin.Context) {
 
 // SearchProductsByName godoc
 // @Summary Search products by name
-// @Description Search products by partial name with pagination
+// @Description Search products by partial name with pagination.
+// @Description With a currency every price is converted to it at the current exchange rate, rounded to the nearest minor unit.
 // @Tags products
 // @Produce json
 // @Param name query string true "Search keyword"
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
-// @Success 200 {array} models.Product "List of products"
+// @Param cursor query string false "Cursor from next_cursor or prev_cursor of another page, can't be combined with page"
+// @Param currency query string false "ISO 4217 code of the currency to show the prices in" example(UZS)
+// @Success 200 {object} models.Page[models.Product] "List of products"
+// @Failure 400 {object} models.Error "Bad request or no exchange rate to the currency"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /products/search [get]
 func (s *ProductHandler) SearchProductsByName(c *gin.Context) {
@@ -192,31 +254,16 @@ func (s *ProductHandler) SearchProductsByName(c *gin.Context) {
 		return
 	}
 
-	page := c.DefaultQuery("page", "1")
-	pageSize := c.DefaultQuery("page_size", "10")
-
-	pageInt, err := strconv.Atoi(page)
-	if err != nil || pageInt <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number."})
-		return
-	}
-
-	pageSizeInt, err := strconv.Atoi(pageSize)
-	if err != nil || pageSizeInt <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page_size."})
+	pagination, err := paginationFromQuery(c)
+	if err != nil {
+		respondError(c, s.logger, err)
 		return
 	}
 
-	// Create pagination parameters
-	var pagination = &models.Pagination{
-		Page:     pageInt,
-		PageSize: pageSizeInt,
-	}
-
 	// Call the service layer to get search results with pagination
-	products, err := s.productService.SearchProductsByName(c, searchQuery, pagination)
+	products, err := s.productService.SearchProductsByName(c, searchQuery, pagination, c.Query("currency"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		respondError(c, s.logger, err)
 		return
 	}
 
@@ -226,36 +273,41 @@ func (s *ProductHandler) SearchProductsByName(c *gin.Context) {
 
 // ExactSearchProductsByPrice godoc
 // @Summary Search products by exact price
-// @Description Retrieve products based on an exact price match with pagination
+// @Description Retrieve products based on an exact price match with pagination.
+// @Description Only the products priced in the currency are searched, prices in different currencies are never compared.
 // @Tags products
 // @Produce json
-// @Param price query float64 true "Price to search for"
+// @Param price query number true "Price to search for, with at most 2 decimal places"
+// @Param currency query string false "ISO 4217 code of the currency the price is in" default(USD)
 // @Param page query int false "Page number" default(1)
-// @Param limit query int false "Limit of products per page" default(10)
-// @Success 200 {array} models.Product "List of products"
+// @Param page_size query int false "Page size" default(10)
+// @Param limit query int false "Alias of page_size"
+// @Param cursor query string false "Cursor from next_cursor or prev_cursor of another page, can't be combined with page"
+// @Success 200 {object} models.Page[models.Product] "List of products"
 // @Failure 400 {object} models.Error "Invalid request parameters"
+// @Failure 422 {object} models.Error "No exchange rate for the currency"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /products/search/price [get]
 func (s *ProductHandler) ExactSearchProductsByPrice(c *gin.Context) {
 	// Get price from query parameter
 	priceParam := c.DefaultQuery("price", "0") // Default is 0 if not provided
-	price, err := strconv.ParseFloat(priceParam, 64)
+	price, err := models.ParseMoney(priceParam)
 	if err != nil || price <= 0 {
 		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid price parameter"})
 		return
 	}
 
 	// Get pagination parameters
-	var pagination models.Pagination
-	if err := c.ShouldBindQuery(&pagination); err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid pagination parameters"})
+	pagination, err := paginationFromQuery(c)
+	if err != nil {
+		respondError(c, s.logger, err)
 		return
 	}
 
 	// Call the service method for fetching products by exact price
-	products, err := s.productService.ExactSearchProductsByPrice(c, price, &pagination)
+	products, err := s.productService.ExactSearchProductsByPrice(c, price, c.DefaultQuery("currency", models.DefaultCurrency), pagination)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		respondError(c, s.logger, err)
 		return
 	}
 
@@ -265,53 +317,57 @@ func (s *ProductHandler) ExactSearchProductsByPrice(c *gin.Context) {
 
 // SearchProductsByPriceRangeInc godoc
 // @Summary Search products by price range
-// @Description Retrieve products based on a price range with pagination
+// @Description Retrieve products based on a price range with pagination.
+// @Description Only the products priced in the currency are searched, prices in different currencies are never compared.
 // @Tags products
 // @Produce json
 // @Param order query int8 true "Order (-1: decreasing, 1: increasing)" default(1)
-// @Param min_price query float64 true "Minimum price"
-// @Param max_price query float64 true "Maximum price"
+// @Param min_price query number true "Minimum price, with at most 2 decimal places"
+// @Param max_price query number true "Maximum price, with at most 2 decimal places"
+// @Param currency query string false "ISO 4217 code of the currency the prices are in" default(USD)
 // @Param page query int false "Page number" default(1)
-// @Param limit query int false "Limit of products per page" default(10)
-// @Success 200 {array} models.Product "List of products"
+// @Param page_size query int false "Page size" default(10)
+// @Param limit query int false "Alias of page_size"
+// @Param cursor query string false "Cursor from next_cursor or prev_cursor of another page, can't be combined with page"
+// @Success 200 {object} models.Page[models.Product] "List of products"
 // @Failure 400 {object} models.Error "Invalid request parameters"
+// @Failure 422 {object} models.Error "No exchange rate for the currency"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /products/search/price-range [get]
 func (s *ProductHandler) SearchProductsByPriceRange(c *gin.Context) {
 	// Get price range from query parameters
-	orderStr := c.DefaultQuery("order", "1")          // Default is 1 if not provided
 	minPriceParam := c.DefaultQuery("min_price", "0") // Default is 0 if not provided
 	maxPriceParam := c.DefaultQuery("max_price", "0") // Default is 0 if not provided
 
-	order, err := strconv.ParseInt(orderStr, 10, 8)
-	if err != nil || order < -1 || order > 1 {
+	order, err := orderFromQuery(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid order parameter. Must be -1 or 1"})
 		return
 	}
 
-	minPrice, err := strconv.ParseFloat(minPriceParam, 64)
+	minPrice, err := models.ParseMoney(minPriceParam)
 	if err != nil || minPrice <= 0 {
 		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid minimum price parameter"})
 		return
 	}
 
-	maxPrice, err := strconv.ParseFloat(maxPriceParam, 64)
+	maxPrice, err := models.ParseMoney(maxPriceParam)
 	if err != nil || maxPrice <= 0 {
 		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid maximum price parameter"})
 		return
 	}
 
 	// Get pagination parameters
-	var pagination models.Pagination
-	if err := c.ShouldBindQuery(&pagination); err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid pagination parameters"})
+	pagination, err := paginationFromQuery(c)
+	if err != nil {
+		respondError(c, s.logger, err)
 		return
 	}
 
 	// Call the service method for fetching products by price range
-	products, err := s.productService.SearchProductsByPriceRange(c, int8(order), minPrice, maxPrice, &pagination)
+	products, err := s.productService.SearchProductsByPriceRange(c, order, minPrice, maxPrice, c.DefaultQuery("currency", models.DefaultCurrency), pagination)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		respondError(c, s.logger, err)
 		return
 	}
 
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// PromotionHandler serves the promotions staff manage, customers redeem them with a coupon code on their orders
+type PromotionHandler struct {
+	logger           *slog.Logger
+	promotionService *service.PromotionService
+}
+
+func NewPromotionHandler(logger *slog.Logger, promotionService *service.PromotionService) *PromotionHandler {
+	return &PromotionHandler{
+		logger:           logger,
+		promotionService: promotionService,
+	}
+}
+
+// CreatePromotion godoc
+// @Summary Create a promotion
+// @Description Create a promotion redeemable with its code in any letter case. A percentage promotion takes percent off the subtotal,
+// @Description a fixed_amount one takes amount off it and a buy_x_get_y one gives freeQuantity of every buyQuantity + freeQuantity units of productId.
+// @Description Any of them may require a minimum order value, be limited to a time window and to a number of uses overall and per user.
+// @Description Amounts are in the currency of the promotion, USD unless given, and converted for orders in other currencies.
+// @Tags Promotions
+// @Accept json
+// @Produce json
+// @Param promotion body models.PromotionCreate true "Promotion"
+// @Success 201 {object} gin.H "Promotion ID"
+// @Failure 400 {object} models.Error "Bad request"
+// @Failure 422 {object} models.Error "Invalid fields"
+// @Failure 409 {object} models.Error "Promotion code already exists"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /promotions [post]
+func (h *PromotionHandler) CreatePromotion(c *gin.Context) {
+	var promotion models.PromotionCreate
+	if !bindJSON(c, h.logger, &promotion) {
+		return
+	}
+
+	promotionID, err := h.promotionService.CreatePromotion(c, &promotion)
+	if err != nil {
+		respondError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": promotionID})
+}
+
+// ListPromotions godoc
+// @Summary List promotions
+// @Description Retrieve the promotions with pagination, oldest first, with the number of orders currently using each of them
+// @Tags Promotions
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param cursor query string false "Cursor from next_cursor or prev_cursor of another page, can't be combined with page"
+// @Success 200 {object} models.Page[models.Promotion] "List of promotions"
+// @Failure 400 {object} models.Error "Bad request"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /promotions [get]
+func (h *PromotionHandler) ListPromotions(c *gin.Context) {
+	pagination, err := paginationFromQuery(c)
+	if err != nil {
+		respondError(c, h.logger, err)
+		return
+	}
+
+	promotions, err := h.promotionService.ListPromotions(c, pagination)
+	if err != nil {
+		respondError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promotions)
+}
+
+// GetPromotion godoc
+// @Summary Get a promotion by ID
+// @Tags Promotions
+// @Produce json
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} models.Promotion "Promotion found"
+// @Failure 400 {object} models.Error "Invalid promotion ID"
+// @Failure 404 {object} models.Error "Promotion not found"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /promotions/{id} [get]
+func (h *PromotionHandler) GetPromotion(c *gin.Context) {
+	promotion, err := h.promotionService.GetPromotionByID(c, c.Param("id"))
+	if err != nil {
+		respondError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// DeletePromotion godoc
+// @Summary Delete a promotion by ID
+// @Description Delete a promotion, its code can't be redeemed anymore and the orders it was applied to keep their discounts
+// @Tags Promotions
+// @Param id path string true "Promotion ID"
+// @Success 204 "Promotion deleted"
+// @Failure 400 {object} models.Error "Invalid promotion ID"
+// @Failure 404 {object} models.Error "Promotion not found"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /promotions/{id} [delete]
+func (h *PromotionHandler) DeletePromotion(c *gin.Context) {
+	if err := h.promotionService.DeletePromotion(c, c.Param("id")); err != nil {
+		respondError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ReportHandler handles the HTTP requests for sales reports
+type ReportHandler struct {
+	logger        *slog.Logger
+	reportService *service.ReportService
+}
+
+// NewReportHandler creates a new ReportHandler
+func NewReportHandler(logger *slog.Logger, reportService *service.ReportService) *ReportHandler {
+	return &ReportHandler{
+		logger:        logger,
+		reportService: reportService,
+	}
+}
+
+// GetReport godoc
+// @Summary Get the sales summary
+// @Description Retrieve the total number of products, orders and the revenue per currency, gross and net of the tax together with the tax
+// @Tags Reports
+// @Produce json
+// @Success 200 {object} models.Report "Sales summary"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /reports/summary [get]
+func (r *ReportHandler) GetReport(c *gin.Context) {
+	report, err := r.reportService.GetReport(c)
+	if err != nil {
+		respondError(c, r.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetTopProducts godoc
+// @Summary Get the top selling products
+// @Description Retrieve the best selling products per currency ranked by units sold and revenue
+// @Tags Reports
+// @Produce json
+// @Param limit query int false "Number of products to return" default(10)
+// @Success 200 {array} models.TopProduct "Top products"
+// @Failure 400 {object} models.Error "Bad request (invalid parameters)"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /reports/top-products [get]
+func (r *ReportHandler) GetTopProducts(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid limit parameter"})
+		return
+	}
+
+	products, err := r.reportService.GetTopProducts(c, limit)
+	if err != nil {
+		respondError(c, r.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+// GetDailyOrderAggregates godoc
+// @Summary Get daily order statistics
+// @Description Retrieve the number of orders and the revenue for every day and currency within a date range, gross and net of the tax together with the tax
+// @Tags Reports
+// @Produce json
+// @Param start_date query string true "Start date in format (YYYY-MM-DD)" default(2000-01-01)
+// @Param end_date query string true "End date in format (YYYY-MM-DD), inclusive" default(2026-01-01)
+// @Success 200 {array} models.OrderAggregate "Daily order statistics"
+// @Failure 400 {object} models.Error "Bad request (invalid parameters)"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /reports/daily [get]
+func (r *ReportHandler) GetDailyOrderAggregates(c *gin.Context) {
+	startDate, err := time.Parse("2006-01-02", c.Query("start_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid start date format"})
+		return
+	}
+
+	endDate, err := time.Parse("2006-01-02", c.Query("end_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid end date format"})
+		return
+	}
+
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, models.Error{Message: "End date must not be before start date"})
+		return
+	}
+
+	// Include every order created on the end date itself
+	endDate = endDate.Add(24*time.Hour - time.Nanosecond)
+
+	aggregates, err := r.reportService.GetDailyOrderAggregates(c, startDate, endDate)
+	if err != nil {
+		respondError(c, r.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, aggregates)
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler struct holds the logger and user service.
+type UserHandler struct {
+	logger      *slog.Logger
+	userService *service.UserService
+}
+
+// NewUserHandler creates a new instance of UserHandler.
+func NewUserHandler(logger *slog.Logger, userService *service.UserService) *UserHandler {
+	return &UserHandler{
+		logger:      logger,
+		userService: userService,
+	}
+}
+
+// CreateUser godoc
+// @Summary Register a new user
+// @Description Creates a new user in the database, the password must be at least 8 characters long
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body models.UserCreate true "User information"
+// @Success 201 {object} gin.H "User created successfully"
+// @Failure 400 {object} models.Error "Bad request"
+// @Failure 422 {object} models.Error "Invalid fields"
+// @Failure 409 {object} models.Error "Email already registered"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users [post]
+func (s *UserHandler) CreateUser(c *gin.Context) {
+	var user models.UserCreate
+	if !bindJSON(c, s.logger, &user) {
+		return
+	}
+
+	userID, err := s.userService.CreateUser(c, &user)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": userID})
+}
+
+// ListUsers godoc
+// @Summary List all users
+// @Description Retrieve a list of all users in the database
+// @Tags users
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param cursor query string false "Cursor from next_cursor or prev_cursor of another page, can't be combined with page"
+// @Success 200 {object} models.Page[models.User] "List of users"
+// @Failure 400 {object} models.Error "Bad request"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /users [get]
+func (s *UserHandler) ListUsers(c *gin.Context) {
+	pagination, err := paginationFromQuery(c)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	users, err := s.userService.ListUsers(c, pagination)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// GetUser godoc
+// @Summary Get a user by ID
+// @Description Retrieve a user by its ID, customers may only retrieve their own account
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.User "User found"
+// @Failure 404 {object} models.Error "User not found"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /users/{id} [get]
+func (s *UserHandler) GetUser(c *gin.Context) {
+	userID := c.Param("id")
+
+	user, err := s.userService.GetUserByID(c, currentActor(c), userID)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUser godoc
+// @Summary Update a user by ID
+// @Description Update user details by its ID, only admins may do this and assign roles
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param user body models.UserUpdate true "Updated user details"
+// @Success 200 {object} gin.H "User updated successfully"
+// @Failure 400 {object} models.Error "Bad request"
+// @Failure 422 {object} models.Error "Invalid fields"
+// @Failure 404 {object} models.Error "User not found"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /users/{id} [put]
+func (s *UserHandler) UpdateUser(c *gin.Context) {
+	userID := c.Param("id")
+	var user models.UserUpdate
+	if !bindJSON(c, s.logger, &user) {
+		return
+	}
+
+	err := s.userService.UpdateUser(c, userID, &user)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
+}
+
+// DeleteUser godoc
+// @Summary Delete a user by ID
+// @Description Delete a user from the database by its ID
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 204 {object} gin.H "User deleted successfully"
+// @Failure 404 {object} models.Error "User not found"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Security BearerAuth
+// @Router /users/{id} [delete]
+func (s *UserHandler) DeleteUser(c *gin.Context) {
+	userID := c.Param("id")
+
+	err := s.userService.DeleteUser(c, userID)
+	if err != nil {
+		respondError(c, s.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{"message": "User deleted successfully"})
+}
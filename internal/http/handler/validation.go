@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// bindJSON reads the JSON body into v and checks it against the rules of its validate tags. It responds to the
+// request itself and returns false when the body is not JSON (400) or when fields are invalid (422).
+func bindJSON(c *gin.Context, logger *slog.Logger, v any) bool {
+	if err := c.ShouldBindBodyWith(v, binding.JSON); err != nil {
+		body, _ := c.Get(gin.BodyBytesKey)
+		data, _ := body.([]byte)
+		if invalid := decodeError(err, data, v); invalid != nil {
+			respondError(c, logger, invalid)
+			return false
+		}
+		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+		return false
+	}
+
+	if err := validation.Struct(v); err != nil {
+		respondError(c, logger, err)
+		return false
+	}
+	return true
+}
+
+// decodeError returns the validation error for a body that is valid JSON but holds a value its field can't take:
+// a value of the wrong type, or a value the type of the field rejects, such as an amount with too many decimal
+// places, reported for its field. It returns nil for any other decoding error of data into v.
+func decodeError(err error, data []byte, v any) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		field := fieldPath(typeErr.Field)
+		return errs.InvalidFields([]errs.FieldError{{
+			Field:   field,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be %s", field, jsonType(typeErr.Type)),
+		}})
+	}
+
+	// encoding/json doesn't tell which field the value rejected by its type belongs to, so the values of
+	// those types are decoded again one by one
+	if fields := invalidValues(data, reflect.TypeOf(v), ""); len(fields) > 0 {
+		return errs.InvalidFields(fields)
+	}
+	if errors.Is(err, errs.ErrValidation) {
+		return err
+	}
+	return nil
+}
+
+// valueRule reports a value rejected by the type of its field
+type valueRule struct {
+	rule    string
+	message func(field string, err error) string
+}
+
+// valueRules are the types of the request fields that reject some of the values of their JSON type
+var valueRules = map[reflect.Type]valueRule{
+	reflect.TypeFor[models.Money](): {"money", func(field string, err error) string {
+		switch {
+		case errors.Is(err, models.ErrAmountTooPrecise):
+			return field + " must have at most 2 decimal places"
+		case errors.Is(err, models.ErrAmountTooLarge):
+			return field + " is too large"
+		default:
+			return field + " must be a decimal number"
+		}
+	}},
+	reflect.TypeFor[models.ID](): {"id", func(field string, _ error) string {
+		return field + " must be an ID of 24 hex characters"
+	}},
+}
+
+// invalidValues decodes the JSON value data of a field of type t at path, and the values inside it,
+// and returns the fields whose values are rejected by their type
+func invalidValues(data []byte, t reflect.Type, path string) []errs.FieldError {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	if rule, ok := valueRules[t]; ok {
+		if err := json.Unmarshal(data, reflect.New(t).Interface()); err != nil {
+			return []errs.FieldError{{Field: path, Rule: rule.rule, Message: rule.message(path, err)}}
+		}
+		return nil
+	}
+
+	var fields []errs.FieldError
+	switch t.Kind() {
+	case reflect.Struct:
+		var members map[string]json.RawMessage
+		if json.Unmarshal(data, &members) != nil {
+			return nil
+		}
+		for _, field := range reflect.VisibleFields(t) {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || field.Anonymous || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			for member, value := range members {
+				// encoding/json matches the names of the members regardless of case too
+				if strings.EqualFold(member, name) {
+					fields = append(fields, invalidValues(value, field.Type, joinPath(path, name))...)
+					break
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		var elements []json.RawMessage
+		if json.Unmarshal(data, &elements) != nil {
+			return nil
+		}
+		for i, element := range elements {
+			fields = append(fields, invalidValues(element, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return fields
+}
+
+// joinPath appends the name of a member to the path of its object
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// fieldPath writes the dotted path of encoding/json, such as items.0.quantity, the way the validation
+// errors do: items[0].quantity
+func fieldPath(dotted string) string {
+	var path strings.Builder
+	for i, segment := range strings.Split(dotted, ".") {
+		switch _, err := strconv.Atoi(segment); {
+		case err == nil:
+			path.WriteString("[" + segment + "]")
+		case i > 0:
+			path.WriteString("." + segment)
+		default:
+			path.WriteString(segment)
+		}
+	}
+	return path.String()
+}
+
+var textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// jsonType names the kind of JSON value t is decoded from
+func jsonType(t reflect.Type) string {
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return "a string"
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
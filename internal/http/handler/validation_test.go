@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestBindJSON(t *testing.T) {
+	productID := models.NewID().Hex()
+
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantDetails []models.FieldError
+	}{
+		{
+			name:       "valid",
+			body:       `{"items": [{"productId": "` + productID + `", "quantity": 1}]}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "not JSON",
+			body:       `{"items": [`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "wrong type",
+			body:       `{"items": [{"productId": "` + productID + `", "quantity": "1"}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantDetails: []models.FieldError{
+				{Field: "items[0].quantity", Rule: "type", Message: "items[0].quantity must be an integer"},
+			},
+		},
+		{
+			name:       "malformed IDs",
+			body:       `{"items": [{"productId": "` + productID + `", "quantity": 1}, {"productId": "abc", "quantity": 1}, {"PRODUCTID": "xyz", "quantity": 1}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantDetails: []models.FieldError{
+				{Field: "items[1].productId", Rule: "id", Message: "items[1].productId must be an ID of 24 hex characters"},
+				{Field: "items[2].productId", Rule: "id", Message: "items[2].productId must be an ID of 24 hex characters"},
+			},
+		},
+		{
+			name:       "broken rules",
+			body:       `{"items": []}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantDetails: []models.FieldError{
+				{Field: "items", Rule: "min", Message: "items must contain at least 1 item"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var order models.OrderCreate
+			requireBindJSON(t, tt.body, &order, tt.wantStatus, tt.wantDetails)
+		})
+	}
+}
+
+func TestBindJSONAmounts(t *testing.T) {
+	tests := []struct {
+		price       string
+		wantStatus  int
+		wantDetails []models.FieldError
+	}{
+		{"19.99", http.StatusOK, nil},
+		{"1.234", http.StatusUnprocessableEntity, []models.FieldError{{Field: "price", Rule: "money", Message: "price must have at most 2 decimal places"}}},
+		{"1e2", http.StatusUnprocessableEntity, []models.FieldError{{Field: "price", Rule: "money", Message: "price must be a decimal number"}}},
+		{"92233720368547758.08", http.StatusUnprocessableEntity, []models.FieldError{{Field: "price", Rule: "money", Message: "price is too large"}}},
+		{`"19.99"`, http.StatusUnprocessableEntity, []models.FieldError{{Field: "price", Rule: "money", Message: "price must be a decimal number"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.price, func(t *testing.T) {
+			var product models.ProductCreate
+			requireBindJSON(t, `{"name": "Laptop", "price": `+tt.price+`, "stock": 1}`, &product, tt.wantStatus, tt.wantDetails)
+		})
+	}
+}
+
+// requireBindJSON binds body into v and checks the response to an invalid body
+func requireBindJSON(t *testing.T, body string, v any, wantStatus int, wantDetails []models.FieldError) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	if ok := bindJSON(c, testLogger, v); ok != (wantStatus == http.StatusOK) {
+		t.Fatalf("bindJSON returned %v for %s", ok, body)
+	}
+	if wantStatus == http.StatusOK {
+		return
+	}
+
+	var response models.Error
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != wantStatus || !reflect.DeepEqual(response.Details, wantDetails) {
+		t.Fatalf("got %d %s, want %d with %+v", w.Code, w.Body, wantStatus, wantDetails)
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ID identifies a stored entity whatever the backend. It has the layout of a MongoDB ObjectID,
+// so IDs grow over time, and is written as 24 hex characters in JSON and in SQL.
+type ID [12]byte
+
+// NilID is the zero ID, it is never assigned to a stored entity
+var NilID ID
+
+// NewID generates a new unique ID
+func NewID() ID {
+	return ID(primitive.NewObjectID())
+}
+
+// ParseID parses the hex form of an ID
+func ParseID(s string) (ID, error) {
+	objectID, err := primitive.ObjectIDFromHex(s)
+	if err != nil {
+		return NilID, err
+	}
+	return ID(objectID), nil
+}
+
+// Hex returns the hex form of the ID
+func (id ID) Hex() string {
+	return primitive.ObjectID(id).Hex()
+}
+
+func (id ID) String() string {
+	return id.Hex()
+}
+
+// IsZero reports whether the ID is unset, which lets bson omitempty leave it to MongoDB
+func (id ID) IsZero() bool {
+	return id == NilID
+}
+
+func (id ID) MarshalText() ([]byte, error) {
+	return []byte(id.Hex()), nil
+}
+
+// UnmarshalText parses the hex form, an empty string is the zero ID
+func (id *ID) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*id = NilID
+		return nil
+	}
+
+	parsed, err := ParseID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// MarshalBSONValue stores the ID as an ObjectID
+func (id ID) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(primitive.ObjectID(id))
+}
+
+func (id *ID) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	var objectID primitive.ObjectID
+	if err := bson.UnmarshalValue(t, data, &objectID); err != nil {
+		return err
+	}
+	*id = ID(objectID)
+	return nil
+}
+
+// Value stores the ID in SQL databases in its hex form
+func (id ID) Value() (driver.Value, error) {
+	return id.Hex(), nil
+}
+
+func (id *ID) Scan(src any) error {
+	switch src := src.(type) {
+	case string:
+		return id.UnmarshalText([]byte(src))
+	case []byte:
+		return id.UnmarshalText(src)
+	default:
+		return fmt.Errorf("cannot scan %T into an ID", src)
+	}
+}
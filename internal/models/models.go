@@ -6,38 +6,87 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// User roles: admins and staff manage the catalogue and all orders, customers only their own orders
+const (
+	RoleAdmin    = "admin"
+	RoleStaff    = "staff"
+	RoleCustomer = "customer"
+)
+
+// Order lifecycle: pending -> paid -> shipped -> delivered, with cancelled and refunded as exits
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusPaid      = "paid"
+	OrderStatusShipped   = "shipped"
+	OrderStatusDelivered = "delivered"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusRefunded  = "refunded"
+)
+
+// Promotion types, any of them may also require a minimum order value
+const (
+	PromotionPercentage  = "percentage"   // Percent off the subtotal of the order
+	PromotionFixedAmount = "fixed_amount" // A fixed amount off the subtotal of the order
+	PromotionBuyXGetY    = "buy_x_get_y"  // Of every BuyQuantity + FreeQuantity units of a product, FreeQuantity are free
+)
+
+// AnyVersion is passed as the expected version of a product or an order to write it whatever its version is.
+// Any other value makes the write conditional: it fails unless the stored version is the expected one.
+const AnyVersion int64 = 0
+
 type (
 
 	// Products structs
 
+	// Product has a single price, in whichever supported currency it was set in.
+	// Other currencies are never stored, lists and lookups convert the price on the fly with ?currency=.
 	Product struct {
-		ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		ID          ID                 `bson:"_id,omitempty" json:"id"`
 		Name        string             `bson:"name" json:"name"`
 		Description string             `bson:"description" json:"description"`
-		Price       float64            `bson:"price" json:"price"`
+		Price       Money              `bson:"price" json:"price" swaggertype:"number"`
+		Currency    string             `bson:"currency" json:"currency" example:"USD"` // ISO 4217 code of the price, the only currency it is stored in
+		TaxClass    string             `bson:"taxClass" json:"taxClass" example:"standard"`
 		Stock       int                `bson:"stock" json:"stock"`
+		Version     int64              `bson:"version" json:"version"` // Incremented by every write but the stock movements of orders, see AnyVersion
 		CreatedAt   primitive.DateTime `bson:"createdAt" json:"createdAt"`
 		UpdatedAt   primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
 	}
 
 	ProductCreate struct {
-		Name        string  `bson:"name" json:"name"`
-		Description string  `bson:"description" json:"description"`
-		Price       float64 `bson:"price" json:"price"`
-		Stock       int     `bson:"stock" json:"stock"`
+		Name        string `bson:"name" json:"name" validate:"notblank"`
+		Description string `bson:"description" json:"description"`
+		Price       Money  `bson:"price" json:"price" validate:"gte=0" swaggertype:"number"`
+		Currency    string `bson:"currency" json:"currency,omitempty" example:"USD" validate:"omitempty,currency"` // DefaultCurrency when left out
+		TaxClass    string `bson:"taxClass" json:"taxClass,omitempty" example:"standard"`                          // DefaultTaxClass when left out
+		Stock       int    `bson:"stock" json:"stock" validate:"gte=0"`
 	}
 
 	ProductUpdate struct {
-		Name        string  `bson:"name" json:"name"`
-		Description string  `bson:"description" json:"description"`
-		Price       float64 `bson:"price" json:"price"`
-		Stock       int     `bson:"stock" json:"stock"`
+		Name        string `bson:"name" json:"name" validate:"notblank"`
+		Description string `bson:"description" json:"description"`
+		Price       Money  `bson:"price" json:"price" validate:"gte=0" swaggertype:"number"`
+		Currency    string `bson:"currency" json:"currency,omitempty" example:"USD" validate:"omitempty,currency"` // Left as it is when left out
+		TaxClass    string `bson:"taxClass" json:"taxClass,omitempty" example:"standard"`                          // Left as it is when left out
+		Stock       int    `bson:"stock" json:"stock" validate:"gte=0"`
+	}
+
+	// ProductPatch is a JSON Merge Patch of a product, nil fields are left as they are
+	ProductPatch struct {
+		Name        *string `json:"name,omitempty" validate:"omitnil,notblank"`
+		Description *string `json:"description,omitempty"`
+		Price       *Money  `json:"price,omitempty" validate:"omitnil,gte=0" swaggertype:"number"`
+		Currency    *string `json:"currency,omitempty" example:"USD" validate:"omitnil,currency"`
+		TaxClass    *string `json:"taxClass,omitempty" example:"standard"`
+		Stock       *int    `json:"stock,omitempty" validate:"omitnil,gte=0"`
 	}
 
 	UpdatedProduct struct {
 		Name        string             `bson:"name" json:"name"`
 		Description string             `bson:"description" json:"description"`
-		Price       float64            `bson:"price" json:"price"`
+		Price       Money              `bson:"price" json:"price"`
+		Currency    string             `bson:"currency" json:"currency"`
+		TaxClass    string             `bson:"taxClass" json:"taxClass"`
 		Stock       int                `bson:"stock" json:"stock"`
 		UpdatedAt   primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
 	}
@@ -45,75 +94,302 @@ type (
 	// Orders structs
 
 	Order struct {
-		ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-		UserID    primitive.ObjectID `bson:"userId" json:"userId"`
-		ProductID primitive.ObjectID `bson:"productId" json:"productId"`
-		Quantity  int                `bson:"quantity" json:"quantity"`
-		Status    string             `bson:"status" json:"status"`
-		Total     float64            `bson:"total" json:"total"`
-		CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt"`
-		UpdatedAt primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+		ID            ID                 `bson:"_id,omitempty" json:"id"`
+		UserID        ID                 `bson:"userId" json:"userId"`
+		Items         []OrderItem        `bson:"items" json:"items"`
+		Status        string             `bson:"status" json:"status"`
+		StatusHistory []StatusChange     `bson:"statusHistory" json:"statusHistory"`
+		Subtotal      Money              `bson:"subtotal" json:"subtotal" swaggertype:"number"` // Sum of the line totals
+		Discounts     []AppliedDiscount  `bson:"discounts" json:"discounts"`
+		Discount      Money              `bson:"discount" json:"discount" swaggertype:"number"` // Sum of the discounts
+		TaxRegion     string             `bson:"taxRegion" json:"taxRegion" example:"UZ"`       // Region the order is taxed in, empty without tax rates
+		TaxMode       string             `bson:"taxMode" json:"taxMode" example:"inclusive"`    // Whether the prices hold the tax or get it on top
+		Taxes         []TaxLine          `bson:"taxes" json:"taxes"`
+		Net           Money              `bson:"net" json:"net" swaggertype:"number"`     // Total less the tax
+		Tax           Money              `bson:"tax" json:"tax" swaggertype:"number"`     // Sum of the taxes
+		Total         Money              `bson:"total" json:"total" swaggertype:"number"` // Subtotal less the discount, plus the tax when it is exclusive
+		Currency      string             `bson:"currency" json:"currency" example:"USD"`  // ISO 4217 code of every price of the order
+		Version       int64              `bson:"version" json:"version"`                  // Incremented by every write, see AnyVersion
+		CreatedAt     primitive.DateTime `bson:"createdAt" json:"createdAt"`
+		UpdatedAt     primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+	}
+
+	// OrderItem is a single line of an order, the unit price is captured when the line is added.
+	// Products priced in another currency than the order are converted at the exchange rate of that moment.
+	OrderItem struct {
+		ProductID     ID     `bson:"productId" json:"productId"`
+		Quantity      int    `bson:"quantity" json:"quantity"`
+		UnitPrice     Money  `bson:"unitPrice" json:"unitPrice" swaggertype:"number"`
+		LineTotal     Money  `bson:"lineTotal" json:"lineTotal" swaggertype:"number"`
+		PriceCurrency string `bson:"priceCurrency" json:"priceCurrency" example:"UZS"`                             // Currency the product was priced in
+		ExchangeRate  Rate   `bson:"exchangeRate" json:"exchangeRate" swaggertype:"number" example:"0.0000790483"` // Converts the product price to the currency of the order
+		TaxClass      string `bson:"taxClass" json:"taxClass" example:"standard"`                                  // Tax class of the product when the line was added
+	}
+
+	OrderItemCreate struct {
+		ProductID ID  `bson:"productId" json:"productId" validate:"required"`
+		Quantity  int `bson:"quantity" json:"quantity" example:"1" validate:"gt=0"`
 	}
 
 	OrderCreate struct {
-		UserID    primitive.ObjectID `bson:"userId" json:"userId"`
-		ProductID primitive.ObjectID `bson:"productId" json:"productId"`
-		Quantity  int                `bson:"quantity" json:"quantity"`
-		Status    string             `bson:"status" json:"status"`
+		Items      []OrderItemCreate `bson:"items" json:"items" validate:"min=1,dive"`
+		Currency   string            `json:"currency,omitempty" example:"USD" validate:"omitempty,currency"` // Currency the order is charged in, the currency of its first product when left out
+		CouponCode string            `json:"couponCode,omitempty" example:"SPRING10"`                        // Code of the promotion to apply
+		Region     string            `json:"region,omitempty" example:"UZ"`                                  // Region to tax the order in, the default region of the tax rates when left out
 	}
 
+	// OrderUpdate replaces the lines of an order
 	OrderUpdate struct {
-		UserID    primitive.ObjectID `bson:"userId" json:"userId"`
-		ProductID primitive.ObjectID `bson:"productId,omitempty" json:"productId,omitempty"`
-		Quantity  int                `bson:"quantity,omitempty" json:"quantity,omitempty"`
-		Status    string             `bson:"status,omitempty" json:"status,omitempty"`
+		Items []OrderItemCreate `bson:"items" json:"items" validate:"min=1,dive"`
+	}
+
+	// OrderPatch is a JSON Merge Patch of an order. Like any array in a merge patch, items replaces all the lines.
+	OrderPatch struct {
+		Items *[]OrderItemCreate `json:"items,omitempty" validate:"omitnil,min=1,dive"`
 	}
 
 	UpdatedOrder struct {
-		UserID    primitive.ObjectID `bson:"userId" json:"userId"`
-		ProductID primitive.ObjectID `bson:"productId,omitempty" json:"productId,omitempty"`
-		Quantity  int                `bson:"quantity,omitempty" json:"quantity,omitempty"`
-		Status    string             `bson:"status,omitempty" json:"status,omitempty"`
-		Total     float64            `bson:"total,omitempty" json:"total,omitempty"`
-		UpdatedAt primitive.DateTime `bson:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+		Items     []OrderItem        `bson:"items" json:"items"`
+		Subtotal  Money              `bson:"subtotal" json:"subtotal"`
+		Discounts []AppliedDiscount  `bson:"discounts" json:"discounts"`
+		Discount  Money              `bson:"discount" json:"discount"`
+		Taxes     []TaxLine          `bson:"taxes" json:"taxes"`
+		Net       Money              `bson:"net" json:"net"`
+		Tax       Money              `bson:"tax" json:"tax"`
+		Total     Money              `bson:"total" json:"total"`
+		UpdatedAt primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+	}
+
+	// AppliedDiscount is what a promotion took off an order, in the currency of the order
+	AppliedDiscount struct {
+		PromotionID ID     `bson:"promotionId" json:"promotionId"`
+		Code        string `bson:"code" json:"code" example:"SPRING10"`
+		Type        string `bson:"type" json:"type" example:"percentage"`
+		Amount      Money  `bson:"amount" json:"amount" swaggertype:"number"`
+	}
+
+	// TaxLine is the tax on the lines of an order in one tax class, after the discounts are taken off them
+	TaxLine struct {
+		TaxClass string  `bson:"taxClass" json:"taxClass" example:"standard"`
+		Rate     TaxRate `bson:"rate" json:"rate" swaggertype:"number" example:"12"` // In percent
+		Net      Money   `bson:"net" json:"net" swaggertype:"number"`                // Taxed amount, without the tax
+		Tax      Money   `bson:"tax" json:"tax" swaggertype:"number"`
+	}
+
+	// StatusChange records a single step of the order lifecycle
+	StatusChange struct {
+		From string             `bson:"from" json:"from"`
+		To   string             `bson:"to" json:"to"`
+		At   primitive.DateTime `bson:"at" json:"at"`
+	}
+
+	OrderTransition struct {
+		Status string `json:"status" example:"paid"`
+	}
+
+	// Carts structs
+
+	// Cart holds the products a user is about to order, prices are looked up live and never stored.
+	// A user without a stored cart gets an empty one, which has no ID and no timestamps yet.
+	Cart struct {
+		ID        *ID                `bson:"_id,omitempty" json:"id,omitempty"`
+		UserID    ID                 `bson:"userId" json:"userId"`
+		Items     []CartItem         `bson:"items" json:"items"`
+		Total     Money              `bson:"-" json:"total" swaggertype:"number"`
+		Currency  string             `bson:"-" json:"currency,omitempty" example:"USD"` // Currency of the products in the cart
+		CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt,omitempty"`
+		UpdatedAt primitive.DateTime `bson:"updatedAt" json:"updatedAt,omitempty"`
+		ExpiresAt primitive.DateTime `bson:"expiresAt" json:"expiresAt,omitempty"`
+	}
+
+	CartItem struct {
+		ProductID ID     `bson:"productId" json:"productId"`
+		Quantity  int    `bson:"quantity" json:"quantity"`
+		Name      string `bson:"-" json:"name"`
+		UnitPrice Money  `bson:"-" json:"unitPrice" swaggertype:"number"`
+		LineTotal Money  `bson:"-" json:"lineTotal" swaggertype:"number"`
+		InStock   bool   `bson:"-" json:"inStock"` // Whether the product still has enough units for this line
+	}
+
+	CartItemCreate struct {
+		ProductID ID  `json:"productId" validate:"required"`
+		Quantity  int `json:"quantity" example:"1" validate:"gt=0"`
 	}
 
+	CartItemUpdate struct {
+		Quantity int `json:"quantity" example:"2" validate:"gt=0"`
+	}
+
+	CartCheckout struct {
+		CouponCode string `json:"couponCode,omitempty" example:"SPRING10"` // Code of the promotion to apply to the order
+		Region     string `json:"region,omitempty" example:"UZ"`           // Region to tax the order in, the default region of the tax rates when left out
+	}
+
+	// Promotions structs
+
+	// Promotion is a discount customers get by entering its code when they place an order.
+	// Amounts are in the currency of the promotion and converted for orders in other currencies.
+	Promotion struct {
+		ID            ID                 `bson:"_id,omitempty" json:"id"`
+		Code          string             `bson:"code" json:"code" example:"SPRING10"` // Unique, kept in upper case
+		Description   string             `bson:"description" json:"description"`
+		Type          string             `bson:"type" json:"type" example:"percentage"`
+		Percent       int                `bson:"percent" json:"percent,omitempty" example:"10"`                     // Percentage off, for percentage promotions
+		Amount        Money              `bson:"amount" json:"amount,omitempty" swaggertype:"number"`               // Amount off, for fixed_amount promotions
+		ProductID     *ID                `bson:"productId,omitempty" json:"productId,omitempty"`                    // Product of a buy_x_get_y promotion
+		BuyQuantity   int                `bson:"buyQuantity" json:"buyQuantity,omitempty" example:"2"`              // Units to pay for, for buy_x_get_y promotions
+		FreeQuantity  int                `bson:"freeQuantity" json:"freeQuantity,omitempty" example:"1"`            // Units given on top, for buy_x_get_y promotions
+		MinOrderValue Money              `bson:"minOrderValue" json:"minOrderValue,omitempty" swaggertype:"number"` // Subtotal the order must reach
+		Currency      string             `bson:"currency" json:"currency" example:"USD"`                            // ISO 4217 code of the amounts
+		StartsAt      primitive.DateTime `bson:"startsAt,omitempty" json:"startsAt,omitempty" swaggertype:"string"` // Not redeemable before, if set
+		EndsAt        primitive.DateTime `bson:"endsAt,omitempty" json:"endsAt,omitempty" swaggertype:"string"`     // Not redeemable from then on, if set
+		UsageLimit    int                `bson:"usageLimit" json:"usageLimit"`                                      // Uses by all users together, 0 for no limit
+		PerUserLimit  int                `bson:"perUserLimit" json:"perUserLimit"`                                  // Uses by a single user, 0 for no limit
+		UsageCount    int                `bson:"usageCount" json:"usageCount"`                                      // Orders currently using the promotion
+		CreatedAt     primitive.DateTime `bson:"createdAt" json:"createdAt"`
+		UpdatedAt     primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+	}
+
+	PromotionCreate struct {
+		Code          string             `json:"code" example:"SPRING10" validate:"notblank,max=32"`
+		Description   string             `json:"description"`
+		Type          string             `json:"type" example:"percentage" validate:"oneof=percentage fixed_amount buy_x_get_y"`
+		Percent       int                `json:"percent,omitempty" example:"10" validate:"required_if=Type percentage,gte=0,lte=100"`
+		Amount        Money              `json:"amount,omitempty" swaggertype:"number" validate:"required_if=Type fixed_amount,gte=0"`
+		ProductID     *ID                `json:"productId,omitempty" validate:"required_if=Type buy_x_get_y"`
+		BuyQuantity   int                `json:"buyQuantity,omitempty" example:"2" validate:"required_if=Type buy_x_get_y,gte=0"`
+		FreeQuantity  int                `json:"freeQuantity,omitempty" example:"1" validate:"required_if=Type buy_x_get_y,gte=0"`
+		MinOrderValue Money              `json:"minOrderValue,omitempty" swaggertype:"number" validate:"gte=0"`
+		Currency      string             `json:"currency,omitempty" example:"USD" validate:"omitempty,currency"` // DefaultCurrency when left out
+		StartsAt      primitive.DateTime `json:"startsAt,omitempty" swaggertype:"string" example:"2026-03-01T00:00:00Z"`
+		EndsAt        primitive.DateTime `json:"endsAt,omitempty" swaggertype:"string" example:"2026-04-01T00:00:00Z"`
+		UsageLimit    int                `json:"usageLimit,omitempty" validate:"gte=0"`
+		PerUserLimit  int                `json:"perUserLimit,omitempty" validate:"gte=0"`
+	}
+
+	// Users structs
+
+	User struct {
+		ID           ID                 `bson:"_id,omitempty" json:"id"`
+		Name         string             `bson:"name" json:"name"`
+		Email        string             `bson:"email" json:"email"`
+		PasswordHash string             `bson:"passwordHash" json:"-"`
+		Role         string             `bson:"role" json:"role"`
+		CreatedAt    primitive.DateTime `bson:"createdAt" json:"createdAt"`
+		UpdatedAt    primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+	}
+
+	UserCreate struct {
+		Name     string `bson:"name" json:"name" validate:"notblank"`
+		Email    string `bson:"email" json:"email" validate:"required,email"`
+		Password string `bson:"-" json:"password" validate:"min=8"`
+		Role     string `bson:"role" json:"-"` // Self registered users are always customers
+	}
+
+	UserUpdate struct {
+		Name  string `bson:"name" json:"name" validate:"notblank"`
+		Email string `bson:"email" json:"email" validate:"required,email"`
+		Role  string `bson:"role,omitempty" json:"role,omitempty" example:"staff" validate:"omitempty,oneof=admin staff customer"`
+	}
+
+	UpdatedUser struct {
+		Name      string             `bson:"name" json:"name"`
+		Email     string             `bson:"email" json:"email"`
+		Role      string             `bson:"role,omitempty" json:"role,omitempty"`
+		UpdatedAt primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+	}
+
+	// Auth structs
+
+	LoginRequest struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	RefreshRequest struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	TokenPair struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"` // Lifetime of the access token in seconds
+	}
+
+	// Report holds the revenue per currency, amounts in different currencies are never added up
 	Report struct {
-		TotalProducts int     `json:"totalProducts"`
-		TotalOrders   int     `json:"totalOrders"`
-		TotalRevenue  float64 `json:"totalRevenue"`
+		TotalProducts int       `json:"totalProducts"`
+		TotalOrders   int       `json:"totalOrders"`
+		Revenue       []Revenue `json:"revenue"` // Sorted by currency
+	}
+
+	Revenue struct {
+		Currency     string `json:"currency" bson:"currency"`
+		TotalOrders  int    `json:"totalOrders" bson:"totalOrders"`
+		TotalRevenue Money  `json:"totalRevenue" bson:"totalRevenue" swaggertype:"number"` // Gross revenue, with the tax
+		NetRevenue   Money  `json:"netRevenue" bson:"netRevenue" swaggertype:"number"`     // Revenue without the tax
+		TotalTax     Money  `json:"totalTax" bson:"totalTax" swaggertype:"number"`
 	}
 
+	// Error is the body of every failed request, details lists the invalid fields of a 422 response
 	Error struct {
-		Message string `json:"message"`
+		Message string       `json:"message"`
+		Details []FieldError `json:"details,omitempty"`
+	}
+
+	// FieldError tells which validation rule a field of the request broke
+	FieldError struct {
+		Field   string `json:"field" example:"items[0].quantity"`
+		Rule    string `json:"rule" example:"gt"`
+		Message string `json:"message" example:"items[0].quantity must be greater than 0"`
 	}
 
 	Pagination struct {
-		Page     int `form:"page" json:"page" binding:"required"`   // Query: ?page=1
-		PageSize int `form:"limit" json:"limit" binding:"required"` // Query: ?limit=10
+		Page     int    `form:"page" json:"page"`           // Query: ?page=1, the first page when left out without a cursor
+		PageSize int    `form:"page_size" json:"page_size"` // Query: ?page_size=10, or ?limit=10
+		Cursor   string `form:"cursor" json:"cursor"`       // Query: ?cursor=..., can't be combined with page
+	}
+
+	// Page is the envelope of every listing, the cursors are opaque tokens for the neighbouring pages.
+	// Page is left out when the page was selected by a cursor.
+	Page[T any] struct {
+		Items      []T    `json:"items"`
+		Page       int    `json:"page,omitempty"`
+		PageSize   int    `json:"page_size"`
+		Total      int64  `json:"total"`
+		HasNext    bool   `json:"has_next"`
+		NextCursor string `json:"next_cursor,omitempty"`
+		PrevCursor string `json:"prev_cursor,omitempty"`
 	}
 
+	// TopProduct sums the sales of a product in one currency, a product sold in several currencies ranks once per currency
 	TopProduct struct {
-		ProductID    string  `json:"product_id" bson:"_id"`
-		Name         string  `json:"name" bson:"name"`
-		TotalSold    int     `json:"total_sold" bson:"total_sold"`
-		TotalRevenue float64 `json:"total_revenue" bson:"total_revenue"`
+		ProductID    string `json:"product_id" bson:"product_id"`
+		Name         string `json:"name" bson:"name"`
+		Currency     string `json:"currency" bson:"currency"`
+		TotalSold    int    `json:"total_sold" bson:"total_sold"`
+		TotalRevenue Money  `json:"total_revenue" bson:"total_revenue" swaggertype:"number"`
 	}
 
 	OrderWithUser struct {
-		OrderID   string    `json:"order_id" bson:"_id"`
-		UserID    string    `json:"user_id" bson:"user_id"`
-		UserName  string    `json:"user_name" bson:"user_name"`
-		ProductID string    `json:"product_id" bson:"product_id"`
-		Quantity  int       `json:"quantity" bson:"quantity"`
-		Status    string    `json:"status" bson:"status"`
-		Total     float64   `json:"total" bson:"total"`
-		CreatedAt time.Time `json:"created_at" bson:"created_at"`
+		OrderID   string      `json:"order_id" bson:"_id"`
+		UserID    string      `json:"user_id" bson:"user_id"`
+		UserName  string      `json:"user_name" bson:"user_name"`
+		Items     []OrderItem `json:"items" bson:"items"`
+		Status    string      `json:"status" bson:"status"`
+		Total     Money       `json:"total" bson:"total" swaggertype:"number"`
+		Currency  string      `json:"currency" bson:"currency"`
+		CreatedAt time.Time   `json:"created_at" bson:"created_at"`
 	}
 
+	// OrderAggregate sums the orders of a day in one currency
 	OrderAggregate struct {
-		Date         string  `json:"date" bson:"_id"`
-		TotalOrders  int     `json:"total_orders" bson:"total_orders"`
-		TotalRevenue float64 `json:"total_revenue" bson:"total_revenue"`
+		Date         string `json:"date" bson:"date"`
+		Currency     string `json:"currency" bson:"currency"`
+		TotalOrders  int    `json:"total_orders" bson:"total_orders"`
+		TotalRevenue Money  `json:"total_revenue" bson:"total_revenue" swaggertype:"number"` // Gross revenue, with the tax
+		NetRevenue   Money  `json:"net_revenue" bson:"net_revenue" swaggertype:"number"`     // Revenue without the tax
+		TotalTax     Money  `json:"total_tax" bson:"total_tax" swaggertype:"number"`
 	}
 )
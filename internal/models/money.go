@@ -0,0 +1,135 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+)
+
+// Money is an amount in minor units, the hundredths of the currency unit: 1999 is 19.99.
+// Only currencies with 2 decimal places are supported, see IsSupportedCurrency.
+// Amounts are added and multiplied exactly. In JSON they are written as a plain decimal number, like the
+// float64 prices used to be, and read back without going through a float.
+type Money int64
+
+// DefaultCurrency is the ISO 4217 code of the products created without a currency and of the prices stored
+// before products had one
+const DefaultCurrency = "USD"
+
+// minorUnits is the number of minor units in a unit of every supported currency
+const minorUnits = 100
+
+// currencies are the ISO 4217 codes of the supported currencies. Money holds hundredths of a unit, so only
+// currencies with 2 decimal places can be supported: 100 JPY, which has none, would be held as 1.00 JPY
+// and 1.000 KWD, which has 3, couldn't be held at all.
+var currencies = map[string]bool{
+	"AED": true, "AUD": true, "BRL": true, "CAD": true, "CHF": true, "CNY": true, "CZK": true, "DKK": true,
+	"EUR": true, "GBP": true, "HKD": true, "INR": true, "KZT": true, "MXN": true, "NOK": true, "NZD": true,
+	"PLN": true, "RUB": true, "SEK": true, "SGD": true, "TRY": true, "UAH": true, "USD": true, "UZS": true,
+	"ZAR": true,
+}
+
+// IsSupportedCurrency reports whether code is the ISO 4217 code of a currency amounts can be held in
+func IsSupportedCurrency(code string) bool {
+	return currencies[code]
+}
+
+// Errors returned by ParseMoney
+var (
+	ErrInvalidAmount    = errs.Validation("amount is not a decimal number")
+	ErrAmountTooPrecise = errs.Validation("amount has more than 2 decimal places")
+	ErrAmountTooLarge   = errs.Validation("amount is too large")
+)
+
+// ParseMoney reads a decimal amount in currency units, such as "19.99" or "20". Amounts that don't fit
+// in minor units exactly are rejected rather than rounded, and so are amounts written with an exponent.
+func ParseMoney(s string) (Money, error) {
+	// big.Rat also reads fractions, hexadecimal numbers and exponents, which could ask for huge
+	// numbers to be built, amounts are plain decimals
+	if digits := strings.TrimPrefix(s, "-"); digits == "" || strings.Trim(digits, "0123456789.") != "" {
+		return 0, ErrInvalidAmount
+	}
+
+	amount, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, ErrInvalidAmount
+	}
+
+	amount.Mul(amount, big.NewRat(minorUnits, 1))
+	if !amount.IsInt() {
+		return 0, ErrAmountTooPrecise
+	}
+	if !amount.Num().IsInt64() {
+		return 0, ErrAmountTooLarge
+	}
+	return Money(amount.Num().Int64()), nil
+}
+
+// Add returns the sum of two amounts
+func (m Money) Add(other Money) Money {
+	return m + other
+}
+
+// Mul returns the amount of quantity units priced at m
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// Percent returns percent hundredths of the amount, rounded to the nearest minor unit
+func (m Money) Percent(percent int) Money {
+	return Money(round(big.NewRat(int64(m)*int64(percent), 100)).Int64())
+}
+
+// Prorate returns the share of the amount that part is of whole, rounded to the nearest minor unit
+func (m Money) Prorate(part, whole Money) Money {
+	share := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(part)))
+	return Money(round(new(big.Rat).SetFrac(share, big.NewInt(int64(whole)))).Int64())
+}
+
+// String returns the amount with both decimal places, such as 19.90
+func (m Money) String() string {
+	units, cents := m.split()
+	return fmt.Sprintf("%s.%02d", units, cents)
+}
+
+// MarshalJSON writes the amount as the shortest decimal number: 19.99, 19.9 or 20
+func (m Money) MarshalJSON() ([]byte, error) {
+	units, cents := m.split()
+	switch {
+	case cents == 0:
+		return []byte(units), nil
+	case cents%10 == 0:
+		return []byte(fmt.Sprintf("%s.%d", units, cents/10)), nil
+	default:
+		return []byte(fmt.Sprintf("%s.%02d", units, cents)), nil
+	}
+}
+
+// UnmarshalJSON reads a JSON number, numbers that aren't amounts are rejected with the error of ParseMoney
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+
+	amount, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = amount
+	return nil
+}
+
+// split returns the signed whole units and the minor units of the amount
+func (m Money) split() (string, int64) {
+	abs := uint64(m)
+	sign := ""
+	if m < 0 {
+		abs = uint64(-m)
+		sign = "-"
+	}
+	return sign + strconv.FormatUint(abs/minorUnits, 10), int64(abs % minorUnits)
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr error
+	}{
+		{"19.99", 1999, nil},
+		{"19.9", 1990, nil},
+		{"20", 2000, nil},
+		{"19.990", 1999, nil}, // Trailing zeros still fit in minor units
+		{"0.01", 1, nil},
+		{"-5.25", -525, nil},
+		{"-0.01", -1, nil},
+		{"92233720368547758.07", 9223372036854775807, nil},
+
+		// Amounts are rejected rather than rounded
+		{"19.999", 0, ErrAmountTooPrecise},
+		{"0.005", 0, ErrAmountTooPrecise},
+		{"-0.001", 0, ErrAmountTooPrecise},
+
+		{"92233720368547758.08", 0, ErrAmountTooLarge},
+		{"-92233720368547758.09", 0, ErrAmountTooLarge},
+
+		// Only plain decimals are amounts
+		{"1e2", 0, ErrInvalidAmount},
+		{"1E-2", 0, ErrInvalidAmount},
+		{"0x10", 0, ErrInvalidAmount},
+		{"1/2", 0, ErrInvalidAmount},
+		{"+5", 0, ErrInvalidAmount},
+		{"--5", 0, ErrInvalidAmount},
+		{"1.2.3", 0, ErrInvalidAmount},
+		{"", 0, ErrInvalidAmount},
+		{"-", 0, ErrInvalidAmount},
+		{" 5", 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, %v, want %d, %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		amount Money
+		want   string
+	}{
+		{1999, "19.99"},
+		{1990, "19.9"},
+		{2000, "20"},
+		{5, "0.05"},
+		{50, "0.5"},
+		{0, "0"},
+		{-525, "-5.25"},
+		{-5, "-0.05"},
+		{-2000, "-20"},
+		{9223372036854775807, "92233720368547758.07"},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.amount)
+		if err != nil || string(data) != tt.want {
+			t.Errorf("Marshal(%d) = %s, %v, want %s", tt.amount, data, err, tt.want)
+			continue
+		}
+
+		var back Money
+		if err := json.Unmarshal(data, &back); err != nil || back != tt.amount {
+			t.Errorf("Unmarshal(%s) = %d, %v, want %d", data, back, err, tt.amount)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	var price struct {
+		Price Money `json:"price"`
+	}
+
+	// null leaves the amount as it was
+	price.Price = 1999
+	if err := json.Unmarshal([]byte(`{"price": null}`), &price); err != nil || price.Price != 1999 {
+		t.Fatalf("got %d, %v for null", price.Price, err)
+	}
+
+	for _, data := range []string{
+		`{"price": "19.99"}`,
+		`{"price": 19.999}`,
+		`{"price": 1e2}`,
+		`{"price": true}`,
+	} {
+		if err := json.Unmarshal([]byte(data), &price); err == nil {
+			t.Errorf("unmarshalled %s into %d", data, price.Price)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	for amount, want := range map[Money]string{
+		1990: "19.90",
+		2000: "20.00",
+		5:    "0.05",
+		0:    "0.00",
+		-525: "-5.25",
+		-5:   "-0.05",
+	} {
+		if got := amount.String(); got != want {
+			t.Errorf("%d.String() = %s, want %s", amount, got, want)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want Money
+	}{
+		{"Add", Money(1999).Add(1), 2000},
+		{"Add negative", Money(1999).Add(-2000), -1},
+		{"Mul", Money(1999).Mul(3), 5997},
+		{"Mul by zero", Money(1999).Mul(0), 0},
+		{"Mul negative", Money(-250).Mul(4), -1000},
+		{"Percent", Money(2000).Percent(15), 300},
+		{"Percent rounds half away from zero", Money(1999).Percent(50), 1000},
+		{"Percent of a negative amount", Money(-1999).Percent(50), -1000},
+		{"Prorate", Money(100).Prorate(1, 4), 25},
+		{"Prorate rounds down", Money(100).Prorate(1, 3), 33},
+		{"Prorate rounds up", Money(100).Prorate(2, 3), 67},
+		{"Prorate rounds half away from zero", Money(1).Prorate(1, 2), 1},
+		{"Prorate a negative amount", Money(-1).Prorate(1, 2), -1},
+		{"Prorate the whole", Money(1999).Prorate(7, 7), 1999},
+		{"Prorate without overflow", Money(9223372036854775807).Prorate(1, 2), 4611686018427387904},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// TestProrateRemainder shares an amount out the way the services do, prorating the running sum of the parts
+// and taking away what was already shared, so the rounding remainders never get lost
+func TestProrateRemainder(t *testing.T) {
+	tests := []struct {
+		amount Money
+		parts  []Money
+		want   []Money
+	}{
+		{100, []Money{1, 1, 1}, []Money{33, 34, 33}},
+		{1, []Money{1, 1, 1}, []Money{0, 1, 0}},
+		{1000, []Money{1999, 1, 8000}, []Money{200, 0, 800}},
+		{-100, []Money{1, 1, 1}, []Money{-33, -34, -33}},
+		{0, []Money{5, 5}, []Money{0, 0}},
+	}
+	for _, tt := range tests {
+		var whole Money
+		for _, part := range tt.parts {
+			whole += part
+		}
+
+		var counted, shared, total Money
+		for i, part := range tt.parts {
+			counted += part
+			share := tt.amount.Prorate(counted, whole) - shared
+			shared += share
+			total += share
+
+			if share != tt.want[i] {
+				t.Errorf("sharing %d by %v: share %d is %d, want %d", tt.amount, tt.parts, i, share, tt.want[i])
+			}
+		}
+		if total != tt.amount {
+			t.Errorf("sharing %d by %v: the shares add up to %d", tt.amount, tt.parts, total)
+		}
+	}
+}
+
+func TestIsSupportedCurrency(t *testing.T) {
+	for code, want := range map[string]bool{
+		DefaultCurrency: true,
+		"EUR":           true,
+		"UZS":           true,
+		"JPY":           false, // No minor units
+		"KWD":           false, // Thousandths
+		"BHD":           false,
+		"usd":           false,
+		"":              false,
+	} {
+		if got := IsSupportedCurrency(code); got != want {
+			t.Errorf("IsSupportedCurrency(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
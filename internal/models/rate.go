@@ -0,0 +1,84 @@
+package models
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+)
+
+// Rate is an exchange rate, the amount of one currency a unit of another currency buys, in units of 10^-10.
+// Ten decimal places keep the rates between currencies as far apart in value as UZS and USD precise
+// in both directions.
+type Rate int64
+
+// rateDecimals is the number of decimal places of a Rate
+const rateDecimals = 10
+
+// rateScale is the number of Rate units in a rate of 1
+const rateScale = 10_000_000_000
+
+// UnitRate is the rate between a currency and itself
+const UnitRate Rate = rateScale
+
+// Errors returned by NewRate
+var (
+	ErrInvalidRate  = errs.Validation("exchange rate must be greater than zero")
+	ErrRateTooLarge = errs.Validation("exchange rate is too large")
+)
+
+// NewRate rounds an exact rate to the nearest Rate, rates too small to round to one unit are rejected
+func NewRate(rate *big.Rat) (Rate, error) {
+	if rate.Sign() <= 0 {
+		return 0, ErrInvalidRate
+	}
+
+	units := round(new(big.Rat).Mul(rate, big.NewRat(rateScale, 1)))
+	if !units.IsInt64() {
+		return 0, ErrRateTooLarge
+	}
+	if units.Sign() == 0 {
+		return 0, ErrInvalidRate
+	}
+	return Rate(units.Int64()), nil
+}
+
+// Convert returns what amount is worth at the rate, rounded to the nearest minor unit
+func (r Rate) Convert(amount Money) (Money, error) {
+	converted := new(big.Int).Mul(big.NewInt(int64(amount)), big.NewInt(int64(r)))
+	units := round(new(big.Rat).SetFrac(converted, big.NewInt(rateScale)))
+	if !units.IsInt64() {
+		return 0, ErrAmountTooLarge
+	}
+	return Money(units.Int64()), nil
+}
+
+// String returns the rate as the shortest decimal number, such as 12650.5
+func (r Rate) String() string {
+	fraction := strconv.FormatInt(int64(r)%rateScale, 10)
+	fraction = strings.TrimRight(strings.Repeat("0", rateDecimals-len(fraction))+fraction, "0")
+
+	units := strconv.FormatInt(int64(r)/rateScale, 10)
+	if fraction == "" {
+		return units
+	}
+	return units + "." + fraction
+}
+
+// MarshalJSON writes the rate as a plain decimal number
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// round returns the integer nearest to x, halves are rounded away from zero
+func round(x *big.Rat) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if remainder.Abs(remainder).Lsh(remainder, 1).Cmp(x.Denom()) >= 0 {
+		if x.Sign() < 0 {
+			return quotient.Sub(quotient, big.NewInt(1))
+		}
+		return quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient
+}
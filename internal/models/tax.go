@@ -0,0 +1,100 @@
+package models
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+)
+
+// Tax modes: inclusive prices already hold the tax, exclusive prices get it added on top
+const (
+	TaxInclusive = "inclusive"
+	TaxExclusive = "exclusive"
+)
+
+// DefaultTaxClass is the tax class of the products created without one and of the products stored
+// before products had one
+const DefaultTaxClass = "standard"
+
+// TaxRate is a tax rate in percent, in units of 10^-4 percent: 72500 is 7.25%.
+// Four decimal places hold every rate in use, such as the 8.875% of New York City.
+type TaxRate int64
+
+// taxRateDecimals is the number of decimal places of a TaxRate in percent
+const taxRateDecimals = 4
+
+// taxRatePercent is the number of TaxRate units in a rate of 1%
+const taxRatePercent = 10_000
+
+// maxTaxRate is the highest rate accepted, 1000%
+const maxTaxRate = 1000 * taxRatePercent
+
+// Errors returned by ParseTaxRate
+var (
+	ErrInvalidTaxRate    = errs.Validation("tax rate is not a decimal number of zero or more")
+	ErrTaxRateTooPrecise = errs.Validation("tax rate has more than 4 decimal places")
+	ErrTaxRateTooLarge   = errs.Validation("tax rate is more than 1000%")
+)
+
+// ParseTaxRate reads a rate in percent, such as "12" or "7.25". Rates that don't fit a TaxRate exactly
+// are rejected rather than rounded.
+func ParseTaxRate(s string) (TaxRate, error) {
+	// Like amounts, rates are plain decimals, see ParseMoney
+	if s == "" || strings.Trim(s, "0123456789.") != "" {
+		return 0, ErrInvalidTaxRate
+	}
+
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, ErrInvalidTaxRate
+	}
+
+	rate.Mul(rate, big.NewRat(taxRatePercent, 1))
+	if !rate.IsInt() {
+		return 0, ErrTaxRateTooPrecise
+	}
+	if !rate.Num().IsInt64() || rate.Num().Int64() > maxTaxRate {
+		return 0, ErrTaxRateTooLarge
+	}
+	return TaxRate(rate.Num().Int64()), nil
+}
+
+// AddedTo returns the tax charged on top of a net amount, rounded to the nearest minor unit
+func (r TaxRate) AddedTo(net Money) (Money, error) {
+	tax := new(big.Int).Mul(big.NewInt(int64(net)), big.NewInt(int64(r)))
+	return roundMoney(new(big.Rat).SetFrac(tax, big.NewInt(100*taxRatePercent)))
+}
+
+// IncludedIn returns the part of a gross amount that is tax, rounded to the nearest minor unit
+func (r TaxRate) IncludedIn(gross Money) (Money, error) {
+	tax := new(big.Int).Mul(big.NewInt(int64(gross)), big.NewInt(int64(r)))
+	return roundMoney(new(big.Rat).SetFrac(tax, big.NewInt(100*taxRatePercent+int64(r))))
+}
+
+// String returns the rate in percent as the shortest decimal number, such as 7.25
+func (r TaxRate) String() string {
+	fraction := strconv.FormatInt(int64(r)%taxRatePercent, 10)
+	fraction = strings.TrimRight(strings.Repeat("0", taxRateDecimals-len(fraction))+fraction, "0")
+
+	units := strconv.FormatInt(int64(r)/taxRatePercent, 10)
+	if fraction == "" {
+		return units
+	}
+	return units + "." + fraction
+}
+
+// MarshalJSON writes the rate in percent as a plain decimal number
+func (r TaxRate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// roundMoney rounds an exact number of minor units to the nearest amount
+func roundMoney(units *big.Rat) (Money, error) {
+	rounded := round(units)
+	if !rounded.IsInt64() {
+		return 0, ErrAmountTooLarge
+	}
+	return Money(rounded.Int64()), nil
+}
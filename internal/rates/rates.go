@@ -0,0 +1,122 @@
+// Package rates provides the exchange rates prices are converted between currencies with.
+//
+// Rates are read from a JSON file listing how much of every currency a unit of a base currency buys:
+//
+//	{
+//	  "base": "USD",
+//	  "rates": {"UZS": 12650.5, "EUR": 0.92}
+//	}
+//
+// The rate between two currencies other than the base one is derived from their rates against the base.
+// Static serves the rates a file held when it was loaded, FileWatcher follows the changes made to the file.
+package rates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+)
+
+// ExchangeRateProvider tells how much of one currency a unit of another currency buys
+type ExchangeRateProvider interface {
+	// Rate returns the rate converting amounts in from into amounts in to.
+	// Currencies without a rate are reported with an errs.ErrValidation error.
+	Rate(ctx context.Context, from, to string) (models.Rate, error)
+}
+
+// Static serves a fixed set of rates against a base currency
+type Static struct {
+	base  string
+	rates map[string]*big.Rat
+}
+
+// NewStatic serves rates, the amount of each currency a unit of base buys.
+// Without any rates only the amounts already in the currency asked for can be converted.
+func NewStatic(base string, rates map[string]*big.Rat) *Static {
+	return &Static{
+		base:  base,
+		rates: rates,
+	}
+}
+
+// LoadFile reads the rates of a JSON file in the format described in the package documentation
+func LoadFile(path string) (*Static, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates: %w", err)
+	}
+
+	static, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exchange rates file %s: %w", path, err)
+	}
+	return static, nil
+}
+
+// Parse reads rates in the JSON format described in the package documentation.
+// The rates are read as exact decimals, not as floats.
+func Parse(data []byte) (*Static, error) {
+	var file struct {
+		Base  string                 `json:"base"`
+		Rates map[string]json.Number `json:"rates"`
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+
+	if !models.IsSupportedCurrency(file.Base) {
+		return nil, fmt.Errorf("base currency %q is not a supported ISO 4217 code", file.Base)
+	}
+
+	rates := make(map[string]*big.Rat, len(file.Rates))
+	for currency, number := range file.Rates {
+		if !models.IsSupportedCurrency(currency) {
+			return nil, fmt.Errorf("currency %q is not a supported ISO 4217 code", currency)
+		}
+
+		rate, ok := new(big.Rat).SetString(number.String())
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("rate %s of %s is not a positive number", number, currency)
+		}
+		rates[currency] = rate
+	}
+
+	return NewStatic(file.Base, rates), nil
+}
+
+// Rate derives the rate between two currencies from their rates against the base currency
+func (s *Static) Rate(_ context.Context, from, to string) (models.Rate, error) {
+	if from == to {
+		return models.UnitRate, nil
+	}
+
+	fromRate, ok := s.against(from)
+	if !ok {
+		return 0, errs.Validation(fmt.Sprintf("no exchange rate from %s to %s", from, to))
+	}
+	toRate, ok := s.against(to)
+	if !ok {
+		return 0, errs.Validation(fmt.Sprintf("no exchange rate from %s to %s", from, to))
+	}
+
+	return models.NewRate(new(big.Rat).Quo(toRate, fromRate))
+}
+
+// against returns the amount of currency a unit of the base currency buys
+func (s *Static) against(currency string) (*big.Rat, bool) {
+	if currency == s.base {
+		return big.NewRat(1, 1), true
+	}
+	rate, ok := s.rates[currency]
+	return rate, ok
+}
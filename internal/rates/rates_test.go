@@ -0,0 +1,129 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestStaticRates(t *testing.T) {
+	static, err := Parse([]byte(`{"base": "USD", "rates": {"UZS": 12650.5, "EUR": 0.92}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from, to string
+		amount   models.Money
+		want     models.Money
+	}{
+		{"USD", "USD", 1999, 1999},
+		{"USD", "UZS", 100, 1265050},
+		{"UZS", "USD", 1265050, 100},
+		{"UZS", "USD", 1000000000, 79048}, // 10 000 000.00 UZS is 790.48 USD
+		{"EUR", "UZS", 100, 1375054},      // Through the base currency: 12650.5 / 0.92
+		{"ZZZ", "ZZZ", 500, 500},          // Any currency converts to itself
+	}
+	for _, tt := range tests {
+		rate, err := static.Rate(context.Background(), tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("%s to %s: %v", tt.from, tt.to, err)
+		}
+		got, err := rate.Convert(tt.amount)
+		if err != nil {
+			t.Fatalf("%s to %s: %v", tt.from, tt.to, err)
+		}
+		if got != tt.want {
+			t.Errorf("%v %s is %v %s, want %v", tt.amount, tt.from, got, tt.to, tt.want)
+		}
+	}
+
+	if _, err := static.Rate(context.Background(), "USD", "GBP"); !errors.Is(err, errs.ErrValidation) {
+		t.Fatalf("got error %v for a currency without a rate, want a validation error", err)
+	}
+}
+
+func TestParseRejectsInvalidRates(t *testing.T) {
+	for _, data := range []string{
+		`{"base": "usd", "rates": {}}`,
+		`{"base": "USD", "rates": {"UZS": 0}}`,
+		`{"base": "USD", "rates": {"UZS": -1}}`,
+		`{"base": "USD", "rates": {"Sum": 12650}}`,
+		`{"base": "USD", "rates": {"JPY": 150}}`, // No minor units, amounts in yen can't be held
+		`{"base": "USD", "rate": {"UZS": 12650}}`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("parsed %s", data)
+		}
+	}
+}
+
+func TestFileWatcherReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	writeRates(t, path, `{"base": "USD", "rates": {"UZS": 12000}}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcher, err := WatchFile(ctx, path, 10*time.Millisecond, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireRate(t, watcher, "12000")
+
+	// A broken file keeps the rates loaded before
+	writeRates(t, path, `{"base": "USD", "rates": {"UZS": "soon"}}`)
+	time.Sleep(50 * time.Millisecond)
+	requireRate(t, watcher, "12000")
+
+	writeRates(t, path, `{"base": "USD", "rates": {"UZS": 12650.5}}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rate, err := watcher.Rate(ctx, "USD", "UZS")
+		if err == nil && rate.String() == "12650.5" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got rate %v, want the reloaded 12650.5", rate)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// writeRates replaces the file at path, with a modification time apart from the one it had
+func writeRates(t *testing.T, path, data string) {
+	t.Helper()
+
+	modTime := time.Now()
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime().Add(time.Second)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func requireRate(t *testing.T, provider ExchangeRateProvider, want string) {
+	t.Helper()
+
+	rate, err := provider.Rate(context.Background(), "USD", "UZS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate.String() != want {
+		t.Fatalf("got rate %v, want %s", rate, want)
+	}
+}
@@ -0,0 +1,91 @@
+package rates
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+)
+
+// FileWatcher serves the rates of a file and picks up the changes made to it without a restart.
+// The file is checked every interval, which also catches files replaced rather than written to,
+// as editors and mounted config maps do. A change that can't be loaded is logged and the rates
+// loaded before stay in use.
+type FileWatcher struct {
+	path   string
+	logger *slog.Logger
+
+	current atomic.Pointer[Static]
+
+	// Only touched by the goroutine watching the file
+	modTime time.Time
+	size    int64
+}
+
+// WatchFile loads the rates of the file at path and keeps them up to date until ctx is done.
+// The file must load on the first try.
+func WatchFile(ctx context.Context, path string, interval time.Duration, logger *slog.Logger) (*FileWatcher, error) {
+	w := &FileWatcher{
+		path:   path,
+		logger: logger,
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	static, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w.current.Store(static)
+	w.modTime, w.size = info.ModTime(), info.Size()
+
+	go w.watch(ctx, interval)
+	return w, nil
+}
+
+// Rate converts with the rates loaded last
+func (w *FileWatcher) Rate(ctx context.Context, from, to string) (models.Rate, error) {
+	return w.current.Load().Rate(ctx, from, to)
+}
+
+func (w *FileWatcher) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+// reload loads the file again when it changed since it was loaded last
+func (w *FileWatcher) reload() {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		w.logger.Error("failed to check exchange rates file", "path", w.path, "error", err)
+		return
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return
+	}
+
+	// A broken file is reported once rather than on every check, and loaded again once it changes
+	w.modTime, w.size = info.ModTime(), info.Size()
+
+	static, err := LoadFile(w.path)
+	if err != nil {
+		w.logger.Error("failed to reload exchange rates, keeping the previous ones", "path", w.path, "error", err)
+		return
+	}
+	w.current.Store(static)
+	w.logger.Info("exchange rates reloaded", "path", w.path)
+}
@@ -7,22 +7,70 @@ import (
 	"github.com/abdulazizax/udevslab-lesson3/internal/models"
 )
 
+// Transactor runs a unit of work atomically. The repositories take part in the transaction when they are
+// called with the context passed to fn. fn may run more than once when a transient error forces a retry,
+// so it must not have effects outside the repositories. Calls nested in a transaction join it.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type PromotionRepo interface {
+	CreatePromotion(ctx context.Context, promotion *models.PromotionCreate) (string, error)
+	GetPromotionByID(ctx context.Context, promotionID string) (*models.Promotion, error)
+	GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error)
+	DeletePromotion(ctx context.Context, promotionID string) error
+	ListPromotions(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Promotion], error)
+	// RedeemPromotion counts a use of the promotion by the user, unless it would exceed the usage limit
+	// or the per user limit of the promotion. The check and the count are a single atomic step.
+	RedeemPromotion(ctx context.Context, promotionID, userID string) error
+	// ReleasePromotion takes back a use counted by RedeemPromotion
+	ReleasePromotion(ctx context.Context, promotionID, userID string) error
+}
+
 type OrderRepo interface {
-	CreateOrder(ctx context.Context, total float64, order *models.OrderCreate) (string, error)
+	CreateOrder(ctx context.Context, order *models.Order) (string, error)
 	GetOrderByID(ctx context.Context, orderID string) (*models.Order, error)
-	UpdateOrder(ctx context.Context, total float64, orderID string, updates *models.OrderUpdate) (string, error)
-	DeleteOrder(ctx context.Context, orderID string) error
-	ListOrders(ctx context.Context, pagination *models.Pagination) ([]models.Order, error)
-	ListOrdersByDateRange(ctx context.Context, order int8, pagination *models.Pagination, startDate, endDate time.Time) ([]models.Order, error)
+	UpdateOrder(ctx context.Context, orderID string, version int64, updates *models.UpdatedOrder) error
+	DeleteOrder(ctx context.Context, orderID string, version int64) error
+	TransitionOrderStatus(ctx context.Context, orderID, from, to string) (*models.Order, error)
+	ListOrders(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Order], error)
+	ListOrdersByUser(ctx context.Context, userID string, pagination *models.Pagination) (*models.Page[models.Order], error)
+	ListOrdersByDateRange(ctx context.Context, order int8, pagination *models.Pagination, startDate, endDate time.Time) (*models.Page[models.Order], error)
+	ListOrdersWithUsers(ctx context.Context, pagination *models.Pagination) (*models.Page[models.OrderWithUser], error)
 }
 
 type ProductRepo interface {
 	CreateProduct(ctx context.Context, product *models.ProductCreate) (string, error)
 	GetProductByID(ctx context.Context, productID string) (*models.Product, error)
-	UpdateProduct(ctx context.Context, productID string, updates *models.ProductUpdate) error
-	DeleteProduct(ctx context.Context, productID string) error
-	ListProducts(ctx context.Context, pagination *models.Pagination) ([]models.Product, error)
-	SearchProductsByName(ctx context.Context, name string, pagination *models.Pagination) ([]models.Product, error)
-	ExactSearchProductsByPrice(ctx context.Context, price float64, pagination *models.Pagination) ([]models.Product, error)
-	SearchProductsByPriceRange(ctx context.Context, order int8, minPrice, maxPrice float64, pagination *models.Pagination) ([]models.Product, error)
+	UpdateProduct(ctx context.Context, productID string, version int64, updates *models.ProductUpdate) error
+	PatchProduct(ctx context.Context, productID string, version int64, patch *models.ProductPatch) error
+	DeleteProduct(ctx context.Context, productID string, version int64) error
+	ReserveStock(ctx context.Context, productID string, quantity int) error
+	ReleaseStock(ctx context.Context, productID string, quantity int) error
+	ListProducts(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Product], error)
+	SearchProductsByName(ctx context.Context, name string, pagination *models.Pagination) (*models.Page[models.Product], error)
+	ExactSearchProductsByPrice(ctx context.Context, price models.Money, currency string, pagination *models.Pagination) (*models.Page[models.Product], error)
+	SearchProductsByPriceRange(ctx context.Context, order int8, minPrice, maxPrice models.Money, currency string, pagination *models.Pagination) (*models.Page[models.Product], error)
+}
+
+type UserRepo interface {
+	CreateUser(ctx context.Context, passwordHash string, user *models.UserCreate) (string, error)
+	GetUserByID(ctx context.Context, userID string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateUser(ctx context.Context, userID string, updates *models.UserUpdate) error
+	DeleteUser(ctx context.Context, userID string) error
+	ListUsers(ctx context.Context, pagination *models.Pagination) (*models.Page[models.User], error)
+}
+
+type ReportRepo interface {
+	GetReport(ctx context.Context) (*models.Report, error)
+	GetTopProducts(ctx context.Context, limit int) ([]models.TopProduct, error)
+	GetDailyOrderAggregates(ctx context.Context, startDate, endDate time.Time) ([]models.OrderAggregate, error)
+}
+
+type CartRepo interface {
+	GetCartByUser(ctx context.Context, userID string) (*models.Cart, error)
+	SetCartItem(ctx context.Context, userID, productID string, quantity int) error
+	RemoveCartItem(ctx context.Context, userID, productID string) error
+	DeleteCart(ctx context.Context, userID string) error
 }
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the email or the password doesn't match a user
+	ErrInvalidCredentials = errs.Unauthorized("invalid email or password")
+	// ErrInvalidToken is returned for tokens that are malformed, expired, badly signed or of the wrong type
+	ErrInvalidToken = errs.Unauthorized("invalid or expired token")
+	// ErrForbidden is returned when the actor isn't allowed to access a resource
+	ErrForbidden = errs.Forbidden("you don't have permission to perform this action")
+)
+
+// Actor identifies the authenticated user a request is made on behalf of
+type Actor struct {
+	UserID string
+	Role   string
+}
+
+// IsStaff reports whether the actor may manage the catalogue and the data of other users
+func (a Actor) IsStaff() bool {
+	return a.Role == models.RoleAdmin || a.Role == models.RoleStaff
+}
+
+// CanAccess reports whether the actor may access data owned by the given user
+func (a Actor) CanAccess(ownerID string) bool {
+	return a.IsStaff() || a.UserID == ownerID
+}
+
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
+// tokenClaims are the claims of both access and refresh tokens, the subject holds the user ID.
+// The role is copied into the token, so a role change takes effect once the access token is refreshed.
+type tokenClaims struct {
+	Type string `json:"typ"`
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+type AuthService struct {
+	logger   *slog.Logger
+	userRepo repos.UserRepo
+	cfg      config.AuthConfig
+}
+
+func NewAuthService(logger *slog.Logger, userRepo repos.UserRepo, cfg config.AuthConfig) *AuthService {
+	return &AuthService{
+		logger:   logger,
+		userRepo: userRepo,
+		cfg:      cfg,
+	}
+}
+
+// Login checks the credentials of a user and issues a new token pair
+func (s *AuthService) Login(ctx context.Context, email, password string) (*models.TokenPair, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.issueTokens(user)
+}
+
+// Refresh exchanges a valid refresh token for a new token pair
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	actor, err := s.parseToken(refreshToken, refreshTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deleted users must not be able to keep their session alive, the fresh lookup also picks up role changes
+	user, err := s.userRepo.GetUserByID(ctx, actor.UserID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return s.issueTokens(user)
+}
+
+// ParseAccessToken validates an access token and returns the user it was issued to
+func (s *AuthService) ParseAccessToken(token string) (Actor, error) {
+	return s.parseToken(token, accessTokenType)
+}
+
+func (s *AuthService) issueTokens(user *models.User) (*models.TokenPair, error) {
+	actor := Actor{UserID: user.ID.Hex(), Role: user.Role}
+	// Accounts created before roles were introduced are customers
+	if actor.Role == "" {
+		actor.Role = models.RoleCustomer
+	}
+
+	accessToken, err := s.signToken(actor, accessTokenType, s.cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.signToken(actor, refreshTokenType, s.cfg.RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.cfg.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (s *AuthService) signToken(actor Actor, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := tokenClaims{
+		Type: tokenType,
+		Role: actor.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   actor.UserID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.cfg.JWTSecret))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signed, nil
+}
+
+func (s *AuthService) parseToken(token, tokenType string) (Actor, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return []byte(s.cfg.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		s.logger.Warn("rejected token", "error", err)
+		return Actor{}, ErrInvalidToken
+	}
+
+	if claims.Type != tokenType || claims.Subject == "" {
+		return Actor{}, ErrInvalidToken
+	}
+
+	return Actor{UserID: claims.Subject, Role: claims.Role}, nil
+}
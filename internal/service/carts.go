@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/rates"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/validation"
+)
+
+// ErrEmptyCart is returned when a cart without products is checked out
+var ErrEmptyCart = errs.Validation("cart is empty")
+
+type CartService struct {
+	logger        *slog.Logger
+	cartRepo      repos.CartRepo
+	productRepo   repos.ProductRepo
+	orderService  *OrderService
+	transactor    repos.Transactor
+	exchangeRates rates.ExchangeRateProvider
+}
+
+func NewCartService(logger *slog.Logger, cartRepo repos.CartRepo, productRepo repos.ProductRepo, orderService *OrderService, transactor repos.Transactor, exchangeRates rates.ExchangeRateProvider) *CartService {
+	return &CartService{
+		logger:        logger,
+		cartRepo:      cartRepo,
+		productRepo:   productRepo,
+		orderService:  orderService,
+		transactor:    transactor,
+		exchangeRates: exchangeRates,
+	}
+}
+
+// GetCart returns the cart of a user priced with the current product prices, users without a cart get an empty one
+func (s *CartService) GetCart(ctx context.Context, userID string) (*models.Cart, error) {
+	cart, err := s.cartRepo.GetCartByUser(ctx, userID)
+	if errors.Is(err, errs.ErrNotFound) {
+		id, err := models.ParseID(userID)
+		if err != nil {
+			return nil, errs.InvalidID("invalid user ID format", err)
+		}
+		return &models.Cart{UserID: id, Items: []models.CartItem{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.priceCart(ctx, cart); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+// AddItem adds units of a product to the cart, on top of the units already there
+func (s *CartService) AddItem(ctx context.Context, userID string, item *models.CartItemCreate) (*models.Cart, error) {
+	if err := validation.Struct(item); err != nil {
+		return nil, err
+	}
+
+	quantity := item.Quantity
+	cart, err := s.cartRepo.GetCartByUser(ctx, userID)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return nil, err
+	}
+	if cart != nil {
+		for _, line := range cart.Items {
+			if line.ProductID == item.ProductID {
+				quantity += line.Quantity
+			}
+		}
+	}
+
+	return s.setItem(ctx, userID, item.ProductID.Hex(), quantity)
+}
+
+// UpdateItem replaces the number of units of a product in the cart
+func (s *CartService) UpdateItem(ctx context.Context, userID, productID string, quantity int) (*models.Cart, error) {
+	if err := validation.Struct(&models.CartItemUpdate{Quantity: quantity}); err != nil {
+		return nil, err
+	}
+	return s.setItem(ctx, userID, productID, quantity)
+}
+
+// RemoveItem takes a product out of the cart
+func (s *CartService) RemoveItem(ctx context.Context, userID, productID string) (*models.Cart, error) {
+	if err := s.cartRepo.RemoveCartItem(ctx, userID, productID); err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, userID)
+}
+
+// ClearCart empties the cart of a user
+func (s *CartService) ClearCart(ctx context.Context, userID string) error {
+	return s.cartRepo.DeleteCart(ctx, userID)
+}
+
+// Checkout turns the cart into an order, priced and reserved by OrderService, and empties the cart.
+// Reading the cart, placing the order and emptying the cart form one unit of work.
+// A coupon code applies its promotion to the order, which is taxed in the region asked for.
+func (s *CartService) Checkout(ctx context.Context, userID string, checkout *models.CartCheckout) (string, error) {
+	var orderID string
+	err := s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		cart, err := s.cartRepo.GetCartByUser(ctx, userID)
+		if errors.Is(err, errs.ErrNotFound) {
+			return ErrEmptyCart
+		}
+		if err != nil {
+			return err
+		}
+		if len(cart.Items) == 0 {
+			return ErrEmptyCart
+		}
+
+		order := &models.OrderCreate{Items: make([]models.OrderItemCreate, 0, len(cart.Items)), CouponCode: checkout.CouponCode, Region: checkout.Region}
+		for _, item := range cart.Items {
+			order.Items = append(order.Items, models.OrderItemCreate{ProductID: item.ProductID, Quantity: item.Quantity})
+		}
+
+		orderID, err = s.orderService.CreateOrder(ctx, userID, order)
+		if err != nil {
+			return err
+		}
+
+		// Without a transaction to roll back the order stands even if the cart can't be emptied,
+		// the cart expires on its own eventually
+		if err := s.cartRepo.DeleteCart(ctx, userID); err != nil {
+			s.logger.Error("failed to empty cart after checkout", "userID", userID, "orderID", orderID, "error", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return orderID, nil
+}
+
+// setItem checks the product has enough units in stock before storing the new quantity of its line
+func (s *CartService) setItem(ctx context.Context, userID, productID string, quantity int) (*models.Cart, error) {
+	product, err := s.productRepo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if product.Stock < quantity {
+		return nil, &errs.InsufficientStockError{ProductID: productID, Requested: quantity}
+	}
+
+	// A product without an exchange rate to the currency of the cart would leave the cart impossible to price
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Currency != "" {
+		if _, err := s.exchangeRates.Rate(ctx, product.Currency, cart.Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.cartRepo.SetCartItem(ctx, userID, productID, quantity); err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, userID)
+}
+
+// priceCart fills in the current name, price and availability of every line and the cart total.
+// The cart is priced in the currency of its first product, like the order it turns into, other products
+// are converted at the current exchange rates.
+// Lines for products that were deleted stay in the cart, marked as out of stock, until the user removes them.
+func (s *CartService) priceCart(ctx context.Context, cart *models.Cart) error {
+	cart.Total, cart.Currency = 0, ""
+	for i := range cart.Items {
+		item := &cart.Items[i]
+
+		product, err := s.productRepo.GetProductByID(ctx, item.ProductID.Hex())
+		if errors.Is(err, errs.ErrNotFound) {
+			item.InStock = false
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if cart.Currency == "" {
+			cart.Currency = product.Currency
+		}
+
+		rate, err := s.exchangeRates.Rate(ctx, product.Currency, cart.Currency)
+		if err != nil {
+			return err
+		}
+		if item.UnitPrice, err = rate.Convert(product.Price); err != nil {
+			return err
+		}
+
+		item.Name = product.Name
+		item.LineTotal = item.UnitPrice.Mul(item.Quantity)
+		item.InStock = product.Stock >= item.Quantity
+		cart.Total = cart.Total.Add(item.LineTotal)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+)
+
+func TestGetCartWithoutCart(t *testing.T) {
+	orders, repo := newTestOrderService(t)
+	s := NewCartService(testLogger, repo.CartRepo(), repo.ProductRepo(), orders, repo.Transactor(), orders.exchangeRates)
+	userID := models.NewID()
+
+	cart, err := s.GetCart(ctx, userID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The empty cart has nothing but its user and no items, it isn't stored yet
+	data, err := json.Marshal(cart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"id", "createdAt", "updatedAt", "expiresAt"} {
+		if _, ok := fields[field]; ok {
+			t.Errorf("the empty cart has %s in %s", field, data)
+		}
+	}
+	if fields["userId"] != userID.Hex() || fields["items"] == nil {
+		t.Errorf("got empty cart %s", data)
+	}
+
+	if _, err := s.GetCart(ctx, "not-an-id"); !errors.Is(err, errs.ErrInvalidID) {
+		t.Fatalf("got error %v for an invalid user ID, want an invalid ID error", err)
+	}
+}
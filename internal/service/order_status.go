@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+)
+
+// ErrOrderLocked is returned when an order is modified after it left the pending status
+var ErrOrderLocked = errs.Conflict("order can only be modified while it is pending")
+
+// orderTransitions lists the statuses every order status is allowed to move to
+var orderTransitions = map[string][]string{
+	models.OrderStatusPending:   {models.OrderStatusPaid, models.OrderStatusCancelled},
+	models.OrderStatusPaid:      {models.OrderStatusShipped, models.OrderStatusCancelled, models.OrderStatusRefunded},
+	models.OrderStatusShipped:   {models.OrderStatusDelivered},
+	models.OrderStatusDelivered: {models.OrderStatusRefunded},
+}
+
+// CanTransition reports whether an order may move from one status to another
+func CanTransition(from, to string) bool {
+	return slices.Contains(orderTransitions[from], to)
+}
+
+// holdsStock reports whether the units of an order in the given status are still reserved in the warehouse.
+// Once an order has shipped the units are gone, so cancelling or deleting it must not put them back on sale.
+func holdsStock(status string) bool {
+	return status == models.OrderStatusPending || status == models.OrderStatusPaid
+}
+
+// calledOff reports whether an order in the given status was cancelled or refunded, which gave up its promotion uses
+func calledOff(status string) bool {
+	return status == models.OrderStatusCancelled || status == models.OrderStatusRefunded
+}
+
+// TransitionOrder moves an order to the given status and releases its stock and promotion uses when the order is called off.
+// Customers may only cancel their own orders, every other transition is up to staff.
+func (s *OrderService) TransitionOrder(ctx context.Context, actor Actor, orderID, to string) (*models.Order, error) {
+	var order *models.Order
+	err := s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		current, err := s.orderRepo.GetOrderByID(ctx, orderID)
+		if err != nil {
+			return err
+		}
+
+		if !actor.IsStaff() && (current.UserID.Hex() != actor.UserID || to != models.OrderStatusCancelled) {
+			return ErrForbidden
+		}
+
+		if !CanTransition(current.Status, to) {
+			return &errs.InvalidTransitionError{From: current.Status, To: to}
+		}
+
+		order, err = s.orderRepo.TransitionOrderStatus(ctx, orderID, current.Status, to)
+		if err != nil {
+			return err
+		}
+
+		if calledOff(to) {
+			if holdsStock(current.Status) {
+				s.releaseItems(ctx, order.Items)
+			}
+			s.releasePromotions(ctx, order.UserID.Hex(), order.Discounts)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
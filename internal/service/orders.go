@@ -5,54 +5,431 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
 	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/rates"
 	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/tax"
+	"github.com/abdulazizax/udevslab-lesson3/internal/validation"
 )
 
+var (
+	// ErrInvalidQuantity is returned when an order asks for zero or less units of a product
+	ErrInvalidQuantity = errs.Validation("quantity must be greater than zero")
+	// ErrEmptyOrder is returned when an order has no lines
+	ErrEmptyOrder = errs.Validation("order must contain at least one item")
+	// ErrOrderChanged is returned when an order is no longer at the version the client expected
+	ErrOrderChanged = errs.PreconditionFailed("order was changed since it was read")
+)
+
+// OrderService runs every change to an order together with the stock and promotion usage changes it causes
+// in one unit of work. Both are still put back by hand when a later step fails, for storages that can't roll back.
 type OrderService struct {
-	logger      *slog.Logger
-	orderRepo   repos.OrderRepo
-	productRepo repos.ProductRepo
+	logger        *slog.Logger
+	orderRepo     repos.OrderRepo
+	productRepo   repos.ProductRepo
+	userRepo      repos.UserRepo
+	promotionRepo repos.PromotionRepo
+	transactor    repos.Transactor
+	exchangeRates rates.ExchangeRateProvider
+	taxRates      *tax.Rates
 }
 
-func NewOrderService(logger *slog.Logger, orderRepo repos.OrderRepo, productRepo repos.ProductRepo) *OrderService {
+func NewOrderService(logger *slog.Logger, orderRepo repos.OrderRepo, productRepo repos.ProductRepo, userRepo repos.UserRepo, promotionRepo repos.PromotionRepo, transactor repos.Transactor, exchangeRates rates.ExchangeRateProvider, taxRates *tax.Rates) *OrderService {
 	return &OrderService{
-		logger:      logger,
-		orderRepo:   orderRepo,
-		productRepo: productRepo,
+		logger:        logger,
+		orderRepo:     orderRepo,
+		productRepo:   productRepo,
+		userRepo:      userRepo,
+		promotionRepo: promotionRepo,
+		transactor:    transactor,
+		exchangeRates: exchangeRates,
+		taxRates:      taxRates,
 	}
 }
 
-func (s *OrderService) CreateOrder(ctx context.Context, order *models.OrderCreate) (string, error) {
-	product, err := s.productRepo.GetProductByID(ctx, order.ProductID.Hex())
+// CreateOrder places an order on behalf of the authenticated user.
+// The stock of every line is reserved or none of it is, at the prices read in the same unit of work.
+// Products priced in another currency than the order are converted at the current exchange rates.
+// A coupon code applies its promotion to the subtotal and counts as a use of it.
+// What is left is taxed at the rates of the region the order asks for.
+func (s *OrderService) CreateOrder(ctx context.Context, userID string, order *models.OrderCreate) (string, error) {
+	if err := validation.Struct(order); err != nil {
+		return "", err
+	}
+	region, err := s.taxRegion(order.Region)
 	if err != nil {
-		return "Product is not exists", err
-	} else {
-		return s.orderRepo.CreateOrder(ctx, product.Price*float64(order.Quantity), order)
+		return "", err
 	}
+
+	var orderID string
+	err = s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		user, err := s.userRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		items, subtotal, currency, err := s.priceItems(ctx, order.Items, nil, order.Currency)
+		if err != nil {
+			return err
+		}
+
+		discounts := []models.AppliedDiscount{}
+		if order.CouponCode != "" {
+			promotion, err := s.promotionForCode(ctx, order.CouponCode)
+			if err != nil {
+				return err
+			}
+			discount, err := s.applyPromotion(ctx, promotion, items, subtotal, currency)
+			if err != nil {
+				return err
+			}
+			discounts = append(discounts, discount)
+		}
+
+		var discount models.Money
+		for _, applied := range discounts {
+			discount = discount.Add(applied.Amount)
+		}
+
+		taxed, err := taxItems(region, items, subtotal, discount)
+		if err != nil {
+			return err
+		}
+
+		if err := s.reserveItems(ctx, items); err != nil {
+			return err
+		}
+		if err := s.redeem(ctx, userID, discounts); err != nil {
+			s.releaseItems(ctx, items)
+			return err
+		}
+
+		orderID, err = s.orderRepo.CreateOrder(ctx, &models.Order{
+			UserID:    user.ID,
+			Items:     items,
+			Subtotal:  subtotal,
+			Discounts: discounts,
+			Discount:  discount,
+			TaxRegion: region.Code,
+			TaxMode:   region.Mode,
+			Taxes:     taxed.taxes,
+			Net:       taxed.net,
+			Tax:       taxed.tax,
+			Total:     taxed.total,
+			Currency:  currency,
+		})
+		if err != nil {
+			// The order was never stored, so the reserved units go back on sale and the promotions are unused
+			s.releaseItems(ctx, items)
+			s.releasePromotions(ctx, userID, discounts)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return orderID, nil
+}
+
+// GetOrderByID fetches an order, customers may only see their own orders
+func (s *OrderService) GetOrderByID(ctx context.Context, actor Actor, orderID string) (*models.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !actor.CanAccess(order.UserID.Hex()) {
+		return nil, ErrForbidden
+	}
+
+	return order, nil
 }
 
-func (s *OrderService) GetOrderByID(ctx context.Context, orderID string) (*models.Order, error) {
-	return s.orderRepo.GetOrderByID(ctx, orderID)
+// UpdateOrder replaces the lines of a pending order that is still at the expected version.
+// Lines for products already on the order keep the unit price captured when they were added,
+// the promotions applied to the order are applied again to the new lines and the order is taxed again
+// at the current rates of its region.
+func (s *OrderService) UpdateOrder(ctx context.Context, orderID string, version int64, updates *models.OrderUpdate) error {
+	if err := validation.Struct(updates); err != nil {
+		return err
+	}
+	return s.replaceItems(ctx, orderID, version, updates.Items, false)
 }
 
-func (s *OrderService) UpdateOrder(ctx context.Context, orderID string, updates *models.OrderUpdate) (string, error) {
-	product, err := s.productRepo.GetProductByID(ctx, updates.ProductID.Hex())
+// PatchOrder applies a merge patch to a pending order that is still at the expected version.
+// The order is only repriced and written when the patch changes its products or quantities.
+func (s *OrderService) PatchOrder(ctx context.Context, orderID string, version int64, patch *models.OrderPatch) error {
+	if err := validation.Struct(patch); err != nil {
+		return err
+	}
+
+	if patch.Items == nil {
+		return s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			_, err := s.patchableOrder(ctx, orderID, version)
+			return err
+		})
+	}
+	return s.replaceItems(ctx, orderID, version, *patch.Items, true)
+}
+
+// replaceItems gives a pending order new lines and moves its stock reservation along.
+// With onlyChanges set, lines that ask for the same quantities of the same products leave the order as it is.
+func (s *OrderService) replaceItems(ctx context.Context, orderID string, version int64, requested []models.OrderItemCreate, onlyChanges bool) error {
+	return s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		current, err := s.patchableOrder(ctx, orderID, version)
+		if err != nil {
+			return err
+		}
+
+		items, subtotal, _, err := s.priceItems(ctx, requested, current.Items, current.Currency)
+		if err != nil {
+			return err
+		}
+
+		if onlyChanges && sameQuantities(current.Items, items) {
+			return nil
+		}
+
+		discounts, discount, err := s.rediscount(ctx, current.Discounts, items, subtotal, current.Currency)
+		if err != nil {
+			return err
+		}
+
+		region, ok := s.taxRates.Region(current.TaxRegion)
+		if !ok {
+			return errs.Validation("the region of the order has no tax rates anymore")
+		}
+		taxed, err := taxItems(region, items, subtotal, discount)
+		if err != nil {
+			return err
+		}
+
+		undo, err := s.adjustStock(ctx, current.Items, items)
+		if err != nil {
+			return err
+		}
+
+		// The order must not change between reading it and writing the new lines
+		updates := &models.UpdatedOrder{
+			Items:     items,
+			Subtotal:  subtotal,
+			Discounts: discounts,
+			Discount:  discount,
+			Taxes:     taxed.taxes,
+			Net:       taxed.net,
+			Tax:       taxed.tax,
+			Total:     taxed.total,
+		}
+		if err := s.orderRepo.UpdateOrder(ctx, orderID, current.Version, updates); err != nil {
+			undo()
+			return raced(err, version, "order")
+		}
+		return nil
+	})
+}
+
+// patchableOrder reads an order that may still be changed: it is pending and at the expected version
+func (s *OrderService) patchableOrder(ctx context.Context, orderID string, version int64) (*models.Order, error) {
+	current, err := s.orderRepo.GetOrderByID(ctx, orderID)
 	if err != nil {
-		return "Product is not exists", err
-	} else {
-		return s.orderRepo.UpdateOrder(ctx, product.Price*float64(updates.Quantity), orderID, updates)
+		return nil, err
+	}
+
+	if version != models.AnyVersion && current.Version != version {
+		return nil, ErrOrderChanged
 	}
+
+	if current.Status != models.OrderStatusPending {
+		return nil, ErrOrderLocked
+	}
+	return current, nil
 }
 
-func (s *OrderService) DeleteOrder(ctx context.Context, orderID string) error {
-	return s.orderRepo.DeleteOrder(ctx, orderID)
+// DeleteOrder deletes an order that is still at the expected version and releases the stock and the promotion uses it holds
+func (s *OrderService) DeleteOrder(ctx context.Context, orderID string, version int64) error {
+	return s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+		if err != nil {
+			return err
+		}
+
+		if version != models.AnyVersion && order.Version != version {
+			return ErrOrderChanged
+		}
+
+		if err := s.orderRepo.DeleteOrder(ctx, orderID, order.Version); err != nil {
+			return raced(err, version, "order")
+		}
+
+		if holdsStock(order.Status) {
+			s.releaseItems(ctx, order.Items)
+		}
+		if !calledOff(order.Status) {
+			s.releasePromotions(ctx, order.UserID.Hex(), order.Discounts)
+		}
+		return nil
+	})
 }
 
-func (s *OrderService) ListOrders(ctx context.Context, pagination *models.Pagination) ([]models.Order, error) {
+// ListOrders lists every order for staff and only their own orders for customers
+func (s *OrderService) ListOrders(ctx context.Context, actor Actor, pagination *models.Pagination) (*models.Page[models.Order], error) {
+	if !actor.IsStaff() {
+		return s.orderRepo.ListOrdersByUser(ctx, actor.UserID, pagination)
+	}
 	return s.orderRepo.ListOrders(ctx, pagination)
 }
 
-func (s *OrderService) ListOrdersByDateRange(ctx context.Context, order int8, pagination *models.Pagination, startDate, endDate time.Time) ([]models.Order, error) {
+func (s *OrderService) ListOrdersWithUsers(ctx context.Context, pagination *models.Pagination) (*models.Page[models.OrderWithUser], error) {
+	return s.orderRepo.ListOrdersWithUsers(ctx, pagination)
+}
+
+func (s *OrderService) ListOrdersByDateRange(ctx context.Context, order int8, pagination *models.Pagination, startDate, endDate time.Time) (*models.Page[models.Order], error) {
 	return s.orderRepo.ListOrdersByDateRange(ctx, order, pagination, startDate, endDate)
-}
\ No newline at end of file
+}
+
+// priceItems validates the requested lines and captures the unit price, line total and tax class of each of them in currency,
+// or in the currency of the first product when it is empty, which is returned with the lines.
+// Lines for the same product are merged, and products found in snapshot keep the prices recorded there.
+func (s *OrderService) priceItems(ctx context.Context, requested []models.OrderItemCreate, snapshot []models.OrderItem, currency string) ([]models.OrderItem, models.Money, string, error) {
+	if len(requested) == 0 {
+		return nil, 0, "", ErrEmptyOrder
+	}
+
+	var items []models.OrderItem
+	positions := make(map[models.ID]int)
+	for _, line := range requested {
+		if line.Quantity <= 0 {
+			return nil, 0, "", ErrInvalidQuantity
+		}
+		if i, ok := positions[line.ProductID]; ok {
+			items[i].Quantity += line.Quantity
+			continue
+		}
+		positions[line.ProductID] = len(items)
+		items = append(items, models.OrderItem{ProductID: line.ProductID, Quantity: line.Quantity})
+	}
+
+	recorded := make(map[models.ID]models.OrderItem, len(snapshot))
+	for _, item := range snapshot {
+		recorded[item.ProductID] = item
+	}
+
+	var total models.Money
+	for i := range items {
+		item := &items[i]
+		if previous, ok := recorded[item.ProductID]; ok {
+			item.UnitPrice, item.PriceCurrency, item.ExchangeRate, item.TaxClass = previous.UnitPrice, previous.PriceCurrency, previous.ExchangeRate, previous.TaxClass
+		} else {
+			product, err := s.productRepo.GetProductByID(ctx, item.ProductID.Hex())
+			if err != nil {
+				return nil, 0, "", err
+			}
+			if currency == "" {
+				currency = product.Currency
+			}
+
+			rate, err := s.exchangeRates.Rate(ctx, product.Currency, currency)
+			if err != nil {
+				return nil, 0, "", err
+			}
+			if item.UnitPrice, err = rate.Convert(product.Price); err != nil {
+				return nil, 0, "", err
+			}
+			item.PriceCurrency, item.ExchangeRate, item.TaxClass = product.Currency, rate, product.TaxClass
+		}
+
+		item.LineTotal = item.UnitPrice.Mul(item.Quantity)
+		total = total.Add(item.LineTotal)
+	}
+
+	return items, total, currency, nil
+}
+
+// sameQuantities reports whether two sets of lines hold the same quantities of the same products, in any order
+func sameQuantities(a, b []models.OrderItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	quantities := make(map[models.ID]int, len(a))
+	for _, item := range a {
+		quantities[item.ProductID] += item.Quantity
+	}
+	for _, item := range b {
+		if quantities[item.ProductID] != item.Quantity {
+			return false
+		}
+	}
+	return true
+}
+
+// adjustStock moves the reservation of an order from its current lines to the new ones.
+// Products that need more units are reserved first, all or nothing, and only then are the freed units released.
+// The returned function reverts the adjustment and is meant to be called when the order update fails.
+func (s *OrderService) adjustStock(ctx context.Context, current, next []models.OrderItem) (func(), error) {
+	var products []models.ID
+	deltas := make(map[models.ID]int)
+	for _, item := range next {
+		products = append(products, item.ProductID)
+		deltas[item.ProductID] += item.Quantity
+	}
+	for _, item := range current {
+		if _, ok := deltas[item.ProductID]; !ok {
+			products = append(products, item.ProductID)
+		}
+		deltas[item.ProductID] -= item.Quantity
+	}
+
+	var reserve, release []models.OrderItem
+	for _, productID := range products {
+		switch delta := deltas[productID]; {
+		case delta > 0:
+			reserve = append(reserve, models.OrderItem{ProductID: productID, Quantity: delta})
+		case delta < 0:
+			release = append(release, models.OrderItem{ProductID: productID, Quantity: -delta})
+		}
+	}
+
+	if err := s.reserveItems(ctx, reserve); err != nil {
+		return nil, err
+	}
+	s.releaseItems(ctx, release)
+
+	return func() {
+		s.releaseItems(ctx, reserve)
+		s.restoreItems(ctx, release)
+	}, nil
+}
+
+// reserveItems reserves the stock of every line. When a line can't be reserved
+// the lines reserved before it are released again, so either all lines hold stock or none does.
+func (s *OrderService) reserveItems(ctx context.Context, items []models.OrderItem) error {
+	for i, item := range items {
+		if err := s.productRepo.ReserveStock(ctx, item.ProductID.Hex(), item.Quantity); err != nil {
+			s.releaseItems(ctx, items[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseItems returns the units of every line to their products, failures are only logged because the caller can't undo them
+func (s *OrderService) releaseItems(ctx context.Context, items []models.OrderItem) {
+	for _, item := range items {
+		if err := s.productRepo.ReleaseStock(ctx, item.ProductID.Hex(), item.Quantity); err != nil {
+			s.logger.Error("failed to release stock", "productID", item.ProductID.Hex(), "quantity", item.Quantity, "error", err)
+		}
+	}
+}
+
+// restoreItems takes the units of every line again while rolling back, failures are only logged
+func (s *OrderService) restoreItems(ctx context.Context, items []models.OrderItem) {
+	for _, item := range items {
+		if err := s.productRepo.ReserveStock(ctx, item.ProductID.Hex(), item.Quantity); err != nil {
+			s.logger.Error("failed to restore stock reservation", "productID", item.ProductID.Hex(), "quantity", item.Quantity, "error", err)
+		}
+	}
+}
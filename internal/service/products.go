@@ -2,52 +2,242 @@ package service
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
 	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/rates"
 	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/tax"
+	"github.com/abdulazizax/udevslab-lesson3/internal/validation"
 )
 
+// ErrProductChanged is returned when a product is no longer at the version the client expected
+var ErrProductChanged = errs.PreconditionFailed("product was changed since it was read")
+
 type ProductService struct {
-	logger      *slog.Logger
-	productRepo repos.ProductRepo
+	logger        *slog.Logger
+	productRepo   repos.ProductRepo
+	exchangeRates rates.ExchangeRateProvider
+	taxRates      *tax.Rates
 }
 
-func NewProductService(logger *slog.Logger, productRepo repos.ProductRepo) *ProductService {
+func NewProductService(logger *slog.Logger, productRepo repos.ProductRepo, exchangeRates rates.ExchangeRateProvider, taxRates *tax.Rates) *ProductService {
 	return &ProductService{
-		logger:      logger,
-		productRepo: productRepo,
+		logger:        logger,
+		productRepo:   productRepo,
+		exchangeRates: exchangeRates,
+		taxRates:      taxRates,
 	}
 }
 
+// CreateProduct adds a product to the catalogue, priced in DefaultCurrency and taxed in DefaultTaxClass
+// unless a currency and a tax class are given
 func (s *ProductService) CreateProduct(ctx context.Context, product *models.ProductCreate) (string, error) {
+	if err := validation.Struct(product); err != nil {
+		return "", err
+	}
+	if product.Currency == "" {
+		product.Currency = models.DefaultCurrency
+	}
+	if product.TaxClass == "" {
+		product.TaxClass = models.DefaultTaxClass
+	}
+	if err := s.checkCurrency(ctx, product.Currency); err != nil {
+		return "", err
+	}
+	if err := s.checkTaxClass(product.TaxClass); err != nil {
+		return "", err
+	}
 	return s.productRepo.CreateProduct(ctx, product)
 }
 
-func (s *ProductService) GetProductByID(ctx context.Context, productID string) (*models.Product, error) {
-	return s.productRepo.GetProductByID(ctx, productID)
+// GetProductByID reads a product, with its price converted to currency at the current exchange rate
+// unless currency is empty
+func (s *ProductService) GetProductByID(ctx context.Context, productID, currency string) (*models.Product, error) {
+	product, err := s.productRepo.GetProductByID(ctx, productID)
+	if err != nil || currency == "" {
+		return product, err
+	}
+
+	if err := s.convertPrice(ctx, product, currency); err != nil {
+		return nil, err
+	}
+	return product, nil
 }
 
-func (s *ProductService) UpdateProduct(ctx context.Context, productID string, updates *models.ProductUpdate) error {
-	return s.productRepo.UpdateProduct(ctx, productID, updates)
+// UpdateProduct replaces the editable fields of a product that is still at the expected version.
+// An update without a currency leaves the price in the currency it was in, and one without a tax class the tax class.
+func (s *ProductService) UpdateProduct(ctx context.Context, productID string, version int64, updates *models.ProductUpdate) error {
+	if err := validation.Struct(updates); err != nil {
+		return err
+	}
+	if updates.Currency != "" {
+		if err := s.checkCurrency(ctx, updates.Currency); err != nil {
+			return err
+		}
+	}
+	if updates.TaxClass != "" {
+		if err := s.checkTaxClass(updates.TaxClass); err != nil {
+			return err
+		}
+	}
+	if updates.Currency != "" && updates.TaxClass != "" {
+		return s.productRepo.UpdateProduct(ctx, productID, version, updates)
+	}
+
+	current, err := s.productRepo.GetProductByID(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if version != models.AnyVersion && current.Version != version {
+		return ErrProductChanged
+	}
+
+	// The currency and tax class kept must be the ones of the product the update applies to
+	if updates.Currency == "" {
+		updates.Currency = current.Currency
+	}
+	if updates.TaxClass == "" {
+		updates.TaxClass = current.TaxClass
+	}
+	return raced(s.productRepo.UpdateProduct(ctx, productID, current.Version, updates), version, "product")
 }
 
-func (s *ProductService) DeleteProduct(ctx context.Context, productID string) error {
-	return s.productRepo.DeleteProduct(ctx, productID)
+// PatchProduct applies a merge patch to a product that is still at the expected version.
+// The patched product is validated as a whole, but only the fields in the patch are written.
+func (s *ProductService) PatchProduct(ctx context.Context, productID string, version int64, patch *models.ProductPatch) error {
+	current, err := s.productRepo.GetProductByID(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	if version != models.AnyVersion && current.Version != version {
+		return ErrProductChanged
+	}
+
+	merged := models.ProductUpdate{Name: current.Name, Description: current.Description, Price: current.Price, Currency: current.Currency, Stock: current.Stock}
+	if patch.Name != nil {
+		merged.Name = *patch.Name
+	}
+	if patch.Price != nil {
+		merged.Price = *patch.Price
+	}
+	if patch.Currency != nil {
+		merged.Currency = *patch.Currency
+	}
+	if patch.Stock != nil {
+		merged.Stock = *patch.Stock
+	}
+	if err := validation.Struct(&merged); err != nil {
+		return err
+	}
+	if patch.Currency != nil {
+		if err := s.checkCurrency(ctx, *patch.Currency); err != nil {
+			return err
+		}
+	}
+	if patch.TaxClass != nil {
+		if err := s.checkTaxClass(*patch.TaxClass); err != nil {
+			return err
+		}
+	}
+
+	if *patch == (models.ProductPatch{}) {
+		return nil
+	}
+
+	// The product validated above must be the one the patch applies to
+	return raced(s.productRepo.PatchProduct(ctx, productID, current.Version, patch), version, "product")
+}
+
+// DeleteProduct deletes a product that is still at the expected version
+func (s *ProductService) DeleteProduct(ctx context.Context, productID string, version int64) error {
+	return s.productRepo.DeleteProduct(ctx, productID, version)
 }
 
-func (s *ProductService) ListProducts(ctx context.Context, pagination *models.Pagination) ([]models.Product, error) {
-	return s.productRepo.ListProducts(ctx, pagination)
+// ListProducts lists a page of products, with their prices converted to currency at the current exchange rates
+// unless currency is empty
+func (s *ProductService) ListProducts(ctx context.Context, pagination *models.Pagination, currency string) (*models.Page[models.Product], error) {
+	page, err := s.productRepo.ListProducts(ctx, pagination)
+	if err != nil {
+		return nil, err
+	}
+	return s.convertPage(ctx, page, currency)
 }
 
-func (s *ProductService) SearchProductsByName(ctx context.Context, name string, pagination *models.Pagination) ([]models.Product, error) {
-	return s.productRepo.SearchProductsByName(ctx, name, pagination)
+// convertPage converts the prices of a page of products to currency unless currency is empty
+func (s *ProductService) convertPage(ctx context.Context, page *models.Page[models.Product], currency string) (*models.Page[models.Product], error) {
+	if currency == "" {
+		return page, nil
+	}
+
+	for i := range page.Items {
+		if err := s.convertPrice(ctx, &page.Items[i], currency); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
 }
 
-func (s *ProductService) ExactSearchProductsByPrice(ctx context.Context, price float64, pagination *models.Pagination) ([]models.Product, error) {
-	return s.productRepo.ExactSearchProductsByPrice(ctx, price, pagination)
+// checkCurrency rejects the currencies there is no exchange rate for, their prices couldn't be converted
+// into the currency of an order
+func (s *ProductService) checkCurrency(ctx context.Context, currency string) error {
+	_, err := s.exchangeRates.Rate(ctx, currency, models.DefaultCurrency)
+	if errors.Is(err, errs.ErrValidation) {
+		return errs.InvalidFields([]errs.FieldError{{Field: "currency", Rule: "exists", Message: "currency is not a currency with an exchange rate"}})
+	}
+	return err
 }
 
-func (s *ProductService) SearchProductsByPriceRange(ctx context.Context, order int8, minPrice, maxPrice float64, pagination *models.Pagination) ([]models.Product, error) {
-	return s.productRepo.SearchProductsByPriceRange(ctx, order, minPrice, maxPrice, pagination)
+// checkTaxClass rejects the tax classes there are no tax rates for
+func (s *ProductService) checkTaxClass(class string) error {
+	if !s.taxRates.HasClass(class) {
+		return errs.InvalidFields([]errs.FieldError{{Field: "taxClass", Rule: "exists", Message: "taxClass is not a tax class with tax rates"}})
+	}
+	return nil
+}
+
+// convertPrice prices product in currency, rounded to the nearest minor unit
+func (s *ProductService) convertPrice(ctx context.Context, product *models.Product, currency string) error {
+	rate, err := s.exchangeRates.Rate(ctx, product.Currency, currency)
+	if err != nil {
+		return err
+	}
+
+	price, err := rate.Convert(product.Price)
+	if err != nil {
+		return err
+	}
+	product.Price, product.Currency = price, currency
+	return nil
+}
+
+// SearchProductsByName lists a page of the products whose name matches, with their prices converted to currency
+// at the current exchange rates unless currency is empty
+func (s *ProductService) SearchProductsByName(ctx context.Context, name string, pagination *models.Pagination, currency string) (*models.Page[models.Product], error) {
+	page, err := s.productRepo.SearchProductsByName(ctx, name, pagination)
+	if err != nil {
+		return nil, err
+	}
+	return s.convertPage(ctx, page, currency)
+}
+
+// ExactSearchProductsByPrice lists a page of the products priced at exactly price in currency.
+// Prices are only compared within their own currency, products priced in other currencies are left out.
+func (s *ProductService) ExactSearchProductsByPrice(ctx context.Context, price models.Money, currency string, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	if err := s.checkCurrency(ctx, currency); err != nil {
+		return nil, err
+	}
+	return s.productRepo.ExactSearchProductsByPrice(ctx, price, currency, pagination)
+}
+
+// SearchProductsByPriceRange lists a page of the products priced in currency between minPrice and maxPrice.
+// Prices are only compared within their own currency, products priced in other currencies are left out.
+func (s *ProductService) SearchProductsByPriceRange(ctx context.Context, order int8, minPrice, maxPrice models.Money, currency string, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	if err := s.checkCurrency(ctx, currency); err != nil {
+		return nil, err
+	}
+	return s.productRepo.SearchProductsByPriceRange(ctx, order, minPrice, maxPrice, currency, pagination)
 }
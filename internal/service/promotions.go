@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/validation"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PromotionService struct {
+	logger        *slog.Logger
+	promotionRepo repos.PromotionRepo
+	productRepo   repos.ProductRepo
+}
+
+func NewPromotionService(logger *slog.Logger, promotionRepo repos.PromotionRepo, productRepo repos.ProductRepo) *PromotionService {
+	return &PromotionService{
+		logger:        logger,
+		promotionRepo: promotionRepo,
+		productRepo:   productRepo,
+	}
+}
+
+// CreatePromotion adds a promotion redeemable with its code in any letter case.
+// Amounts are in DefaultCurrency unless a currency is given.
+func (s *PromotionService) CreatePromotion(ctx context.Context, promotion *models.PromotionCreate) (string, error) {
+	if err := validation.Struct(promotion); err != nil {
+		return "", err
+	}
+
+	promotion.Code = normalizeCode(promotion.Code)
+	if promotion.Currency == "" {
+		promotion.Currency = models.DefaultCurrency
+	}
+
+	if promotion.StartsAt != 0 && promotion.EndsAt != 0 && promotion.EndsAt <= promotion.StartsAt {
+		return "", errs.InvalidFields([]errs.FieldError{{Field: "endsAt", Rule: "gtfield", Message: "endsAt must be after startsAt"}})
+	}
+
+	if promotion.Type == models.PromotionBuyXGetY {
+		if _, err := s.productRepo.GetProductByID(ctx, promotion.ProductID.Hex()); err != nil {
+			if errors.Is(err, errs.ErrNotFound) {
+				return "", errs.InvalidFields([]errs.FieldError{{Field: "productId", Rule: "exists", Message: "productId must be an existing product"}})
+			}
+			return "", err
+		}
+	}
+
+	return s.promotionRepo.CreatePromotion(ctx, promotion)
+}
+
+func (s *PromotionService) GetPromotionByID(ctx context.Context, promotionID string) (*models.Promotion, error) {
+	return s.promotionRepo.GetPromotionByID(ctx, promotionID)
+}
+
+// DeletePromotion deletes a promotion, the orders it was applied to keep their discounts
+func (s *PromotionService) DeletePromotion(ctx context.Context, promotionID string) error {
+	return s.promotionRepo.DeletePromotion(ctx, promotionID)
+}
+
+func (s *PromotionService) ListPromotions(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Promotion], error) {
+	return s.promotionRepo.ListPromotions(ctx, pagination)
+}
+
+// normalizeCode returns the form codes are stored and looked up in
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// couponError reports a coupon code an order can't use
+func couponError(rule, message string) error {
+	return errs.InvalidFields([]errs.FieldError{{Field: "couponCode", Rule: rule, Message: message}})
+}
+
+// promotionForCode looks up the promotion of a coupon code and checks it can be redeemed now
+func (s *OrderService) promotionForCode(ctx context.Context, code string) (*models.Promotion, error) {
+	promotion, err := s.promotionRepo.GetPromotionByCode(ctx, normalizeCode(code))
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, couponError("exists", "couponCode is not a valid coupon code")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	if promotion.StartsAt != 0 && now < promotion.StartsAt || promotion.EndsAt != 0 && now >= promotion.EndsAt {
+		return nil, couponError("active", "couponCode is not active")
+	}
+	return promotion, nil
+}
+
+// applyPromotion works out what a promotion takes off lines priced in currency, whose sum is subtotal.
+// Amounts of the promotion in another currency are converted at the current exchange rate.
+// The discount never exceeds the subtotal.
+func (s *OrderService) applyPromotion(ctx context.Context, promotion *models.Promotion, items []models.OrderItem, subtotal models.Money, currency string) (models.AppliedDiscount, error) {
+	rate, err := s.exchangeRates.Rate(ctx, promotion.Currency, currency)
+	if err != nil {
+		return models.AppliedDiscount{}, err
+	}
+
+	minimum, err := rate.Convert(promotion.MinOrderValue)
+	if err != nil {
+		return models.AppliedDiscount{}, err
+	}
+	if subtotal < minimum {
+		return models.AppliedDiscount{}, couponError("min", fmt.Sprintf("couponCode needs an order of at least %s %s", minimum, currency))
+	}
+
+	var amount models.Money
+	switch promotion.Type {
+	case models.PromotionPercentage:
+		amount = subtotal.Percent(promotion.Percent)
+	case models.PromotionFixedAmount:
+		if amount, err = rate.Convert(promotion.Amount); err != nil {
+			return models.AppliedDiscount{}, err
+		}
+	case models.PromotionBuyXGetY:
+		// Of every group of BuyQuantity + FreeQuantity units FreeQuantity are free, units short of a group pay in full
+		group := promotion.BuyQuantity + promotion.FreeQuantity
+		for _, item := range items {
+			if promotion.ProductID != nil && item.ProductID == *promotion.ProductID && group > 0 {
+				amount = item.UnitPrice.Mul(item.Quantity / group * promotion.FreeQuantity)
+			}
+		}
+		if amount == 0 {
+			return models.AppliedDiscount{}, couponError("items", fmt.Sprintf("couponCode needs at least %d units of the promoted product", group))
+		}
+	}
+
+	return models.AppliedDiscount{
+		PromotionID: promotion.ID,
+		Code:        promotion.Code,
+		Type:        promotion.Type,
+		Amount:      min(amount, subtotal),
+	}, nil
+}
+
+// rediscount works out the discounts recorded on an order again for its new lines.
+// A promotion deleted since keeps the amount it took off, as far as the new subtotal allows.
+func (s *OrderService) rediscount(ctx context.Context, recorded []models.AppliedDiscount, items []models.OrderItem, subtotal models.Money, currency string) ([]models.AppliedDiscount, models.Money, error) {
+	discounts := []models.AppliedDiscount{}
+	var total models.Money
+	for _, previous := range recorded {
+		discount := previous
+		discount.Amount = min(previous.Amount, subtotal-total)
+
+		promotion, err := s.promotionRepo.GetPromotionByID(ctx, previous.PromotionID.Hex())
+		switch {
+		case err == nil:
+			if discount, err = s.applyPromotion(ctx, promotion, items, subtotal-total, currency); err != nil {
+				return nil, 0, err
+			}
+		case !errors.Is(err, errs.ErrNotFound):
+			return nil, 0, err
+		}
+
+		discounts = append(discounts, discount)
+		total = total.Add(discount.Amount)
+	}
+	return discounts, total, nil
+}
+
+// redeem counts the use of the promotions applied to an order placed by the user
+func (s *OrderService) redeem(ctx context.Context, userID string, discounts []models.AppliedDiscount) error {
+	for i, discount := range discounts {
+		if err := s.promotionRepo.RedeemPromotion(ctx, discount.PromotionID.Hex(), userID); err != nil {
+			s.releasePromotions(ctx, userID, discounts[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// releasePromotions takes back the uses of the promotions applied to an order, failures are only logged because
+// the caller can't undo them. Promotions deleted in the meantime have no uses left to take back.
+func (s *OrderService) releasePromotions(ctx context.Context, userID string, discounts []models.AppliedDiscount) {
+	for _, discount := range discounts {
+		err := s.promotionRepo.ReleasePromotion(ctx, discount.PromotionID.Hex(), userID)
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			s.logger.Error("failed to release promotion", "promotionID", discount.PromotionID.Hex(), "userID", userID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/rates"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+	"github.com/abdulazizax/udevslab-lesson3/internal/tax"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ctx        = context.Background()
+	testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+)
+
+const testTaxRates = `{
+  "defaultRegion": "UZ",
+  "regions": {
+    "UZ": {"mode": "inclusive", "rates": {"standard": 12, "reduced": 0}},
+    "US-NY": {"mode": "exclusive", "rates": {"standard": 8.875, "reduced": 4}}
+  }
+}`
+
+// newTestOrderService serves orders out of an in-memory storage, with 12650.5 UZS to the dollar
+func newTestOrderService(t *testing.T) (*OrderService, storage.StorageI) {
+	t.Helper()
+
+	exchangeRates, err := rates.Parse([]byte(`{"base": "USD", "rates": {"UZS": 12650.5}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	taxRates, err := tax.Parse([]byte(testTaxRates))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := storage.NewMemory(&config.Config{Cart: config.CartConfig{TTL: time.Hour}}, testLogger)
+	return NewOrderService(testLogger, repo.OrderRepo(), repo.ProductRepo(), repo.UserRepo(), repo.PromotionRepo(), repo.Transactor(), exchangeRates, taxRates), repo
+}
+
+func createTestPromotion(t *testing.T, repo storage.StorageI, promotion models.PromotionCreate) *models.Promotion {
+	t.Helper()
+
+	if promotion.Currency == "" {
+		promotion.Currency = models.DefaultCurrency
+	}
+	id, err := repo.PromotionRepo().CreatePromotion(ctx, &promotion)
+	if err != nil {
+		t.Fatalf("CreatePromotion(%q) failed: %v", promotion.Code, err)
+	}
+	created, err := repo.PromotionRepo().GetPromotionByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+// requireCouponError fails unless err is a validation error of the coupon code breaking rule
+func requireCouponError(t *testing.T, err error, rule string) {
+	t.Helper()
+
+	fields := errs.FieldErrors(err)
+	if !errors.Is(err, errs.ErrValidation) || len(fields) != 1 || fields[0].Field != "couponCode" || fields[0].Rule != rule {
+		t.Fatalf("got error %v, want couponCode to break %s", err, rule)
+	}
+}
+
+func TestApplyPromotion(t *testing.T) {
+	s, _ := newTestOrderService(t)
+	laptop, mouse := models.NewID(), models.NewID()
+	line := func(productID models.ID, quantity int, unitPrice models.Money) models.OrderItem {
+		return models.OrderItem{ProductID: productID, Quantity: quantity, UnitPrice: unitPrice, LineTotal: unitPrice.Mul(quantity)}
+	}
+
+	tests := []struct {
+		name      string
+		promotion models.Promotion
+		items     []models.OrderItem
+		currency  string
+		want      models.Money
+		wantRule  string
+	}{
+		{
+			name:      "percentage",
+			promotion: models.Promotion{Type: models.PromotionPercentage, Percent: 10},
+			items:     []models.OrderItem{line(laptop, 1, 1999)},
+			want:      200, // 199.9 rounded
+		},
+		{
+			name:      "percentage of everything",
+			promotion: models.Promotion{Type: models.PromotionPercentage, Percent: 100},
+			items:     []models.OrderItem{line(laptop, 1, 1999), line(mouse, 2, 500)},
+			want:      2999,
+		},
+		{
+			name:      "fixed amount",
+			promotion: models.Promotion{Type: models.PromotionFixedAmount, Amount: 500},
+			items:     []models.OrderItem{line(laptop, 1, 1999)},
+			want:      500,
+		},
+		{
+			name:      "fixed amount capped at the subtotal",
+			promotion: models.Promotion{Type: models.PromotionFixedAmount, Amount: 5000},
+			items:     []models.OrderItem{line(laptop, 1, 1999)},
+			want:      1999,
+		},
+		{
+			name:      "fixed amount in another currency",
+			promotion: models.Promotion{Type: models.PromotionFixedAmount, Amount: 100},
+			items:     []models.OrderItem{line(laptop, 1, 5000000)},
+			currency:  "UZS",
+			want:      1265050,
+		},
+		{
+			name:      "buy 2 get 1 of 3 units",
+			promotion: models.Promotion{Type: models.PromotionBuyXGetY, ProductID: &laptop, BuyQuantity: 2, FreeQuantity: 1},
+			items:     []models.OrderItem{line(mouse, 1, 500), line(laptop, 3, 1000)},
+			want:      1000,
+		},
+		{
+			name:      "buy 2 get 1 of 5 units",
+			promotion: models.Promotion{Type: models.PromotionBuyXGetY, ProductID: &laptop, BuyQuantity: 2, FreeQuantity: 1},
+			items:     []models.OrderItem{line(laptop, 5, 1000)},
+			want:      1000, // The 2 units short of a second group pay in full
+		},
+		{
+			name:      "buy 2 get 1 of 6 units",
+			promotion: models.Promotion{Type: models.PromotionBuyXGetY, ProductID: &laptop, BuyQuantity: 2, FreeQuantity: 1},
+			items:     []models.OrderItem{line(laptop, 6, 1000)},
+			want:      2000,
+		},
+		{
+			name:      "buy 3 get 2 of 9 units",
+			promotion: models.Promotion{Type: models.PromotionBuyXGetY, ProductID: &laptop, BuyQuantity: 3, FreeQuantity: 2},
+			items:     []models.OrderItem{line(laptop, 9, 1000)},
+			want:      2000,
+		},
+		{
+			name:      "buy 2 get 1 short of a group",
+			promotion: models.Promotion{Type: models.PromotionBuyXGetY, ProductID: &laptop, BuyQuantity: 2, FreeQuantity: 1},
+			items:     []models.OrderItem{line(laptop, 2, 1000)},
+			wantRule:  "items",
+		},
+		{
+			name:      "buy 2 get 1 of another product",
+			promotion: models.Promotion{Type: models.PromotionBuyXGetY, ProductID: &laptop, BuyQuantity: 2, FreeQuantity: 1},
+			items:     []models.OrderItem{line(mouse, 3, 500)},
+			wantRule:  "items",
+		},
+		{
+			name:      "exactly the minimum order value",
+			promotion: models.Promotion{Type: models.PromotionFixedAmount, Amount: 500, MinOrderValue: 1999},
+			items:     []models.OrderItem{line(laptop, 1, 1999)},
+			want:      500,
+		},
+		{
+			name:      "below the minimum order value",
+			promotion: models.Promotion{Type: models.PromotionFixedAmount, Amount: 500, MinOrderValue: 2000},
+			items:     []models.OrderItem{line(laptop, 1, 1999)},
+			wantRule:  "min",
+		},
+		{
+			name:      "minimum order value in another currency",
+			promotion: models.Promotion{Type: models.PromotionPercentage, Percent: 10, MinOrderValue: 100},
+			items:     []models.OrderItem{line(laptop, 1, 1265049)},
+			currency:  "UZS",
+			wantRule:  "min", // 1.00 USD is 12650.50 UZS
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promotion := tt.promotion
+			promotion.ID = models.NewID()
+			promotion.Code = "PROMO"
+			promotion.Currency = models.DefaultCurrency
+			currency := tt.currency
+			if currency == "" {
+				currency = models.DefaultCurrency
+			}
+			var subtotal models.Money
+			for _, item := range tt.items {
+				subtotal = subtotal.Add(item.LineTotal)
+			}
+
+			discount, err := s.applyPromotion(ctx, &promotion, tt.items, subtotal, currency)
+			if tt.wantRule != "" {
+				requireCouponError(t, err, tt.wantRule)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if discount.Amount != tt.want || discount.PromotionID != promotion.ID || discount.Code != "PROMO" || discount.Type != promotion.Type {
+				t.Fatalf("got discount %+v, want %v off", discount, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromotionForCodeWindow(t *testing.T) {
+	s, repo := newTestOrderService(t)
+	hourAgo := primitive.NewDateTimeFromTime(time.Now().Add(-time.Hour))
+	inAnHour := primitive.NewDateTimeFromTime(time.Now().Add(time.Hour))
+
+	tests := []struct {
+		code             string
+		startsAt, endsAt primitive.DateTime
+		active           bool
+	}{
+		{"ALWAYS", 0, 0, true},
+		{"STARTED", hourAgo, 0, true},
+		{"RUNNING", hourAgo, inAnHour, true},
+		{"ENDING", 0, inAnHour, true},
+		{"NOTSTARTED", inAnHour, 0, false},
+		{"LATER", inAnHour, inAnHour + 1000, false},
+		{"ENDED", 0, hourAgo, false},
+		{"EXPIRED", hourAgo - 1000, hourAgo, false},
+	}
+	for _, tt := range tests {
+		createTestPromotion(t, repo, models.PromotionCreate{Code: tt.code, Type: models.PromotionPercentage, Percent: 10, StartsAt: tt.startsAt, EndsAt: tt.endsAt})
+
+		// Codes are redeemable in any letter case
+		promotion, err := s.promotionForCode(ctx, " "+strings.ToLower(tt.code)+" ")
+		if tt.active {
+			if err != nil || promotion.Code != tt.code {
+				t.Errorf("%s: got %v, %v, want the promotion", tt.code, promotion, err)
+			}
+			continue
+		}
+		fields := errs.FieldErrors(err)
+		if len(fields) != 1 || fields[0].Rule != "active" {
+			t.Errorf("%s: got %v, %v, want it not active", tt.code, promotion, err)
+		}
+	}
+
+	_, err := s.promotionForCode(ctx, "WINTER10")
+	requireCouponError(t, err, "exists")
+}
+
+func TestRediscount(t *testing.T) {
+	s, repo := newTestOrderService(t)
+	laptop := models.NewID()
+	items := []models.OrderItem{{ProductID: laptop, Quantity: 3, UnitPrice: 1000, LineTotal: 3000}}
+
+	percentage := createTestPromotion(t, repo, models.PromotionCreate{Code: "TEN", Type: models.PromotionPercentage, Percent: 10})
+	fixed := createTestPromotion(t, repo, models.PromotionCreate{Code: "FIVE", Type: models.PromotionFixedAmount, Amount: 500})
+	deleted := models.AppliedDiscount{PromotionID: models.NewID(), Code: "GONE", Type: models.PromotionFixedAmount, Amount: 2000}
+
+	recorded := []models.AppliedDiscount{
+		{PromotionID: percentage.ID, Code: percentage.Code, Type: percentage.Type, Amount: 999},
+		deleted,
+		{PromotionID: fixed.ID, Code: fixed.Code, Type: fixed.Type, Amount: 500},
+	}
+
+	// Promotions that still exist are worked out on what the ones before them left, a deleted one keeps its amount
+	discounts, total, err := s.rediscount(ctx, recorded, items, 3000, models.DefaultCurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.Money{300, 2000, 500}
+	for i, discount := range discounts {
+		if discount.Amount != want[i] || discount.PromotionID != recorded[i].PromotionID {
+			t.Fatalf("discount %d is %+v, want %v off", i, discount, want[i])
+		}
+	}
+	if len(discounts) != len(want) || total != 2800 {
+		t.Fatalf("got discounts %+v of %v", discounts, total)
+	}
+
+	// With fewer lines the deleted promotion takes off no more than is left
+	items = []models.OrderItem{{ProductID: laptop, Quantity: 1, UnitPrice: 1000, LineTotal: 1000}}
+	discounts, total, err = s.rediscount(ctx, recorded, items, 1000, models.DefaultCurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []models.Money{100, 900, 0}
+	for i, discount := range discounts {
+		if discount.Amount != want[i] {
+			t.Fatalf("discount %d is %+v, want %v off", i, discount, want[i])
+		}
+	}
+	if total != 1000 {
+		t.Fatalf("got a discount of %v on a subtotal of 1000", total)
+	}
+
+	// A promotion whose conditions the new lines don't meet any more refuses the change
+	minimum := createTestPromotion(t, repo, models.PromotionCreate{Code: "BIG", Type: models.PromotionFixedAmount, Amount: 100, MinOrderValue: 2000})
+	_, _, err = s.rediscount(ctx, []models.AppliedDiscount{{PromotionID: minimum.ID, Amount: 100}}, items, 1000, models.DefaultCurrency)
+	requireCouponError(t, err, "min")
+}
+
+func TestPromotionPerUserLimit(t *testing.T) {
+	s, repo := newTestOrderService(t)
+
+	var users []string
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		id, err := repo.UserRepo().CreateUser(ctx, "hash", &models.UserCreate{Name: email, Email: email, Role: models.RoleCustomer})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, id)
+	}
+	productID, err := repo.ProductRepo().CreateProduct(ctx, &models.ProductCreate{Name: "Laptop", Price: 1000, Currency: models.DefaultCurrency, TaxClass: models.DefaultTaxClass, Stock: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	product, err := models.ParseID(productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	promotion := createTestPromotion(t, repo, models.PromotionCreate{Code: "ONCE", Type: models.PromotionFixedAmount, Amount: 100, PerUserLimit: 1})
+
+	order := func(userID string) error {
+		_, err := s.CreateOrder(ctx, userID, &models.OrderCreate{
+			Items:      []models.OrderItemCreate{{ProductID: product, Quantity: 1}},
+			CouponCode: "once",
+		})
+		return err
+	}
+
+	if err := order(users[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := order(users[0]); !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("got error %v for a second use by the same user, want a conflict", err)
+	}
+	if err := order(users[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	// The refused order reserved no stock and used up nothing
+	stored, err := repo.ProductRepo().GetProductByID(ctx, productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	promotion, err = repo.PromotionRepo().GetPromotionByID(ctx, promotion.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Stock != 8 || promotion.UsageCount != 2 {
+		t.Fatalf("got stock %d and usage count %d, want 8 and 2", stored.Stock, promotion.UsageCount)
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+)
+
+type ReportService struct {
+	logger     *slog.Logger
+	reportRepo repos.ReportRepo
+}
+
+func NewReportService(logger *slog.Logger, reportRepo repos.ReportRepo) *ReportService {
+	return &ReportService{
+		logger:     logger,
+		reportRepo: reportRepo,
+	}
+}
+
+func (s *ReportService) GetReport(ctx context.Context) (*models.Report, error) {
+	return s.reportRepo.GetReport(ctx)
+}
+
+func (s *ReportService) GetTopProducts(ctx context.Context, limit int) ([]models.TopProduct, error) {
+	return s.reportRepo.GetTopProducts(ctx, limit)
+}
+
+func (s *ReportService) GetDailyOrderAggregates(ctx context.Context, startDate, endDate time.Time) ([]models.OrderAggregate, error) {
+	return s.reportRepo.GetDailyOrderAggregates(ctx, startDate, endDate)
+}
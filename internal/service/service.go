@@ -1,19 +1,46 @@
 package service
 
 import (
+	"errors"
 	"log/slog"
 
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/rates"
 	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+	"github.com/abdulazizax/udevslab-lesson3/internal/tax"
 )
 
 type Service struct {
-	OrderService   *OrderService
-	ProductService *ProductService
+	AuthService      *AuthService
+	CartService      *CartService
+	OrderService     *OrderService
+	ProductService   *ProductService
+	PromotionService *PromotionService
+	ReportService    *ReportService
+	UserService      *UserService
 }
 
-func NewService(logger *slog.Logger, repo storage.StorageI) *Service {
+func NewService(logger *slog.Logger, repo storage.StorageI, exchangeRates rates.ExchangeRateProvider, taxRates *tax.Rates, cfg *config.Config) *Service {
+	orderService := NewOrderService(logger, repo.OrderRepo(), repo.ProductRepo(), repo.UserRepo(), repo.PromotionRepo(), repo.Transactor(), exchangeRates, taxRates)
+
 	return &Service{
-		OrderService:   NewOrderService(logger, repo.OrderRepo(), repo.ProductRepo()),
-		ProductService: NewProductService(logger, repo.ProductRepo()),
+		AuthService:      NewAuthService(logger, repo.UserRepo(), cfg.Auth),
+		CartService:      NewCartService(logger, repo.CartRepo(), repo.ProductRepo(), orderService, repo.Transactor(), exchangeRates),
+		OrderService:     orderService,
+		ProductService:   NewProductService(logger, repo.ProductRepo(), exchangeRates, taxRates),
+		PromotionService: NewPromotionService(logger, repo.PromotionRepo(), repo.ProductRepo()),
+		ReportService:    NewReportService(logger, repo.ReportRepo()),
+		UserService:      NewUserService(logger, repo.UserRepo()),
+	}
+}
+
+// raced reports a write refused because the resource changed after it was read in the same request.
+// Without an expected version from the client that is a conflict worth retrying, not a failed precondition.
+func raced(err error, version int64, what string) error {
+	if version == models.AnyVersion && errors.Is(err, errs.ErrPreconditionFailed) {
+		return errs.Conflict(what + " was changed concurrently, try again")
 	}
+	return err
 }
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/tax"
+)
+
+// taxation is what an order owes in tax and what it comes to with it
+type taxation struct {
+	taxes []models.TaxLine
+	net   models.Money
+	tax   models.Money
+	total models.Money
+}
+
+// taxRegion looks up the region an order asks to be taxed in, the default region when it asks for none
+func (s *OrderService) taxRegion(code string) (tax.Region, error) {
+	region, ok := s.taxRates.Region(strings.ToUpper(code))
+	if !ok {
+		return tax.Region{}, errs.InvalidFields([]errs.FieldError{{Field: "region", Rule: "exists", Message: "region is not a region with tax rates"}})
+	}
+	return region, nil
+}
+
+// taxItems works out the tax on lines whose sum is subtotal, once discount is taken off them.
+// The discount is shared between the tax classes in proportion to their amounts, then every class is taxed
+// at its rate in the region, on top of its amount or out of it depending on the mode of the region.
+func taxItems(region tax.Region, items []models.OrderItem, subtotal, discount models.Money) (taxation, error) {
+	var classes []string
+	amounts := make(map[string]models.Money)
+	for _, item := range items {
+		if _, ok := amounts[item.TaxClass]; !ok {
+			classes = append(classes, item.TaxClass)
+		}
+		amounts[item.TaxClass] = amounts[item.TaxClass].Add(item.LineTotal)
+	}
+
+	result := taxation{taxes: []models.TaxLine{}}
+	var counted, shared models.Money
+	for _, class := range classes {
+		rate, ok := region.Rate(class)
+		if !ok {
+			return taxation{}, errs.Validation(fmt.Sprintf("region %s has no tax rate for the %s tax class", region.Code, class))
+		}
+
+		// Sharing the running sum out keeps the shares adding up to the discount whatever they round to
+		counted = counted.Add(amounts[class])
+		var share models.Money
+		if subtotal != 0 {
+			share = discount.Prorate(counted, subtotal) - shared
+		}
+		shared = shared.Add(share)
+
+		line := models.TaxLine{TaxClass: class, Rate: rate, Net: amounts[class] - share}
+		var err error
+		if region.Mode == models.TaxInclusive {
+			line.Tax, err = rate.IncludedIn(line.Net)
+			line.Net -= line.Tax
+		} else {
+			line.Tax, err = rate.AddedTo(line.Net)
+		}
+		if err != nil {
+			return taxation{}, err
+		}
+
+		result.taxes = append(result.taxes, line)
+		result.net = result.net.Add(line.Net)
+		result.tax = result.tax.Add(line.Tax)
+	}
+
+	result.total = result.net.Add(result.tax)
+	return result, nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/tax"
+)
+
+func TestTaxItems(t *testing.T) {
+	taxRates, err := tax.Parse([]byte(testTaxRates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := func(class string, lineTotal models.Money) models.OrderItem {
+		return models.OrderItem{ProductID: models.NewID(), Quantity: 1, UnitPrice: lineTotal, LineTotal: lineTotal, TaxClass: class}
+	}
+
+	tests := []struct {
+		name      string
+		region    string
+		items     []models.OrderItem
+		discount  models.Money
+		wantNet   []models.Money // Per tax class, in the order the classes first appear
+		wantTax   []models.Money
+		wantTotal models.Money
+	}{
+		{
+			name:      "inclusive",
+			region:    "UZ",
+			items:     []models.OrderItem{line("standard", 2000), line("reduced", 1000), line("standard", 1000)},
+			wantNet:   []models.Money{2679, 1000}, // 30.00 includes 3.2143 of tax at 12%
+			wantTax:   []models.Money{321, 0},
+			wantTotal: 4000,
+		},
+		{
+			name:      "inclusive with a discount",
+			region:    "UZ",
+			items:     []models.OrderItem{line("standard", 3000), line("reduced", 1000)},
+			discount:  400,
+			wantNet:   []models.Money{2411, 900}, // The standard class takes 300 of the discount
+			wantTax:   []models.Money{289, 0},
+			wantTotal: 3600,
+		},
+		{
+			name:      "exclusive",
+			region:    "US-NY",
+			items:     []models.OrderItem{line("standard", 2000), line("reduced", 1000), line("standard", 1000)},
+			wantNet:   []models.Money{3000, 1000},
+			wantTax:   []models.Money{266, 40}, // 8.875% of 30.00 is 2.6625
+			wantTotal: 4306,
+		},
+		{
+			name:      "exclusive with a discount",
+			region:    "US-NY",
+			items:     []models.OrderItem{line("standard", 3000), line("reduced", 1000)},
+			discount:  400,
+			wantNet:   []models.Money{2700, 900},
+			wantTax:   []models.Money{240, 36},
+			wantTotal: 3876,
+		},
+		{
+			name:      "inclusive with a discount that doesn't share out evenly",
+			region:    "UZ",
+			items:     []models.OrderItem{line("standard", 1999), line("reduced", 1)},
+			discount:  999,
+			wantNet:   []models.Money{893, 1}, // The standard class takes 998.5005 rounded up of the discount
+			wantTax:   []models.Money{107, 0},
+			wantTotal: 1001,
+		},
+		{
+			name:      "exclusive with a discount that doesn't share out evenly",
+			region:    "US-NY",
+			items:     []models.OrderItem{line("reduced", 1), line("standard", 1999)},
+			discount:  999,
+			wantNet:   []models.Money{1, 1000}, // The reduced class takes 0.4995 rounded down of the discount
+			wantTax:   []models.Money{0, 89},
+			wantTotal: 1090,
+		},
+		{
+			name:      "inclusive with all of it discounted",
+			region:    "UZ",
+			items:     []models.OrderItem{line("standard", 1999), line("reduced", 500)},
+			discount:  2499,
+			wantNet:   []models.Money{0, 0},
+			wantTax:   []models.Money{0, 0},
+			wantTotal: 0,
+		},
+		{
+			name:      "exclusive with all of it discounted",
+			region:    "US-NY",
+			items:     []models.OrderItem{line("standard", 1999), line("reduced", 500)},
+			discount:  2499,
+			wantNet:   []models.Money{0, 0},
+			wantTax:   []models.Money{0, 0},
+			wantTotal: 0,
+		},
+		{
+			name:      "free lines",
+			region:    "US-NY",
+			items:     []models.OrderItem{line("standard", 0)},
+			wantNet:   []models.Money{0},
+			wantTax:   []models.Money{0},
+			wantTotal: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			region, ok := taxRates.Region(tt.region)
+			if !ok {
+				t.Fatalf("region %s not found", tt.region)
+			}
+			var subtotal models.Money
+			for _, item := range tt.items {
+				subtotal = subtotal.Add(item.LineTotal)
+			}
+
+			taxed, err := taxItems(region, tt.items, subtotal, tt.discount)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(taxed.taxes) != len(tt.wantNet) {
+				t.Fatalf("got tax lines %+v, want %d", taxed.taxes, len(tt.wantNet))
+			}
+			var net, taxes models.Money
+			for i, line := range taxed.taxes {
+				if line.Net != tt.wantNet[i] || line.Tax != tt.wantTax[i] {
+					t.Errorf("tax line %d is %+v, want %v net and %v tax", i, line, tt.wantNet[i], tt.wantTax[i])
+				}
+				net = net.Add(line.Net)
+				taxes = taxes.Add(line.Tax)
+			}
+
+			if taxed.net != net || taxed.tax != taxes || taxed.net+taxed.tax != taxed.total || taxed.total != tt.wantTotal {
+				t.Fatalf("got net %v, tax %v and total %v, want a total of %v", taxed.net, taxed.tax, taxed.total, tt.wantTotal)
+			}
+
+			// Tax comes out of what the order pays in inclusive regions, on top of it in exclusive ones
+			if region.Mode == models.TaxInclusive && taxed.total != subtotal-tt.discount {
+				t.Fatalf("got total %v, want the discounted subtotal %v", taxed.total, subtotal-tt.discount)
+			}
+			if region.Mode == models.TaxExclusive && taxed.net != subtotal-tt.discount {
+				t.Fatalf("got net %v, want the discounted subtotal %v", taxed.net, subtotal-tt.discount)
+			}
+		})
+	}
+}
+
+func TestTaxItemsWithoutRate(t *testing.T) {
+	taxRates, err := tax.Parse([]byte(testTaxRates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	region, _ := taxRates.Region("UZ")
+
+	items := []models.OrderItem{{ProductID: models.NewID(), Quantity: 1, UnitPrice: 1000, LineTotal: 1000, TaxClass: "luxury"}}
+	if _, err := taxItems(region, items, 1000, 0); !errors.Is(err, errs.ErrValidation) {
+		t.Fatalf("got error %v for a tax class without a rate, want a validation error", err)
+	}
+}
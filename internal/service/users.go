@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/validation"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrEmailTaken is returned when a user registers with an email that already belongs to someone else
+var ErrEmailTaken = errs.Conflict("email is already registered")
+
+type UserService struct {
+	logger   *slog.Logger
+	userRepo repos.UserRepo
+}
+
+func NewUserService(logger *slog.Logger, userRepo repos.UserRepo) *UserService {
+	return &UserService{
+		logger:   logger,
+		userRepo: userRepo,
+	}
+}
+
+// CreateUser registers a new customer account
+func (s *UserService) CreateUser(ctx context.Context, user *models.UserCreate) (string, error) {
+	user.Role = models.RoleCustomer
+	return s.createUser(ctx, user)
+}
+
+// EnsureAdmin creates the bootstrap admin account unless a user with the same email already exists
+func (s *UserService) EnsureAdmin(ctx context.Context, email, password string) error {
+	_, err := s.createUser(ctx, &models.UserCreate{
+		Name:     "Administrator",
+		Email:    email,
+		Password: password,
+		Role:     models.RoleAdmin,
+	})
+	if errors.Is(err, ErrEmailTaken) {
+		return nil
+	}
+	return err
+}
+
+func (s *UserService) createUser(ctx context.Context, user *models.UserCreate) (string, error) {
+	if err := validation.Struct(user); err != nil {
+		return "", err
+	}
+
+	if _, err := s.userRepo.GetUserByEmail(ctx, user.Email); err == nil {
+		return "", ErrEmailTaken
+	} else if !errors.Is(err, errs.ErrNotFound) {
+		return "", err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return s.userRepo.CreateUser(ctx, string(hash), user)
+}
+
+// GetUserByID fetches a user, customers may only look up their own account
+func (s *UserService) GetUserByID(ctx context.Context, actor Actor, userID string) (*models.User, error) {
+	if !actor.CanAccess(userID) {
+		return nil, ErrForbidden
+	}
+	return s.userRepo.GetUserByID(ctx, userID)
+}
+
+func (s *UserService) UpdateUser(ctx context.Context, userID string, updates *models.UserUpdate) error {
+	if err := validation.Struct(updates); err != nil {
+		return err
+	}
+	return s.userRepo.UpdateUser(ctx, userID, updates)
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, userID string) error {
+	return s.userRepo.DeleteUser(ctx, userID)
+}
+
+func (s *UserService) ListUsers(ctx context.Context, pagination *models.Pagination) (*models.Page[models.User], error) {
+	return s.userRepo.ListUsers(ctx, pagination)
+}
@@ -0,0 +1,138 @@
+// Package cursor encodes the opaque tokens used for keyset pagination.
+//
+// A cursor remembers the sort key and ID of the item a page starts or ends with,
+// so the next query continues right after it no matter how many items were
+// inserted before it in the meantime.
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+)
+
+// ErrInvalid is returned for cursors that weren't issued by this API or belong to a different listing
+var ErrInvalid = errs.Validation("invalid cursor")
+
+// Cursor points at a single item of a listing sorted by CreatedAt or Price, with the ID as tie breaker
+type Cursor struct {
+	Sort      string       `json:"s"` // Listing the cursor was issued for, e.g. "createdAt:-1"
+	CreatedAt time.Time    `json:"c"`
+	Price     models.Money `json:"p"`
+	ID        string       `json:"i"`
+	Backward  bool         `json:"b,omitempty"` // Whether the page lies before the item rather than after it
+}
+
+// Encode turns a cursor into an opaque URL safe token
+func Encode(c Cursor) string {
+	data, _ := json.Marshal(c) // A struct of plain fields always marshals
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a token created by Encode and checks that it was issued for the given listing
+func Decode(token, sort string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort || c.ID == "" {
+		return nil, ErrInvalid
+	}
+	return &c, nil
+}
+
+// Sort keys of the listings
+const (
+	KeyCreatedAt = "createdAt"
+	KeyPrice     = "price"
+)
+
+// Order is the order of a listing: by Key first and by ID for items with equal keys
+type Order struct {
+	Key       string
+	Direction int // 1 ascending, -1 descending
+}
+
+var (
+	OldestFirst   = Order{Key: KeyCreatedAt, Direction: 1}
+	NewestFirst   = Order{Key: KeyCreatedAt, Direction: -1}
+	CheapestFirst = Order{Key: KeyPrice, Direction: 1}
+)
+
+// WithDirection returns the same order in the given direction, 1 ascending and -1 descending
+func (o Order) WithDirection(direction int8) Order {
+	o.Direction = int(direction)
+	return o
+}
+
+// Sort identifies the listing in the cursors issued for it
+func (o Order) Sort() string {
+	return o.Key + ":" + strconv.Itoa(o.Direction)
+}
+
+// Resume decodes the cursor of a page request, if there is one, and returns the direction the listing
+// has to be read in: a cursor pointing backwards reads the listing in reverse up to the cursor.
+func (o Order) Resume(pagination *models.Pagination) (*Cursor, int, error) {
+	if pagination.Cursor == "" {
+		return nil, o.Direction, nil
+	}
+
+	c, err := Decode(pagination.Cursor, o.Sort())
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.Backward {
+		return c, -o.Direction, nil
+	}
+	return c, o.Direction, nil
+}
+
+// NewPage builds a page from items read in the direction returned by Resume, with one item more than
+// the page size if there is anything beyond the page. from is the cursor returned by Resume.
+func NewPage[T any](items []T, total int64, from *Cursor, order Order, pagination *models.Pagination, keyOf func(*T) Cursor) *models.Page[T] {
+	more := len(items) > pagination.PageSize
+	if more {
+		items = items[:pagination.PageSize]
+	}
+	backward := from != nil && from.Backward
+	if backward {
+		slices.Reverse(items)
+	}
+
+	// Coming from a cursor there is always something on the side we came from
+	hasNext := more || backward
+	hasPrev := (backward && more) || (!backward && (from != nil || pagination.Page > 1))
+
+	page := &models.Page[T]{
+		Items:    items,
+		PageSize: pagination.PageSize,
+		Total:    total,
+		HasNext:  hasNext && len(items) > 0,
+	}
+	if from == nil {
+		page.Page = pagination.Page
+	}
+	if len(items) == 0 {
+		return page
+	}
+
+	if hasNext {
+		next := keyOf(&items[len(items)-1])
+		next.Sort = order.Sort()
+		page.NextCursor = Encode(next)
+	}
+	if hasPrev {
+		prev := keyOf(&items[0])
+		prev.Sort, prev.Backward = order.Sort(), true
+		page.PrevCursor = Encode(prev)
+	}
+
+	return page
+}
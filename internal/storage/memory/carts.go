@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CartStorage struct {
+	store  *Store
+	logger *slog.Logger
+	cfg    *config.Config
+}
+
+func NewCartStorage(store *Store, logger *slog.Logger, cfg *config.Config) repos.CartRepo {
+	return &CartStorage{
+		store:  store,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// GetCartByUser fetches the cart of a user, carts past their expiry count as missing
+func (c *CartStorage) GetCartByUser(ctx context.Context, userID string) (*models.Cart, error) {
+	objectID, err := parseID(userID, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := c.store.rlock(ctx)
+	cart, ok := c.store.carts[objectID]
+	unlock()
+	if !ok || expired(cart) {
+		return nil, errs.NotFound("cart not found")
+	}
+
+	cart.Items = slices.Clone(cart.Items)
+	return &cart, nil
+}
+
+// SetCartItem sets the quantity of a product in the cart of a user, adding the line and creating the cart as needed
+func (c *CartStorage) SetCartItem(ctx context.Context, userID, productID string, quantity int) error {
+	c.logger.Info("setting cart item", "userID", userID, "productID", productID, "quantity", quantity)
+
+	userObjectID, err := parseID(userID, "user")
+	if err != nil {
+		return err
+	}
+	productObjectID, err := parseID(productID, "product")
+	if err != nil {
+		return err
+	}
+
+	defer c.store.lock(ctx)()
+
+	updatedAt := time.Now()
+	cart, ok := c.store.carts[userObjectID]
+	if !ok || expired(cart) {
+		// An expired cart would have been removed by the MongoDB TTL monitor, start over
+		id := models.NewID()
+		cart = models.Cart{ID: &id, UserID: userObjectID, CreatedAt: primitive.NewDateTimeFromTime(updatedAt)}
+	}
+
+	cart.Items = slices.Clone(cart.Items)
+	i := slices.IndexFunc(cart.Items, func(item models.CartItem) bool { return item.ProductID == productObjectID })
+	if i >= 0 {
+		cart.Items[i].Quantity = quantity
+	} else {
+		cart.Items = append(cart.Items, models.CartItem{ProductID: productObjectID, Quantity: quantity})
+	}
+	c.touch(&cart, updatedAt)
+	c.store.carts[userObjectID] = cart
+
+	c.logger.Info("cart item set successfully", "userID", userID, "productID", productID)
+	return nil
+}
+
+// RemoveCartItem removes a product from the cart of a user
+func (c *CartStorage) RemoveCartItem(ctx context.Context, userID, productID string) error {
+	c.logger.Info("removing cart item", "userID", userID, "productID", productID)
+
+	userObjectID, err := parseID(userID, "user")
+	if err != nil {
+		return err
+	}
+	productObjectID, err := parseID(productID, "product")
+	if err != nil {
+		return err
+	}
+
+	defer c.store.lock(ctx)()
+
+	cart, ok := c.store.carts[userObjectID]
+	i := slices.IndexFunc(cart.Items, func(item models.CartItem) bool { return item.ProductID == productObjectID })
+	if !ok || expired(cart) || i < 0 {
+		c.logger.Warn("no cart item found to remove", "userID", userID, "productID", productID)
+		return errs.NotFound("product is not in the cart")
+	}
+
+	cart.Items = slices.Delete(slices.Clone(cart.Items), i, i+1)
+	c.touch(&cart, time.Now())
+	c.store.carts[userObjectID] = cart
+
+	c.logger.Info("cart item removed successfully", "userID", userID, "productID", productID)
+	return nil
+}
+
+// DeleteCart removes the cart of a user, deleting a missing cart is not an error
+func (c *CartStorage) DeleteCart(ctx context.Context, userID string) error {
+	objectID, err := parseID(userID, "user")
+	if err != nil {
+		return err
+	}
+
+	unlock := c.store.lock(ctx)
+	delete(c.store.carts, objectID)
+	unlock()
+
+	return nil
+}
+
+// touch records a change of the cart and pushes its expiry back by the configured TTL
+func (c *CartStorage) touch(cart *models.Cart, at time.Time) {
+	cart.UpdatedAt = primitive.NewDateTimeFromTime(at)
+	cart.ExpiresAt = primitive.NewDateTimeFromTime(at.Add(c.cfg.Cart.TTL))
+}
+
+// expired reports whether a cart is past its expiry
+func expired(cart models.Cart) bool {
+	return !cart.ExpiresAt.Time().After(time.Now())
+}
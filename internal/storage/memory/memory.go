@@ -0,0 +1,177 @@
+// Package memory implements the repositories in memory, for tests and for running the API without MongoDB.
+//
+// The repositories mirror the semantics of the MongoDB ones: the same errors, the same sort orders
+// and the same cursors. Everything they store is lost when the process exits.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store holds the collections of the in-memory backend. A single lock guards all of them,
+// so lookups across collections, like the reports, always see a consistent state.
+type Store struct {
+	mu       sync.RWMutex
+	gate     sync.RWMutex // Held by a running unit of work, see Transactor
+	products map[models.ID]models.Product
+	orders   map[models.ID]models.Order
+	users    map[models.ID]models.User
+	carts    map[models.ID]models.Cart // By user ID
+
+	promotions  map[models.ID]models.Promotion
+	redemptions map[redemption]int // Uses of a promotion by a user
+}
+
+// redemption identifies the uses of a promotion by a user
+type redemption struct {
+	promotionID models.ID
+	userID      models.ID
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{
+		products: make(map[models.ID]models.Product),
+		orders:   make(map[models.ID]models.Order),
+		users:    make(map[models.ID]models.User),
+		carts:    make(map[models.ID]models.Cart),
+
+		promotions:  make(map[models.ID]models.Promotion),
+		redemptions: make(map[redemption]int),
+	}
+}
+
+// lock takes the store lock for a repository call and returns the function releasing it.
+// Calls outside a unit of work first wait for the running one to finish.
+func (s *Store) lock(ctx context.Context) func() {
+	if ctx.Value(txKey{}) == s {
+		s.mu.Lock()
+		return s.mu.Unlock
+	}
+
+	s.gate.RLock()
+	s.mu.Lock()
+	return func() {
+		s.mu.Unlock()
+		s.gate.RUnlock()
+	}
+}
+
+// rlock is lock for calls that only read
+func (s *Store) rlock(ctx context.Context) func() {
+	if ctx.Value(txKey{}) == s {
+		s.mu.RLock()
+		return s.mu.RUnlock
+	}
+
+	s.gate.RLock()
+	s.mu.RLock()
+	return func() {
+		s.mu.RUnlock()
+		s.gate.RUnlock()
+	}
+}
+
+// parseID converts a hex ID like the MongoDB repositories do, what names the kind of ID in the error
+func parseID(id, what string) (models.ID, error) {
+	objectID, err := models.ParseID(id)
+	if err != nil {
+		return models.NilID, errs.InvalidID(fmt.Sprintf("invalid %s ID format", what), err)
+	}
+	return objectID, nil
+}
+
+// stale reports whether a write expecting the given version has to be refused for the stored one
+func stale(version, stored int64) bool {
+	return version != models.AnyVersion && version != stored
+}
+
+// now returns the current time with the millisecond precision MongoDB stores
+func now() primitive.DateTime {
+	return primitive.NewDateTimeFromTime(time.Now())
+}
+
+// paginate returns one page of items in the given order with the same semantics as the MongoDB listings:
+// a cursor continues right after (or before) the item it was issued for, otherwise page and page size
+// select the page by offset. items may be reordered.
+func paginate[T any](items []T, order cursor.Order, pagination *models.Pagination, keyOf func(*T) cursor.Cursor) (*models.Page[T], error) {
+	from, direction, err := order.Resume(pagination)
+	if err != nil {
+		return nil, err
+	}
+	if from != nil {
+		if _, err := models.ParseID(from.ID); err != nil {
+			return nil, cursor.ErrInvalid
+		}
+	}
+
+	slices.SortFunc(items, func(a, b T) int {
+		return direction * compareKeys(order.Key, keyOf(&a), keyOf(&b))
+	})
+
+	start := min(max(pagination.Page-1, 0)*pagination.PageSize, len(items))
+	if from != nil {
+		start = len(items)
+		for i := range items {
+			if direction*compareKeys(order.Key, keyOf(&items[i]), *from) > 0 {
+				start = i
+				break
+			}
+		}
+	}
+	end := min(start+pagination.PageSize+1, len(items))
+
+	page := append(make([]T, 0, end-start), items[start:end]...)
+	return cursor.NewPage(page, int64(len(items)), from, order, pagination, keyOf), nil
+}
+
+// compareKeys orders two cursors by the sort key and then by ID, hex IDs sort like the ObjectIDs they encode
+func compareKeys(key string, a, b cursor.Cursor) int {
+	var c int
+	if key == cursor.KeyPrice {
+		c = cmp.Compare(a.Price, b.Price)
+	} else {
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// productKey, orderKey, userKey and promotionKey position a product, an order, a user or a promotion within a listing
+func productKey(p *models.Product) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: p.CreatedAt.Time(), Price: p.Price, ID: p.ID.Hex()}
+}
+
+func orderKey(o *models.Order) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: o.CreatedAt.Time(), Price: o.Total, ID: o.ID.Hex()}
+}
+
+func userKey(u *models.User) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: u.CreatedAt.Time(), ID: u.ID.Hex()}
+}
+
+func promotionKey(p *models.Promotion) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: p.CreatedAt.Time(), ID: p.ID.Hex()}
+}
+
+// cloneOrder copies the slices of an order so callers can't modify the stored one
+func cloneOrder(o models.Order) models.Order {
+	o.Items = slices.Clone(o.Items)
+	o.Discounts = slices.Clone(o.Discounts)
+	o.Taxes = slices.Clone(o.Taxes)
+	o.StatusHistory = slices.Clone(o.StatusHistory)
+	return o
+}
@@ -0,0 +1,268 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+)
+
+type OrderStorage struct {
+	store  *Store
+	logger *slog.Logger
+	cfg    *config.Config
+}
+
+func NewOrderStorage(store *Store, logger *slog.Logger, cfg *config.Config) repos.OrderRepo {
+	return &OrderStorage{
+		store:  store,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// CreateOrder stores a priced order, every order starts its lifecycle as pending
+func (o *OrderStorage) CreateOrder(ctx context.Context, order *models.Order) (string, error) {
+	o.logger.Info("starting order creation", "userID", order.UserID, "items", len(order.Items))
+
+	created_at := now()
+	newOrder := models.Order{
+		ID:     models.NewID(),
+		UserID: order.UserID,
+		Items:  slices.Clone(order.Items),
+		Status: models.OrderStatusPending,
+		StatusHistory: []models.StatusChange{
+			{To: models.OrderStatusPending, At: created_at},
+		},
+		Subtotal:  order.Subtotal,
+		Discounts: append([]models.AppliedDiscount{}, order.Discounts...),
+		Discount:  order.Discount,
+		TaxRegion: order.TaxRegion,
+		TaxMode:   order.TaxMode,
+		Taxes:     append([]models.TaxLine{}, order.Taxes...),
+		Net:       order.Net,
+		Tax:       order.Tax,
+		Total:     order.Total,
+		Currency:  order.Currency,
+		Version:   1,
+		CreatedAt: created_at,
+		UpdatedAt: created_at,
+	}
+
+	unlock := o.store.lock(ctx)
+	o.store.orders[newOrder.ID] = newOrder
+	unlock()
+
+	o.logger.Info("order creation successful", "orderID", newOrder.ID.Hex())
+	return newOrder.ID.Hex(), nil
+}
+
+// GetOrderByID fetches an order by its ID
+func (o *OrderStorage) GetOrderByID(ctx context.Context, orderID string) (*models.Order, error) {
+	objectID, err := parseID(orderID, "order")
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := o.store.rlock(ctx)
+	order, ok := o.store.orders[objectID]
+	unlock()
+	if !ok {
+		o.logger.Warn("order not found", "orderID", orderID)
+		return nil, errs.NotFound("order not found")
+	}
+
+	order = cloneOrder(order)
+	return &order, nil
+}
+
+// UpdateOrder replaces the lines, the discounts and the totals of an order, as long as it is still at the expected version
+func (o *OrderStorage) UpdateOrder(ctx context.Context, orderID string, version int64, updates *models.UpdatedOrder) error {
+	o.logger.Info("updating order", "orderID", orderID, "version", version)
+
+	objectID, err := parseID(orderID, "order")
+	if err != nil {
+		return err
+	}
+
+	defer o.store.lock(ctx)()
+
+	order, ok := o.store.orders[objectID]
+	if !ok {
+		o.logger.Warn("no order found to update", "orderID", orderID)
+		return errs.NotFound("order not found")
+	}
+	if stale(version, order.Version) {
+		o.logger.Warn("order version mismatch", "orderID", orderID, "expected", version)
+		return errs.PreconditionFailed("order was changed since it was read")
+	}
+
+	order.Items = slices.Clone(updates.Items)
+	order.Subtotal = updates.Subtotal
+	order.Discounts = append([]models.AppliedDiscount{}, updates.Discounts...)
+	order.Discount = updates.Discount
+	order.Taxes = append([]models.TaxLine{}, updates.Taxes...)
+	order.Net = updates.Net
+	order.Tax = updates.Tax
+	order.Total = updates.Total
+	order.Version++
+	order.UpdatedAt = now()
+	o.store.orders[objectID] = order
+
+	o.logger.Info("order updated successfully", "orderID", orderID, "items", len(updates.Items))
+	return nil
+}
+
+// DeleteOrder deletes an order by its ID, as long as it is still at the expected version
+func (o *OrderStorage) DeleteOrder(ctx context.Context, orderID string, version int64) error {
+	o.logger.Info("deleting order", "orderID", orderID, "version", version)
+
+	objectID, err := parseID(orderID, "order")
+	if err != nil {
+		return err
+	}
+
+	defer o.store.lock(ctx)()
+
+	order, ok := o.store.orders[objectID]
+	if !ok {
+		o.logger.Warn("no order found to delete", "orderID", orderID)
+		return errs.NotFound("order not found")
+	}
+	if stale(version, order.Version) {
+		o.logger.Warn("order version mismatch", "orderID", orderID, "expected", version)
+		return errs.PreconditionFailed("order was changed since it was read")
+	}
+	delete(o.store.orders, objectID)
+
+	o.logger.Info("order deleted successfully", "orderID", orderID)
+	return nil
+}
+
+// TransitionOrderStatus moves an order from one status to another and records the change in its history.
+// The change only applies while the order is still in the from status.
+func (o *OrderStorage) TransitionOrderStatus(ctx context.Context, orderID, from, to string) (*models.Order, error) {
+	o.logger.Info("transitioning order status", "orderID", orderID, "from", from, "to", to)
+
+	objectID, err := parseID(orderID, "order")
+	if err != nil {
+		return nil, err
+	}
+
+	defer o.store.lock(ctx)()
+
+	order, ok := o.store.orders[objectID]
+	if !ok {
+		o.logger.Warn("order not found", "orderID", orderID)
+		return nil, errs.NotFound("order not found")
+	}
+	if order.Status != from {
+		o.logger.Warn("order status changed concurrently", "orderID", orderID, "expected", from)
+		return nil, errs.Conflict("order status was changed concurrently, try again")
+	}
+
+	changedAt := now()
+	order = cloneOrder(order)
+	order.Status = to
+	order.StatusHistory = append(order.StatusHistory, models.StatusChange{From: from, To: to, At: changedAt})
+	order.Version++
+	order.UpdatedAt = changedAt
+	o.store.orders[objectID] = order
+
+	o.logger.Info("order status transitioned successfully", "orderID", orderID, "status", to)
+	order = cloneOrder(order)
+	return &order, nil
+}
+
+// ListOrders lists all orders, newest first
+func (o *OrderStorage) ListOrders(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Order], error) {
+	return o.find(ctx, func(*models.Order) bool { return true }, cursor.NewestFirst, pagination)
+}
+
+// ListOrdersByUser lists the orders placed by a single user, newest first
+func (o *OrderStorage) ListOrdersByUser(ctx context.Context, userID string, pagination *models.Pagination) (*models.Page[models.Order], error) {
+	objectID, err := parseID(userID, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	return o.find(ctx, func(order *models.Order) bool {
+		return order.UserID == objectID
+	}, cursor.NewestFirst, pagination)
+}
+
+// ListOrdersWithUsers lists orders together with the name of the user who placed them, newest first
+func (o *OrderStorage) ListOrdersWithUsers(ctx context.Context, pagination *models.Pagination) (*models.Page[models.OrderWithUser], error) {
+	defer o.store.rlock(ctx)()
+
+	orders := make([]models.Order, 0, len(o.store.orders))
+	for _, order := range o.store.orders {
+		orders = append(orders, order)
+	}
+	slices.SortFunc(orders, func(a, b models.Order) int {
+		return -compareKeys(cursor.KeyCreatedAt, orderKey(&a), orderKey(&b))
+	})
+
+	skip := min(max(pagination.Page-1, 0)*pagination.PageSize, len(orders))
+	end := min(skip+pagination.PageSize, len(orders))
+
+	page := &models.Page[models.OrderWithUser]{
+		Items:    make([]models.OrderWithUser, 0, end-skip),
+		Page:     pagination.Page,
+		PageSize: pagination.PageSize,
+		Total:    int64(len(orders)),
+		HasNext:  end < len(orders),
+	}
+	for _, order := range orders[skip:end] {
+		// Orders of deleted users keep an empty name
+		page.Items = append(page.Items, models.OrderWithUser{
+			OrderID:   order.ID.Hex(),
+			UserID:    order.UserID.Hex(),
+			UserName:  o.store.users[order.UserID].Name,
+			Items:     slices.Clone(order.Items),
+			Status:    order.Status,
+			Total:     order.Total,
+			Currency:  order.Currency,
+			CreatedAt: order.CreatedAt.Time().UTC(),
+		})
+	}
+
+	return page, nil
+}
+
+// ListOrdersByDateRange lists the orders created between startDate and endDate inclusive, sorted by creation time
+func (o *OrderStorage) ListOrdersByDateRange(ctx context.Context, order int8, pagination *models.Pagination, startDate, endDate time.Time) (*models.Page[models.Order], error) {
+	page, err := o.find(ctx, func(o *models.Order) bool {
+		createdAt := o.CreatedAt.Time()
+		return !createdAt.Before(startDate) && !createdAt.After(endDate)
+	}, cursor.OldestFirst.WithDirection(order), pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) == 0 {
+		return nil, errs.NotFound("no orders found in the given date range")
+	}
+
+	return page, nil
+}
+
+// find pages through the orders that match
+func (o *OrderStorage) find(ctx context.Context, match func(*models.Order) bool, order cursor.Order, pagination *models.Pagination) (*models.Page[models.Order], error) {
+	unlock := o.store.rlock(ctx)
+	orders := make([]models.Order, 0, len(o.store.orders))
+	for _, stored := range o.store.orders {
+		if match(&stored) {
+			orders = append(orders, cloneOrder(stored))
+		}
+	}
+	unlock()
+
+	return paginate(orders, order, pagination, orderKey)
+}
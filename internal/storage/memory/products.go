@@ -0,0 +1,274 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+)
+
+type ProductStorage struct {
+	store  *Store
+	logger *slog.Logger
+	cfg    *config.Config
+}
+
+func NewProductStorage(store *Store, logger *slog.Logger, cfg *config.Config) repos.ProductRepo {
+	return &ProductStorage{
+		store:  store,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// CreateProduct stores a new product
+func (p *ProductStorage) CreateProduct(ctx context.Context, product *models.ProductCreate) (string, error) {
+	p.logger.Info("starting product creation", "name", product.Name)
+
+	created_at := now()
+	newProduct := models.Product{
+		ID:          models.NewID(),
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		Currency:    product.Currency,
+		TaxClass:    product.TaxClass,
+		Stock:       product.Stock,
+		Version:     1,
+		CreatedAt:   created_at,
+		UpdatedAt:   created_at,
+	}
+
+	unlock := p.store.lock(ctx)
+	p.store.products[newProduct.ID] = newProduct
+	unlock()
+
+	p.logger.Info("product creation successful", "productID", newProduct.ID.Hex())
+	return newProduct.ID.Hex(), nil
+}
+
+// GetProductByID fetches a product by its ID
+func (p *ProductStorage) GetProductByID(ctx context.Context, productID string) (*models.Product, error) {
+	objectID, err := parseID(productID, "product")
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := p.store.rlock(ctx)
+	product, ok := p.store.products[objectID]
+	unlock()
+	if !ok {
+		p.logger.Warn("product not found", "productID", productID)
+		return nil, errs.NotFound("product not found")
+	}
+
+	return &product, nil
+}
+
+// UpdateProduct replaces the editable fields of a product, as long as it is still at the expected version
+func (p *ProductStorage) UpdateProduct(ctx context.Context, productID string, version int64, updates *models.ProductUpdate) error {
+	p.logger.Info("updating product", "productID", productID, "version", version)
+
+	objectID, err := parseID(productID, "product")
+	if err != nil {
+		return err
+	}
+
+	defer p.store.lock(ctx)()
+
+	product, ok := p.store.products[objectID]
+	if !ok {
+		p.logger.Warn("no product found to update", "productID", productID)
+		return errs.NotFound("product not found")
+	}
+	if stale(version, product.Version) {
+		p.logger.Warn("product version mismatch", "productID", productID, "expected", version)
+		return errs.PreconditionFailed("product was changed since it was read")
+	}
+
+	product.Name = updates.Name
+	product.Description = updates.Description
+	product.Price = updates.Price
+	product.Currency = updates.Currency
+	product.TaxClass = updates.TaxClass
+	product.Stock = updates.Stock
+	product.Version++
+	product.UpdatedAt = now()
+	p.store.products[objectID] = product
+
+	p.logger.Info("product updated successfully", "productID", productID)
+	return nil
+}
+
+// PatchProduct sets the fields present in the patch, as long as the product is still at the expected version
+func (p *ProductStorage) PatchProduct(ctx context.Context, productID string, version int64, patch *models.ProductPatch) error {
+	p.logger.Info("patching product", "productID", productID, "version", version)
+
+	objectID, err := parseID(productID, "product")
+	if err != nil {
+		return err
+	}
+
+	defer p.store.lock(ctx)()
+
+	product, ok := p.store.products[objectID]
+	if !ok {
+		p.logger.Warn("no product found to patch", "productID", productID)
+		return errs.NotFound("product not found")
+	}
+	if stale(version, product.Version) {
+		p.logger.Warn("product version mismatch", "productID", productID, "expected", version)
+		return errs.PreconditionFailed("product was changed since it was read")
+	}
+
+	if patch.Name != nil {
+		product.Name = *patch.Name
+	}
+	if patch.Description != nil {
+		product.Description = *patch.Description
+	}
+	if patch.Price != nil {
+		product.Price = *patch.Price
+	}
+	if patch.Currency != nil {
+		product.Currency = *patch.Currency
+	}
+	if patch.TaxClass != nil {
+		product.TaxClass = *patch.TaxClass
+	}
+	if patch.Stock != nil {
+		product.Stock = *patch.Stock
+	}
+	product.Version++
+	product.UpdatedAt = now()
+	p.store.products[objectID] = product
+
+	p.logger.Info("product patched successfully", "productID", productID)
+	return nil
+}
+
+// DeleteProduct deletes a product by its ID, as long as it is still at the expected version
+func (p *ProductStorage) DeleteProduct(ctx context.Context, productID string, version int64) error {
+	p.logger.Info("deleting product", "productID", productID, "version", version)
+
+	objectID, err := parseID(productID, "product")
+	if err != nil {
+		return err
+	}
+
+	defer p.store.lock(ctx)()
+
+	product, ok := p.store.products[objectID]
+	if !ok {
+		p.logger.Warn("no product found to delete", "productID", productID)
+		return errs.NotFound("product not found")
+	}
+	if stale(version, product.Version) {
+		p.logger.Warn("product version mismatch", "productID", productID, "expected", version)
+		return errs.PreconditionFailed("product was changed since it was read")
+	}
+	delete(p.store.products, objectID)
+
+	p.logger.Info("product deleted successfully", "productID", productID)
+	return nil
+}
+
+// ReserveStock takes quantity units out of the product stock, or none when fewer units are left
+func (p *ProductStorage) ReserveStock(ctx context.Context, productID string, quantity int) error {
+	objectID, err := parseID(productID, "product")
+	if err != nil {
+		return err
+	}
+
+	defer p.store.lock(ctx)()
+
+	product, ok := p.store.products[objectID]
+	if !ok {
+		p.logger.Warn("product not found", "productID", productID)
+		return errs.NotFound("product not found")
+	}
+	if product.Stock < quantity {
+		p.logger.Warn("insufficient stock", "productID", productID, "quantity", quantity)
+		return &errs.InsufficientStockError{ProductID: productID, Requested: quantity}
+	}
+
+	product.Stock -= quantity
+	product.UpdatedAt = now()
+	p.store.products[objectID] = product
+
+	p.logger.Info("stock reserved successfully", "productID", productID, "quantity", quantity)
+	return nil
+}
+
+// ReleaseStock puts quantity previously reserved units back into the product stock
+func (p *ProductStorage) ReleaseStock(ctx context.Context, productID string, quantity int) error {
+	objectID, err := parseID(productID, "product")
+	if err != nil {
+		return err
+	}
+
+	defer p.store.lock(ctx)()
+
+	product, ok := p.store.products[objectID]
+	if !ok {
+		p.logger.Warn("product not found", "productID", productID)
+		return errs.NotFound("product not found")
+	}
+
+	product.Stock += quantity
+	product.UpdatedAt = now()
+	p.store.products[objectID] = product
+
+	p.logger.Info("stock released successfully", "productID", productID, "quantity", quantity)
+	return nil
+}
+
+// ListProducts lists all products, oldest first
+func (p *ProductStorage) ListProducts(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	return p.find(ctx, func(*models.Product) bool { return true }, cursor.OldestFirst, pagination)
+}
+
+// SearchProductsByName lists the products whose name matches the pattern, case-insensitive like the MongoDB $regex search
+func (p *ProductStorage) SearchProductsByName(ctx context.Context, name string, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	pattern, err := regexp.Compile("(?i)" + name)
+	if err != nil {
+		return nil, errs.Validation("invalid search pattern")
+	}
+
+	return p.find(ctx, func(product *models.Product) bool {
+		return pattern.MatchString(product.Name)
+	}, cursor.OldestFirst, pagination)
+}
+
+// ExactSearchProductsByPrice lists the products priced in currency with exactly the given price, newest first
+func (p *ProductStorage) ExactSearchProductsByPrice(ctx context.Context, price models.Money, currency string, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	return p.find(ctx, func(product *models.Product) bool {
+		return product.Currency == currency && product.Price == price
+	}, cursor.NewestFirst, pagination)
+}
+
+// SearchProductsByPriceRange lists the products priced in currency between minPrice and maxPrice inclusive, sorted by price
+func (p *ProductStorage) SearchProductsByPriceRange(ctx context.Context, order int8, minPrice, maxPrice models.Money, currency string, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	return p.find(ctx, func(product *models.Product) bool {
+		return product.Currency == currency && product.Price >= minPrice && product.Price <= maxPrice
+	}, cursor.CheapestFirst.WithDirection(order), pagination)
+}
+
+// find pages through the products that match
+func (p *ProductStorage) find(ctx context.Context, match func(*models.Product) bool, order cursor.Order, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	unlock := p.store.rlock(ctx)
+	products := make([]models.Product, 0, len(p.store.products))
+	for _, product := range p.store.products {
+		if match(&product) {
+			products = append(products, product)
+		}
+	}
+	unlock()
+
+	return paginate(products, order, pagination, productKey)
+}
@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+)
+
+type PromotionStorage struct {
+	store  *Store
+	logger *slog.Logger
+	cfg    *config.Config
+}
+
+func NewPromotionStorage(store *Store, logger *slog.Logger, cfg *config.Config) repos.PromotionRepo {
+	return &PromotionStorage{
+		store:  store,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// CreatePromotion stores a new promotion, codes are unique like under the MongoDB unique index
+func (p *PromotionStorage) CreatePromotion(ctx context.Context, promotion *models.PromotionCreate) (string, error) {
+	p.logger.Info("starting promotion creation", "code", promotion.Code)
+
+	defer p.store.lock(ctx)()
+
+	for _, existing := range p.store.promotions {
+		if existing.Code == promotion.Code {
+			p.logger.Warn("promotion code already exists", "code", promotion.Code)
+			return "", errs.Conflict("promotion code already exists")
+		}
+	}
+
+	created_at := now()
+	newPromotion := models.Promotion{
+		ID:            models.NewID(),
+		Code:          promotion.Code,
+		Description:   promotion.Description,
+		Type:          promotion.Type,
+		Percent:       promotion.Percent,
+		Amount:        promotion.Amount,
+		ProductID:     promotion.ProductID,
+		BuyQuantity:   promotion.BuyQuantity,
+		FreeQuantity:  promotion.FreeQuantity,
+		MinOrderValue: promotion.MinOrderValue,
+		Currency:      promotion.Currency,
+		StartsAt:      promotion.StartsAt,
+		EndsAt:        promotion.EndsAt,
+		UsageLimit:    promotion.UsageLimit,
+		PerUserLimit:  promotion.PerUserLimit,
+		CreatedAt:     created_at,
+		UpdatedAt:     created_at,
+	}
+	p.store.promotions[newPromotion.ID] = newPromotion
+
+	p.logger.Info("promotion creation successful", "promotionID", newPromotion.ID.Hex())
+	return newPromotion.ID.Hex(), nil
+}
+
+// GetPromotionByID fetches a promotion by its ID
+func (p *PromotionStorage) GetPromotionByID(ctx context.Context, promotionID string) (*models.Promotion, error) {
+	objectID, err := parseID(promotionID, "promotion")
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := p.store.rlock(ctx)
+	promotion, ok := p.store.promotions[objectID]
+	unlock()
+	if !ok {
+		p.logger.Warn("promotion not found", "promotionID", promotionID)
+		return nil, errs.NotFound("promotion not found")
+	}
+
+	return &promotion, nil
+}
+
+// GetPromotionByCode fetches a promotion by its code
+func (p *PromotionStorage) GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	defer p.store.rlock(ctx)()
+
+	for _, promotion := range p.store.promotions {
+		if promotion.Code == code {
+			return &promotion, nil
+		}
+	}
+
+	p.logger.Warn("promotion not found", "code", code)
+	return nil, errs.NotFound("promotion not found")
+}
+
+// DeletePromotion deletes a promotion and the uses counted for it, the orders it was applied to keep their discounts
+func (p *PromotionStorage) DeletePromotion(ctx context.Context, promotionID string) error {
+	p.logger.Info("deleting promotion", "promotionID", promotionID)
+
+	objectID, err := parseID(promotionID, "promotion")
+	if err != nil {
+		return err
+	}
+
+	defer p.store.lock(ctx)()
+
+	if _, ok := p.store.promotions[objectID]; !ok {
+		p.logger.Warn("no promotion found to delete", "promotionID", promotionID)
+		return errs.NotFound("promotion not found")
+	}
+	delete(p.store.promotions, objectID)
+	for key := range p.store.redemptions {
+		if key.promotionID == objectID {
+			delete(p.store.redemptions, key)
+		}
+	}
+
+	p.logger.Info("promotion deleted successfully", "promotionID", promotionID)
+	return nil
+}
+
+// ListPromotions lists all promotions, oldest first
+func (p *PromotionStorage) ListPromotions(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Promotion], error) {
+	unlock := p.store.rlock(ctx)
+	promotions := make([]models.Promotion, 0, len(p.store.promotions))
+	for _, promotion := range p.store.promotions {
+		promotions = append(promotions, promotion)
+	}
+	unlock()
+
+	return paginate(promotions, cursor.OldestFirst, pagination, promotionKey)
+}
+
+// RedeemPromotion counts a use of the promotion by the user, checking both limits under the store lock
+func (p *PromotionStorage) RedeemPromotion(ctx context.Context, promotionID, userID string) error {
+	p.logger.Info("redeeming promotion", "promotionID", promotionID, "userID", userID)
+
+	objectID, err := parseID(promotionID, "promotion")
+	if err != nil {
+		return err
+	}
+	user, err := parseID(userID, "user")
+	if err != nil {
+		return err
+	}
+
+	defer p.store.lock(ctx)()
+
+	promotion, ok := p.store.promotions[objectID]
+	if !ok {
+		p.logger.Warn("promotion not found", "promotionID", promotionID)
+		return errs.NotFound("promotion not found")
+	}
+	if promotion.UsageLimit > 0 && promotion.UsageCount >= promotion.UsageLimit {
+		p.logger.Warn("promotion used up", "promotionID", promotionID)
+		return errs.Conflict("promotion has reached its usage limit")
+	}
+	key := redemption{promotionID: objectID, userID: user}
+	if promotion.PerUserLimit > 0 && p.store.redemptions[key] >= promotion.PerUserLimit {
+		p.logger.Warn("promotion used up by user", "promotionID", promotionID, "userID", userID)
+		return errs.Conflict("promotion has reached its usage limit for this user")
+	}
+
+	promotion.UsageCount++
+	p.store.promotions[objectID] = promotion
+	p.store.redemptions[key]++
+
+	p.logger.Info("promotion redeemed successfully", "promotionID", promotionID, "userID", userID)
+	return nil
+}
+
+// ReleasePromotion takes back a use of the promotion by the user, the counts never go below zero
+func (p *PromotionStorage) ReleasePromotion(ctx context.Context, promotionID, userID string) error {
+	p.logger.Info("releasing promotion", "promotionID", promotionID, "userID", userID)
+
+	objectID, err := parseID(promotionID, "promotion")
+	if err != nil {
+		return err
+	}
+	user, err := parseID(userID, "user")
+	if err != nil {
+		return err
+	}
+
+	defer p.store.lock(ctx)()
+
+	promotion, ok := p.store.promotions[objectID]
+	if !ok {
+		p.logger.Warn("promotion not found", "promotionID", promotionID)
+		return errs.NotFound("promotion not found")
+	}
+	if promotion.UsageCount > 0 {
+		promotion.UsageCount--
+		p.store.promotions[objectID] = promotion
+	}
+	key := redemption{promotionID: objectID, userID: user}
+	if p.store.redemptions[key] > 0 {
+		p.store.redemptions[key]--
+	}
+
+	p.logger.Info("promotion released successfully", "promotionID", promotionID, "userID", userID)
+	return nil
+}
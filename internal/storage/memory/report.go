@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+)
+
+type ReportStorage struct {
+	store  *Store
+	logger *slog.Logger
+	cfg    *config.Config
+}
+
+func NewReportStorage(store *Store, logger *slog.Logger, cfg *config.Config) repos.ReportRepo {
+	return &ReportStorage{
+		store:  store,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// isSale reports whether an order turned into revenue, cancelled and refunded orders don't count
+func isSale(order *models.Order) bool {
+	return order.Status != models.OrderStatusCancelled && order.Status != models.OrderStatusRefunded
+}
+
+// GetReport calculates the overall number of products, orders and the revenue with and without the tax per currency
+func (r *ReportStorage) GetReport(ctx context.Context) (*models.Report, error) {
+	defer r.store.rlock(ctx)()
+
+	currencies := make(map[string]*models.Revenue)
+	report := &models.Report{TotalProducts: len(r.store.products), Revenue: []models.Revenue{}}
+	for _, order := range r.store.orders {
+		if !isSale(&order) {
+			continue
+		}
+		report.TotalOrders++
+
+		revenue, ok := currencies[order.Currency]
+		if !ok {
+			revenue = &models.Revenue{Currency: order.Currency}
+			currencies[order.Currency] = revenue
+		}
+		revenue.TotalOrders++
+		revenue.TotalRevenue += order.Total
+		revenue.NetRevenue += order.Net
+		revenue.TotalTax += order.Tax
+	}
+
+	for _, revenue := range currencies {
+		report.Revenue = append(report.Revenue, *revenue)
+	}
+	slices.SortFunc(report.Revenue, func(a, b models.Revenue) int {
+		return cmp.Compare(a.Currency, b.Currency)
+	})
+
+	return report, nil
+}
+
+// sale identifies the sales of a product in one currency
+type sale struct {
+	productID models.ID
+	currency  string
+}
+
+// GetTopProducts returns the best selling products per currency, ranked by units sold, then by revenue,
+// then by ID and then by currency
+func (r *ReportStorage) GetTopProducts(ctx context.Context, limit int) ([]models.TopProduct, error) {
+	defer r.store.rlock(ctx)()
+
+	totals := make(map[sale]*models.TopProduct)
+	for _, order := range r.store.orders {
+		if !isSale(&order) {
+			continue
+		}
+		for _, item := range order.Items {
+			key := sale{item.ProductID, order.Currency}
+			total, ok := totals[key]
+			if !ok {
+				// Products that were deleted keep an empty name
+				total = &models.TopProduct{ProductID: item.ProductID.Hex(), Name: r.store.products[item.ProductID].Name, Currency: order.Currency}
+				totals[key] = total
+			}
+			total.TotalSold += item.Quantity
+			total.TotalRevenue += item.LineTotal
+		}
+	}
+
+	products := make([]models.TopProduct, 0, len(totals))
+	for _, total := range totals {
+		products = append(products, *total)
+	}
+	slices.SortFunc(products, func(a, b models.TopProduct) int {
+		if c := cmp.Compare(b.TotalSold, a.TotalSold); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.TotalRevenue, a.TotalRevenue); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.ProductID, b.ProductID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Currency, b.Currency)
+	})
+
+	return products[:min(limit, len(products))], nil
+}
+
+// dayInCurrency identifies the orders of a day in one currency
+type dayInCurrency struct {
+	date     string
+	currency string
+}
+
+// GetDailyOrderAggregates groups the orders created between startDate and endDate by UTC day and currency
+func (r *ReportStorage) GetDailyOrderAggregates(ctx context.Context, startDate, endDate time.Time) ([]models.OrderAggregate, error) {
+	defer r.store.rlock(ctx)()
+
+	days := make(map[dayInCurrency]*models.OrderAggregate)
+	for _, order := range r.store.orders {
+		createdAt := order.CreatedAt.Time().UTC()
+		if !isSale(&order) || createdAt.Before(startDate) || createdAt.After(endDate) {
+			continue
+		}
+
+		key := dayInCurrency{createdAt.Format(time.DateOnly), order.Currency}
+		day, ok := days[key]
+		if !ok {
+			day = &models.OrderAggregate{Date: key.date, Currency: key.currency}
+			days[key] = day
+		}
+		day.TotalOrders++
+		day.TotalRevenue += order.Total
+		day.NetRevenue += order.Net
+		day.TotalTax += order.Tax
+	}
+
+	aggregates := make([]models.OrderAggregate, 0, len(days))
+	for _, day := range days {
+		aggregates = append(aggregates, *day)
+	}
+	slices.SortFunc(aggregates, func(a, b models.OrderAggregate) int {
+		if c := cmp.Compare(a.Date, b.Date); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Currency, b.Currency)
+	})
+
+	return aggregates, nil
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+)
+
+// txKey marks the contexts of a running unit of work with the store it runs on
+type txKey struct{}
+
+// Transactor runs units of work one at a time. A unit of work keeps the repository calls made outside
+// of it waiting until it is done, and puts the store back the way it found it when it fails.
+type Transactor struct {
+	store  *Store
+	logger *slog.Logger
+}
+
+func NewTransactor(store *Store, logger *slog.Logger) repos.Transactor {
+	return &Transactor{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// WithTransaction runs fn with the store to itself, rolling back its changes when it returns an error
+func (t *Transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) == t.store {
+		return fn(ctx)
+	}
+
+	t.store.gate.Lock()
+	defer t.store.gate.Unlock()
+
+	t.store.mu.RLock()
+	saved := t.store.snapshot()
+	t.store.mu.RUnlock()
+
+	if err := fn(context.WithValue(ctx, txKey{}, t.store)); err != nil {
+		t.store.mu.Lock()
+		t.store.restore(saved)
+		t.store.mu.Unlock()
+
+		t.logger.Info("unit of work rolled back", "error", err)
+		return err
+	}
+	return nil
+}
+
+// snapshot copies the collections deeply enough that changes to the store don't reach the copy
+func (s *Store) snapshot() *Store {
+	saved := &Store{
+		products: maps.Clone(s.products),
+		orders:   make(map[models.ID]models.Order, len(s.orders)),
+		users:    maps.Clone(s.users),
+		carts:    make(map[models.ID]models.Cart, len(s.carts)),
+
+		promotions:  maps.Clone(s.promotions),
+		redemptions: maps.Clone(s.redemptions),
+	}
+	for id, order := range s.orders {
+		saved.orders[id] = cloneOrder(order)
+	}
+	for id, cart := range s.carts {
+		cart.Items = slices.Clone(cart.Items)
+		saved.carts[id] = cart
+	}
+	return saved
+}
+
+// restore puts back the collections of a snapshot
+func (s *Store) restore(saved *Store) {
+	s.products, s.orders, s.users, s.carts = saved.products, saved.orders, saved.users, saved.carts
+	s.promotions, s.redemptions = saved.promotions, saved.redemptions
+}
@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+)
+
+type UserStorage struct {
+	store  *Store
+	logger *slog.Logger
+	cfg    *config.Config
+}
+
+func NewUserStorage(store *Store, logger *slog.Logger, cfg *config.Config) repos.UserRepo {
+	return &UserStorage{
+		store:  store,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// CreateUser stores a new user, emails are unique like under the MongoDB unique index
+func (u *UserStorage) CreateUser(ctx context.Context, passwordHash string, user *models.UserCreate) (string, error) {
+	u.logger.Info("starting user creation", "email", user.Email)
+
+	defer u.store.lock(ctx)()
+
+	if u.emailTaken(user.Email, models.NilID) {
+		u.logger.Warn("email already registered", "email", user.Email)
+		return "", errs.Conflict("email is already registered")
+	}
+
+	created_at := now()
+	newUser := models.User{
+		ID:           models.NewID(),
+		Name:         user.Name,
+		Email:        user.Email,
+		PasswordHash: passwordHash,
+		Role:         user.Role,
+		CreatedAt:    created_at,
+		UpdatedAt:    created_at,
+	}
+	u.store.users[newUser.ID] = newUser
+
+	u.logger.Info("user creation successful", "userID", newUser.ID.Hex())
+	return newUser.ID.Hex(), nil
+}
+
+// GetUserByID fetches a user by its ID
+func (u *UserStorage) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	objectID, err := parseID(userID, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := u.store.rlock(ctx)
+	user, ok := u.store.users[objectID]
+	unlock()
+	if !ok {
+		u.logger.Warn("user not found", "userID", userID)
+		return nil, errs.NotFound("user not found")
+	}
+
+	return &user, nil
+}
+
+// GetUserByEmail fetches a user by its email address
+func (u *UserStorage) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	defer u.store.rlock(ctx)()
+
+	for _, user := range u.store.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+
+	u.logger.Warn("user not found", "email", email)
+	return nil, errs.NotFound("user not found")
+}
+
+// UpdateUser changes the name and email of a user, and the role when one is given
+func (u *UserStorage) UpdateUser(ctx context.Context, userID string, updates *models.UserUpdate) error {
+	u.logger.Info("updating user", "userID", userID)
+
+	objectID, err := parseID(userID, "user")
+	if err != nil {
+		return err
+	}
+
+	defer u.store.lock(ctx)()
+
+	user, ok := u.store.users[objectID]
+	if !ok {
+		u.logger.Warn("no user found to update", "userID", userID)
+		return errs.NotFound("user not found")
+	}
+	if u.emailTaken(updates.Email, objectID) {
+		u.logger.Warn("email already registered", "email", updates.Email)
+		return errs.Conflict("email is already registered")
+	}
+
+	user.Name = updates.Name
+	user.Email = updates.Email
+	if updates.Role != "" {
+		user.Role = updates.Role
+	}
+	user.UpdatedAt = now()
+	u.store.users[objectID] = user
+
+	u.logger.Info("user updated successfully", "userID", userID)
+	return nil
+}
+
+// DeleteUser deletes a user by its ID
+func (u *UserStorage) DeleteUser(ctx context.Context, userID string) error {
+	u.logger.Info("deleting user", "userID", userID)
+
+	objectID, err := parseID(userID, "user")
+	if err != nil {
+		return err
+	}
+
+	defer u.store.lock(ctx)()
+
+	if _, ok := u.store.users[objectID]; !ok {
+		u.logger.Warn("no user found to delete", "userID", userID)
+		return errs.NotFound("user not found")
+	}
+	delete(u.store.users, objectID)
+
+	u.logger.Info("user deleted successfully", "userID", userID)
+	return nil
+}
+
+// ListUsers lists all users, oldest first
+func (u *UserStorage) ListUsers(ctx context.Context, pagination *models.Pagination) (*models.Page[models.User], error) {
+	unlock := u.store.rlock(ctx)
+	users := make([]models.User, 0, len(u.store.users))
+	for _, user := range u.store.users {
+		users = append(users, user)
+	}
+	unlock()
+
+	return paginate(users, cursor.OldestFirst, pagination, userKey)
+}
+
+// emailTaken reports whether another user than except already uses the email, the caller holds the lock
+func (u *UserStorage) emailTaken(email string, except models.ID) bool {
+	for id, user := range u.store.users {
+		if user.Email == email && id != except {
+			return true
+		}
+	}
+	return false
+}
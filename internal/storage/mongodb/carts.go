@@ -0,0 +1,185 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CartStorage struct {
+	db     *mongo.Collection
+	logger *slog.Logger
+	cfg    *config.Config
+}
+
+func NewCartStorage(db *mongo.Database, logger *slog.Logger, cfg *config.Config) repos.CartRepo {
+	return &CartStorage{
+		db:     db.Collection("Carts"),
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// GetCartByUser fetches the cart of a user, carts past their expiry count as missing
+// even if the TTL monitor hasn't removed them yet
+func (c *CartStorage) GetCartByUser(ctx context.Context, userID string) (*models.Cart, error) {
+	c.logger.Info("fetching cart of user", "userID", userID)
+
+	// Convert string ID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.logger.Error("invalid user ID format", "error", err)
+		return nil, errs.InvalidID("invalid user ID format", err)
+	}
+
+	filter := bson.M{
+		"userId":    objectID,
+		"expiresAt": bson.M{"$gt": primitive.NewDateTimeFromTime(time.Now())},
+	}
+
+	var cart models.Cart
+	err = c.db.FindOne(ctx, filter).Decode(&cart)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.logger.Warn("cart not found", "userID", userID)
+			return nil, errs.NotFound("cart not found")
+		}
+		c.logger.Error("failed to fetch cart from database", "error", err)
+		return nil, fmt.Errorf("failed to fetch cart: %w", err)
+	}
+
+	c.logger.Info("successfully fetched cart", "userID", userID, "items", len(cart.Items))
+	return &cart, nil
+}
+
+// SetCartItem sets the quantity of a product in the cart of a user, creating the cart and the line when needed.
+// Every change pushes the expiry of the cart forward by the configured TTL.
+func (c *CartStorage) SetCartItem(ctx context.Context, userID, productID string, quantity int) error {
+	c.logger.Info("setting cart item", "userID", userID, "productID", productID, "quantity", quantity)
+
+	// Convert string IDs to ObjectIDs
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.logger.Error("invalid user ID format", "error", err)
+		return errs.InvalidID("invalid user ID format", err)
+	}
+	productObjectID, err := primitive.ObjectIDFromHex(productID)
+	if err != nil {
+		c.logger.Error("invalid product ID format", "error", err)
+		return errs.InvalidID("invalid product ID format", err)
+	}
+
+	now := time.Now()
+	updatedAt := primitive.NewDateTimeFromTime(now)
+	expiresAt := primitive.NewDateTimeFromTime(now.Add(c.cfg.Cart.TTL))
+
+	// Two attempts: a concurrent request may add the same product between the update and the push
+	for attempt := 0; attempt < 2; attempt++ {
+		// Change the quantity when the product is already in the cart
+		result, err := c.db.UpdateOne(ctx,
+			bson.M{"userId": userObjectID, "items.productId": productObjectID},
+			bson.M{"$set": bson.M{"items.$.quantity": quantity, "updatedAt": updatedAt, "expiresAt": expiresAt}},
+		)
+		if err != nil {
+			c.logger.Error("failed to update cart item", "error", err)
+			return fmt.Errorf("failed to update cart item: %w", err)
+		}
+		if result.MatchedCount > 0 {
+			c.logger.Info("cart item updated successfully", "userID", userID, "productID", productID)
+			return nil
+		}
+
+		// Otherwise append the line, creating the cart if the user has none
+		_, err = c.db.UpdateOne(ctx,
+			bson.M{"userId": userObjectID, "items.productId": bson.M{"$ne": productObjectID}},
+			bson.M{
+				"$push":        bson.M{"items": models.CartItem{ProductID: models.ID(productObjectID), Quantity: quantity}},
+				"$set":         bson.M{"updatedAt": updatedAt, "expiresAt": expiresAt},
+				"$setOnInsert": bson.M{"createdAt": updatedAt},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			c.logger.Info("cart item added successfully", "userID", userID, "productID", productID)
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			c.logger.Error("failed to add cart item", "error", err)
+			return fmt.Errorf("failed to add cart item: %w", err)
+		}
+	}
+
+	c.logger.Warn("cart changed concurrently", "userID", userID)
+	return errs.Conflict("cart was changed concurrently, try again")
+}
+
+// RemoveCartItem removes a product from the cart of a user
+func (c *CartStorage) RemoveCartItem(ctx context.Context, userID, productID string) error {
+	c.logger.Info("removing cart item", "userID", userID, "productID", productID)
+
+	// Convert string IDs to ObjectIDs
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.logger.Error("invalid user ID format", "error", err)
+		return errs.InvalidID("invalid user ID format", err)
+	}
+	productObjectID, err := primitive.ObjectIDFromHex(productID)
+	if err != nil {
+		c.logger.Error("invalid product ID format", "error", err)
+		return errs.InvalidID("invalid product ID format", err)
+	}
+
+	now := time.Now()
+	result, err := c.db.UpdateOne(ctx,
+		bson.M{"userId": userObjectID, "items.productId": productObjectID},
+		bson.M{
+			"$pull": bson.M{"items": bson.M{"productId": productObjectID}},
+			"$set": bson.M{
+				"updatedAt": primitive.NewDateTimeFromTime(now),
+				"expiresAt": primitive.NewDateTimeFromTime(now.Add(c.cfg.Cart.TTL)),
+			},
+		},
+	)
+	if err != nil {
+		c.logger.Error("failed to remove cart item", "error", err)
+		return fmt.Errorf("failed to remove cart item: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		c.logger.Warn("no cart item found to remove", "userID", userID, "productID", productID)
+		return errs.NotFound("product is not in the cart")
+	}
+
+	c.logger.Info("cart item removed successfully", "userID", userID, "productID", productID)
+	return nil
+}
+
+// DeleteCart removes the cart of a user, deleting a missing cart is not an error
+func (c *CartStorage) DeleteCart(ctx context.Context, userID string) error {
+	c.logger.Info("deleting cart", "userID", userID)
+
+	// Convert string ID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.logger.Error("invalid user ID format", "error", err)
+		return errs.InvalidID("invalid user ID format", err)
+	}
+
+	if _, err := c.db.DeleteOne(ctx, bson.M{"userId": objectID}); err != nil {
+		c.logger.Error("failed to delete cart", "error", err)
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+
+	c.logger.Info("cart deleted successfully", "userID", userID)
+	return nil
+}
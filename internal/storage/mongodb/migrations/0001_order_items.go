@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Orders used to hold a single product in productId and quantity. Multi-item orders keep their
+// lines in items, with the price of every line captured when the order was placed.
+func init() {
+	register(Migration{
+		Version:     1,
+		Description: "move the product of single-product orders into items",
+		Up:          orderItemsUp,
+		Down:        orderItemsDown,
+	})
+}
+
+func orderItemsUp(ctx context.Context, db *mongo.Database) error {
+	// The total of an old order is the only price on record, it becomes the line total
+	_, err := db.Collection("Orders").UpdateMany(ctx,
+		bson.M{"items": bson.M{"$exists": false}, "productId": bson.M{"$exists": true}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"items": bson.A{bson.M{
+				"productId": "$productId",
+				"quantity":  "$quantity",
+				"unitPrice": bson.M{"$cond": bson.A{
+					bson.M{"$gt": bson.A{"$quantity", 0}},
+					bson.M{"$divide": bson.A{"$total", "$quantity"}},
+					0,
+				}},
+				"lineTotal": "$total",
+			}}}}},
+			{{Key: "$unset", Value: bson.A{"productId", "quantity"}}},
+		},
+		options.Update().SetBypassDocumentValidation(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to convert orders: %w", err)
+	}
+	return nil
+}
+
+func orderItemsDown(ctx context.Context, db *mongo.Database) error {
+	orders := db.Collection("Orders")
+
+	// Orders placed with several products have no single-product form, refuse before touching anything
+	count, err := orders.CountDocuments(ctx, bson.M{"items.1": bson.M{"$exists": true}})
+	if err != nil {
+		return fmt.Errorf("failed to count multi-item orders: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%d orders have more than one item and can't be converted back", count)
+	}
+
+	_, err = orders.UpdateMany(ctx,
+		bson.M{"items": bson.M{"$size": 1}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"productId": bson.M{"$arrayElemAt": bson.A{"$items.productId", 0}},
+				"quantity":  bson.M{"$arrayElemAt": bson.A{"$items.quantity", 0}},
+			}}},
+			{{Key: "$unset", Value: "items"}},
+		},
+		// The Orders validator requires items, the old shape doesn't have them
+		options.Update().SetBypassDocumentValidation(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to convert orders back: %w", err)
+	}
+	return nil
+}
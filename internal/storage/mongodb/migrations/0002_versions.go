@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Products and orders carry a version that every write increments, conditional updates compare it.
+// Documents written before start at version 1, like new ones do.
+func init() {
+	register(Migration{
+		Version:     2,
+		Description: "start products and orders at version 1",
+		Up:          versionsUp,
+		Down:        versionsDown,
+	})
+}
+
+var versioned = []string{"Products", "Orders"}
+
+func versionsUp(ctx context.Context, db *mongo.Database) error {
+	for _, collection := range versioned {
+		_, err := db.Collection(collection).UpdateMany(ctx,
+			bson.M{"version": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"version": int64(1)}},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to set the versions of %s: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+func versionsDown(ctx context.Context, db *mongo.Database) error {
+	for _, collection := range versioned {
+		_, err := db.Collection(collection).UpdateMany(ctx,
+			bson.M{"version": bson.M{"$exists": true}},
+			bson.M{"$unset": bson.M{"version": ""}},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to remove the versions of %s: %w", collection, err)
+		}
+	}
+	return nil
+}
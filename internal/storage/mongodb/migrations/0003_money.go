@@ -0,0 +1,114 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Amounts are stored as longs in minor units, the hundredths of the currency unit, so they add up exactly.
+// Products and orders name the currency of their amounts, the amounts written before were in USD.
+func init() {
+	register(Migration{
+		Version:     3,
+		Description: "store amounts in minor units with a currency",
+		Up:          moneyUp,
+		Down:        moneyDown,
+	})
+}
+
+// legacyCurrency is the currency of the amounts stored before products and orders had one
+const legacyCurrency = "USD"
+
+// toMinorUnits converts an amount in currency units, amounts that already are longs are left as they are
+func toMinorUnits(amount string) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$type": amount}, "long"}},
+		amount,
+		bson.M{"$toLong": bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{amount, 100}}, 0}}},
+	}}
+}
+
+// toUnits converts an amount in minor units back to currency units
+func toUnits(amount string) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$type": amount}, "long"}},
+		bson.M{"$divide": bson.A{amount, 100}},
+		amount,
+	}}
+}
+
+// convertItems applies convert to the amounts of every order line
+func convertItems(convert func(string) bson.M) bson.M {
+	return bson.M{"$map": bson.M{
+		"input": "$items",
+		"as":    "item",
+		"in": bson.M{"$mergeObjects": bson.A{"$$item", bson.M{
+			"unitPrice": convert("$$item.unitPrice"),
+			"lineTotal": convert("$$item.lineTotal"),
+		}}},
+	}}
+}
+
+func moneyUp(ctx context.Context, db *mongo.Database) error {
+	// The validators expect the new shape, the documents still in the old one don't pass them yet
+	opts := options.Update().SetBypassDocumentValidation(true)
+
+	_, err := db.Collection("Products").UpdateMany(ctx,
+		bson.M{"$or": bson.A{bson.M{"price": bson.M{"$not": bson.M{"$type": "long"}}}, bson.M{"currency": bson.M{"$exists": false}}}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"price":    toMinorUnits("$price"),
+			"currency": bson.M{"$ifNull": bson.A{"$currency", legacyCurrency}},
+		}}}},
+		opts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to convert product prices: %w", err)
+	}
+
+	_, err = db.Collection("Orders").UpdateMany(ctx,
+		bson.M{"$or": bson.A{bson.M{"total": bson.M{"$not": bson.M{"$type": "long"}}}, bson.M{"currency": bson.M{"$exists": false}}}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"total":    toMinorUnits("$total"),
+			"items":    convertItems(toMinorUnits),
+			"currency": bson.M{"$ifNull": bson.A{"$currency", legacyCurrency}},
+		}}}},
+		opts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to convert order totals: %w", err)
+	}
+	return nil
+}
+
+func moneyDown(ctx context.Context, db *mongo.Database) error {
+	opts := options.Update().SetBypassDocumentValidation(true)
+
+	_, err := db.Collection("Products").UpdateMany(ctx,
+		bson.M{"$or": bson.A{bson.M{"price": bson.M{"$type": "long"}}, bson.M{"currency": bson.M{"$exists": true}}}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"price": toUnits("$price")}}},
+			{{Key: "$unset", Value: "currency"}},
+		},
+		opts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to convert product prices back: %w", err)
+	}
+
+	_, err = db.Collection("Orders").UpdateMany(ctx,
+		bson.M{"$or": bson.A{bson.M{"total": bson.M{"$type": "long"}}, bson.M{"currency": bson.M{"$exists": true}}}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"total": toUnits("$total"), "items": convertItems(toUnits)}}},
+			{{Key: "$unset", Value: "currency"}},
+		},
+		opts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to convert order totals back: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Order lines record the currency their product was priced in and the exchange rate that converted the price
+// to the currency of the order. Lines written before were priced in the currency of their order.
+func init() {
+	register(Migration{
+		Version:     4,
+		Description: "record the price currency and exchange rate of order lines",
+		Up:          exchangeRatesUp,
+		Down:        exchangeRatesDown,
+	})
+}
+
+// unitRate is the exchange rate of 1 in the units of 10^-10 rates are stored in
+const unitRate = int64(10_000_000_000)
+
+func exchangeRatesUp(ctx context.Context, db *mongo.Database) error {
+	opts := options.Update().SetBypassDocumentValidation(true)
+
+	_, err := db.Collection("Orders").UpdateMany(ctx,
+		bson.M{"items": bson.M{"$elemMatch": bson.M{"$or": bson.A{
+			bson.M{"priceCurrency": bson.M{"$exists": false}},
+			bson.M{"exchangeRate": bson.M{"$exists": false}},
+		}}}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"items": bson.M{"$map": bson.M{
+			"input": "$items",
+			"as":    "item",
+			"in": bson.M{"$mergeObjects": bson.A{"$$item", bson.M{
+				"priceCurrency": bson.M{"$ifNull": bson.A{"$$item.priceCurrency", "$currency"}},
+				"exchangeRate":  bson.M{"$ifNull": bson.A{"$$item.exchangeRate", unitRate}},
+			}}},
+		}}}}}},
+		opts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record the exchange rates of order lines: %w", err)
+	}
+	return nil
+}
+
+func exchangeRatesDown(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("Orders").UpdateMany(ctx,
+		bson.M{"items": bson.M{"$elemMatch": bson.M{"$or": bson.A{
+			bson.M{"priceCurrency": bson.M{"$exists": true}},
+			bson.M{"exchangeRate": bson.M{"$exists": true}},
+		}}}},
+		bson.M{"$unset": bson.M{"items.$[].priceCurrency": "", "items.$[].exchangeRate": ""}},
+		options.Update().SetBypassDocumentValidation(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove the exchange rates of order lines: %w", err)
+	}
+	return nil
+}
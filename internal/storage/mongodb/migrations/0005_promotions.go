@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Orders keep the subtotal of their lines and the discounts applied to it, the total is what is left.
+// Orders placed before had no discounts, their subtotal is their total. The Promotions collections are
+// new and created by the schema setup, going down leaves them alone.
+func init() {
+	register(Migration{
+		Version:     5,
+		Description: "record the subtotal and discounts of orders",
+		Up:          promotionsUp,
+		Down:        promotionsDown,
+	})
+}
+
+func promotionsUp(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("Orders").UpdateMany(ctx,
+		bson.M{"subtotal": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"subtotal":  "$total",
+			"discounts": bson.A{},
+			"discount":  int64(0),
+		}}}},
+		options.Update().SetBypassDocumentValidation(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record the subtotals of orders: %w", err)
+	}
+	return nil
+}
+
+func promotionsDown(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("Orders").UpdateMany(ctx,
+		bson.M{"subtotal": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"subtotal": "", "discounts": "", "discount": ""}},
+		options.Update().SetBypassDocumentValidation(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove the subtotals of orders: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Products have a tax class, the products stored before get the standard one. Order lines keep the tax class
+// they were taxed in, orders keep the region and the mode they were taxed with, the tax by class and the net,
+// the total without the tax. Orders placed before weren't taxed, their net is their total.
+func init() {
+	register(Migration{
+		Version:     6,
+		Description: "record the tax class of products and the taxes of orders",
+		Up:          taxesUp,
+		Down:        taxesDown,
+	})
+}
+
+// standardTaxClass is the tax class of the products and order lines stored before tax classes existed
+const standardTaxClass = "standard"
+
+func taxesUp(ctx context.Context, db *mongo.Database) error {
+	opts := options.Update().SetBypassDocumentValidation(true)
+
+	_, err := db.Collection("Products").UpdateMany(ctx,
+		bson.M{"taxClass": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"taxClass": standardTaxClass}},
+		opts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record the tax class of products: %w", err)
+	}
+
+	_, err = db.Collection("Orders").UpdateMany(ctx,
+		bson.M{"net": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"items": bson.M{"$map": bson.M{
+				"input": "$items",
+				"as":    "item",
+				"in":    bson.M{"$mergeObjects": bson.A{"$$item", bson.M{"taxClass": standardTaxClass}}},
+			}},
+			"taxRegion": "",
+			"taxMode":   "exclusive",
+			"taxes":     bson.A{},
+			"net":       "$total",
+			"tax":       int64(0),
+		}}}},
+		opts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record the taxes of orders: %w", err)
+	}
+	return nil
+}
+
+func taxesDown(ctx context.Context, db *mongo.Database) error {
+	opts := options.Update().SetBypassDocumentValidation(true)
+
+	_, err := db.Collection("Products").UpdateMany(ctx,
+		bson.M{"taxClass": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"taxClass": ""}},
+		opts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove the tax class of products: %w", err)
+	}
+
+	_, err = db.Collection("Orders").UpdateMany(ctx,
+		bson.M{"net": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"items.$[].taxClass": "", "taxRegion": "", "taxMode": "", "taxes": "", "net": "", "tax": ""}},
+		opts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove the taxes of orders: %w", err)
+	}
+	return nil
+}
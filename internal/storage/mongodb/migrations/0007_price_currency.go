@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Prices are only compared within their currency, the price searches filter on the currency and sort by the price.
+// The index on the prices gives way to one led by the currency, which the schema builds on startup.
+func init() {
+	register(Migration{
+		Version:     7,
+		Description: "index the product prices by currency",
+		Up:          priceCurrencyUp,
+		Down:        priceCurrencyDown,
+	})
+}
+
+const (
+	priceIndex         = "price_1__id_1"
+	currencyPriceIndex = "currency_1_price_1__id_1"
+)
+
+// codeIndexNotFound is returned when dropping an index that doesn't exist
+const codeIndexNotFound = 27
+
+func priceCurrencyUp(ctx context.Context, db *mongo.Database) error {
+	if err := dropIndex(ctx, db.Collection("Products"), priceIndex); err != nil {
+		return fmt.Errorf("failed to drop the index on the product prices: %w", err)
+	}
+	return nil
+}
+
+func priceCurrencyDown(ctx context.Context, db *mongo.Database) error {
+	products := db.Collection("Products")
+	_, err := products.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "price", Value: 1}, {Key: "_id", Value: 1}}})
+	if err != nil {
+		return fmt.Errorf("failed to build the index on the product prices: %w", err)
+	}
+	if err := dropIndex(ctx, products, currencyPriceIndex); err != nil {
+		return fmt.Errorf("failed to drop the index on the product prices by currency: %w", err)
+	}
+	return nil
+}
+
+// dropIndex drops an index, an index that is already gone is left as it is
+func dropIndex(ctx context.Context, collection *mongo.Collection, name string) error {
+	_, err := collection.Indexes().DropOne(ctx, name)
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(codeIndexNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Product names are searched by prefix regardless of case, which only an index with the same collation serves.
+// The plain index on the names gives way to the case-insensitive one the schema builds on startup.
+func init() {
+	register(Migration{
+		Version:     8,
+		Description: "index the product names regardless of case",
+		Up:          nameCollationUp,
+		Down:        nameCollationDown,
+	})
+}
+
+const (
+	nameIndex            = "name_1"
+	caseInsensitiveIndex = "name_ci"
+)
+
+func nameCollationUp(ctx context.Context, db *mongo.Database) error {
+	if err := dropIndex(ctx, db.Collection("Products"), nameIndex); err != nil {
+		return fmt.Errorf("failed to drop the index on the product names: %w", err)
+	}
+	return nil
+}
+
+func nameCollationDown(ctx context.Context, db *mongo.Database) error {
+	products := db.Collection("Products")
+	if _, err := products.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "name", Value: 1}}}); err != nil {
+		return fmt.Errorf("failed to build the index on the product names: %w", err)
+	}
+	if err := dropIndex(ctx, products, caseInsensitiveIndex); err != nil {
+		return fmt.Errorf("failed to drop the case-insensitive index on the product names: %w", err)
+	}
+	return nil
+}
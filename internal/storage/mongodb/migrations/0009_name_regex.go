@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Product names are searched by a case-insensitive $regex again, which isn't collation-aware and can't use an index
+// with a collation. The case-insensitive index gives way to the plain one the schema builds on startup again.
+func init() {
+	register(Migration{
+		Version:     9,
+		Description: "index the product names for the regex search",
+		Up:          nameRegexUp,
+		Down:        nameRegexDown,
+	})
+}
+
+func nameRegexUp(ctx context.Context, db *mongo.Database) error {
+	if err := dropIndex(ctx, db.Collection("Products"), caseInsensitiveIndex); err != nil {
+		return fmt.Errorf("failed to drop the case-insensitive index on the product names: %w", err)
+	}
+	return nil
+}
+
+func nameRegexDown(ctx context.Context, db *mongo.Database) error {
+	products := db.Collection("Products")
+	_, err := products.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetName(caseInsensitiveIndex).SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build the case-insensitive index on the product names: %w", err)
+	}
+	if err := dropIndex(ctx, products, nameIndex); err != nil {
+		return fmt.Errorf("failed to drop the index on the product names: %w", err)
+	}
+	return nil
+}
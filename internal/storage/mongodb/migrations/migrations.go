@@ -0,0 +1,383 @@
+// Package migrations keeps the documents in MongoDB in step with the models.
+//
+// Every change to the shape of the documents is a Migration with an up step bringing existing documents
+// to the new shape and a down step taking them back. Applied versions are recorded in the Migrations
+// collection, and a lock document in the same collection keeps two runners from migrating at once.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collection holds a document per applied version and the lock document
+const collection = "Migrations"
+
+const (
+	lockID = "lock"
+
+	// lockTTL is how long a lock is honoured, a runner that died while migrating doesn't block the next one forever.
+	// The lock is renewed before every step.
+	lockTTL = 15 * time.Minute
+)
+
+// ErrLocked is returned when another runner holds the migration lock
+var ErrLocked = errors.New("migrations are locked by another runner")
+
+// Migration changes the shape of the stored documents.
+//
+// Both steps have to be idempotent: a runner that fails after a step but before recording it
+// runs the same step again next time. Steps select the documents still in the old shape rather
+// than assuming none have been converted yet.
+type Migration struct {
+	Version     int // Applied in ascending order, never reuse or renumber a released version
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	Down        func(ctx context.Context, db *mongo.Database) error
+}
+
+var registry = map[int]Migration{}
+
+// register adds a migration to the registry, every migration registers itself from an init function
+func register(m Migration) {
+	if m.Version <= 0 || m.Up == nil || m.Down == nil {
+		panic(fmt.Sprintf("migrations: migration %d needs a positive version and both steps", m.Version))
+	}
+	if _, ok := registry[m.Version]; ok {
+		panic(fmt.Sprintf("migrations: version %d is registered twice", m.Version))
+	}
+	registry[m.Version] = m
+}
+
+// All returns the registered migrations ordered by version
+func All() []Migration {
+	all := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// Status tells whether a migration has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// record is the document stored for an applied migration
+type record struct {
+	Version     int                `bson:"_id"`
+	Description string             `bson:"description"`
+	AppliedAt   primitive.DateTime `bson:"appliedAt"`
+}
+
+// Migrator applies and reverts the migrations of a database
+type Migrator struct {
+	db         *mongo.Database
+	logger     *slog.Logger
+	migrations []Migration
+	owner      string // Identifies this runner in the lock document
+}
+
+// New creates a migrator for the registered migrations
+func New(db *mongo.Database, logger *slog.Logger) *Migrator {
+	return newMigrator(db, logger, All())
+}
+
+func newMigrator(db *mongo.Database, logger *slog.Logger, migrations []Migration) *Migrator {
+	host, _ := os.Hostname()
+	return &Migrator{
+		db:         db,
+		logger:     logger,
+		migrations: migrations,
+		owner:      fmt.Sprintf("%s/%d/%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
+
+// Status lists every migration, oldest first, with whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i].Migration = migration
+		if r, ok := applied[migration.Version]; ok {
+			statuses[i].Applied, statuses[i].AppliedAt = true, r.AppliedAt.Time()
+		}
+	}
+	return statuses, nil
+}
+
+// Pending lists the migrations that haven't been applied yet, oldest first
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, s := range statuses {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies the pending migrations in ascending order and returns the ones it applied.
+// With dryRun nothing is changed, the returned migrations are the ones that would be applied.
+//
+// The pending migrations are read while holding the lock, and every step is checked again right before
+// it runs, so a runner that waited for another one doesn't apply the migrations that one already applied.
+func (m *Migrator) Up(ctx context.Context, dryRun bool) ([]Migration, error) {
+	if dryRun {
+		return m.Pending(ctx)
+	}
+
+	var applied []Migration
+	err := m.locked(ctx, func() error {
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range pending {
+			if err := m.renewLock(ctx); err != nil {
+				return err
+			}
+			done, err := m.isApplied(ctx, migration.Version)
+			if err != nil {
+				return err
+			}
+			if done {
+				continue
+			}
+
+			m.logger.Info("applying migration", "version", migration.Version, "description", migration.Description)
+			started := time.Now()
+			if err := migration.Up(ctx, m.db); err != nil {
+				m.logger.Error("failed to apply migration", "version", migration.Version, "error", err)
+				return fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
+			}
+
+			_, err = m.db.Collection(collection).ReplaceOne(ctx,
+				bson.M{"_id": migration.Version},
+				record{Version: migration.Version, Description: migration.Description, AppliedAt: primitive.NewDateTimeFromTime(time.Now())},
+				options.Replace().SetUpsert(true),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+			}
+			applied = append(applied, migration)
+			m.logger.Info("migration applied", "version", migration.Version, "took", time.Since(started))
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns the ones it reverted.
+// With dryRun nothing is changed, the returned migrations are the ones that would be reverted.
+// Like Up, it reads the applied migrations while holding the lock and checks every step again before it runs.
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool) ([]Migration, error) {
+	if dryRun {
+		return m.lastApplied(ctx, steps)
+	}
+
+	var reverted []Migration
+	err := m.locked(ctx, func() error {
+		reverting, err := m.lastApplied(ctx, steps)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range reverting {
+			if err := m.renewLock(ctx); err != nil {
+				return err
+			}
+			done, err := m.isApplied(ctx, migration.Version)
+			if err != nil {
+				return err
+			}
+			if !done {
+				continue
+			}
+
+			m.logger.Info("reverting migration", "version", migration.Version, "description", migration.Description)
+			started := time.Now()
+			if err := migration.Down(ctx, m.db); err != nil {
+				m.logger.Error("failed to revert migration", "version", migration.Version, "error", err)
+				return fmt.Errorf("failed to revert migration %d: %w", migration.Version, err)
+			}
+
+			if _, err := m.db.Collection(collection).DeleteOne(ctx, bson.M{"_id": migration.Version}); err != nil {
+				return fmt.Errorf("failed to record migration %d as reverted: %w", migration.Version, err)
+			}
+			reverted = append(reverted, migration)
+			m.logger.Info("migration reverted", "version", migration.Version, "took", time.Since(started))
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Baseline records every migration as applied on a database without any collection yet, there are no documents
+// in an old shape to migrate in it. It reports whether the database was fresh. The lock is only taken for a fresh
+// database, which is checked again while holding it.
+func (m *Migrator) Baseline(ctx context.Context) (bool, error) {
+	if fresh, err := m.isFresh(ctx); err != nil || !fresh {
+		return false, err
+	}
+
+	fresh := false
+	err := m.locked(ctx, func() error {
+		var err error
+		if fresh, err = m.isFresh(ctx); err != nil || !fresh {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			_, err := m.db.Collection(collection).ReplaceOne(ctx,
+				bson.M{"_id": migration.Version},
+				record{Version: migration.Version, Description: migration.Description, AppliedAt: primitive.NewDateTimeFromTime(time.Now())},
+				options.Replace().SetUpsert(true),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+			}
+		}
+		m.logger.Info("fresh database, recorded every migration as applied", "migrations", len(m.migrations))
+		return nil
+	})
+	return fresh, err
+}
+
+// isFresh reports whether the database holds no collection besides the migrations
+func (m *Migrator) isFresh(ctx context.Context) (bool, error) {
+	names, err := m.db.ListCollectionNames(ctx, bson.M{"name": bson.M{"$ne": collection}})
+	if err != nil {
+		return false, fmt.Errorf("failed to list collections: %w", err)
+	}
+	return len(names) == 0, nil
+}
+
+// lastApplied lists the last steps applied migrations, newest first
+func (m *Migrator) lastApplied(ctx context.Context, steps int) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var last []Migration
+	for i := len(statuses) - 1; i >= 0 && len(last) < steps; i-- {
+		if statuses[i].Applied {
+			last = append(last, statuses[i].Migration)
+		}
+	}
+	return last, nil
+}
+
+// isApplied reads whether a migration is recorded as applied
+func (m *Migrator) isApplied(ctx context.Context, version int) (bool, error) {
+	count, err := m.db.Collection(collection).CountDocuments(ctx, bson.M{"_id": version})
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration %d: %w", version, err)
+	}
+	return count > 0, nil
+}
+
+// applied reads the records of the applied migrations by version
+func (m *Migrator) applied(ctx context.Context) (map[int]record, error) {
+	cursor, err := m.db.Collection(collection).Find(ctx, bson.M{"_id": bson.M{"$type": "number"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	var records []record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode applied migrations: %w", err)
+	}
+
+	applied := make(map[int]record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// locked runs fn while holding the migration lock
+func (m *Migrator) locked(ctx context.Context, fn func() error) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		// Release the lock even when ctx was cancelled halfway
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := m.db.Collection(collection).DeleteOne(unlockCtx, bson.M{"_id": lockID, "owner": m.owner}); err != nil {
+			m.logger.Error("failed to release the migration lock", "error", err)
+		}
+	}()
+
+	return fn()
+}
+
+// lock takes the lock document, or takes it over once the previous holder let it expire
+func (m *Migrator) lock(ctx context.Context) error {
+	now := time.Now()
+	filter := bson.M{"_id": lockID, "expiresAt": bson.M{"$lt": primitive.NewDateTimeFromTime(now)}}
+	update := bson.M{"$set": bson.M{
+		"owner":     m.owner,
+		"lockedAt":  primitive.NewDateTimeFromTime(now),
+		"expiresAt": primitive.NewDateTimeFromTime(now.Add(lockTTL)),
+	}}
+
+	// The upsert inserts the lock when there is none and fails on the _id when a live lock exists
+	_, err := m.db.Collection(collection).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		var holder struct {
+			Owner     string             `bson:"owner"`
+			ExpiresAt primitive.DateTime `bson:"expiresAt"`
+		}
+		if err := m.db.Collection(collection).FindOne(ctx, bson.M{"_id": lockID}).Decode(&holder); err == nil {
+			return fmt.Errorf("%w: held by %s until %s", ErrLocked, holder.Owner, holder.ExpiresAt.Time().Format(time.RFC3339))
+		}
+		return ErrLocked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+
+	m.logger.Info("migration lock taken", "owner", m.owner)
+	return nil
+}
+
+// renewLock pushes the expiry of the lock forward, failing when another runner took it over in the meantime
+func (m *Migrator) renewLock(ctx context.Context) error {
+	result, err := m.db.Collection(collection).UpdateOne(ctx,
+		bson.M{"_id": lockID, "owner": m.owner},
+		bson.M{"$set": bson.M{"expiresAt": primitive.NewDateTimeFromTime(time.Now().Add(lockTTL))}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to renew the migration lock: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: the lock expired and was taken over", ErrLocked)
+	}
+	return nil
+}
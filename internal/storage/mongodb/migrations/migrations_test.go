@@ -0,0 +1,215 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestRegistryIsOrdered(t *testing.T) {
+	all := All()
+	if len(all) == 0 {
+		t.Fatal("no migrations registered")
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i-1].Version >= all[i].Version {
+			t.Fatalf("migration %d is listed before %d", all[i-1].Version, all[i].Version)
+		}
+	}
+}
+
+// testDatabase returns a fresh database on the server in TEST_MONGODB_URI, dropped when the test ends
+func testDatabase(t *testing.T) *mongo.Database {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	db := client.Database(fmt.Sprintf("migrations_%d", time.Now().UnixNano()))
+	t.Cleanup(func() { db.Drop(context.Background()) })
+	return db
+}
+
+// counting returns a migration whose steps count how often they ran
+func counting(version int, ups, downs *int) Migration {
+	return Migration{
+		Version:     version,
+		Description: fmt.Sprintf("migration %d", version),
+		Up:          func(context.Context, *mongo.Database) error { *ups++; return nil },
+		Down:        func(context.Context, *mongo.Database) error { *downs++; return nil },
+	}
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	var ups, downs int
+	m := newMigrator(db, testLogger, []Migration{counting(1, &ups, &downs), counting(2, &ups, &downs)})
+
+	planned, err := m.Up(ctx, true)
+	if err != nil || len(planned) != 2 || ups != 0 {
+		t.Fatalf("dry run planned %d migrations and ran %d, error %v", len(planned), ups, err)
+	}
+
+	if _, err := m.Up(ctx, false); err != nil {
+		t.Fatalf("up failed: %v", err)
+	}
+	if _, err := m.Up(ctx, false); err != nil {
+		t.Fatalf("second up failed: %v", err)
+	}
+	if ups != 2 {
+		t.Fatalf("up steps ran %d times, expected 2", ups)
+	}
+
+	reverted, err := m.Down(ctx, 1, false)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 2 || downs != 1 {
+		t.Fatalf("down reverted %v with %d steps, error %v", reverted, downs, err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Fatalf("expected only migration 1 to be applied, got %+v", statuses)
+	}
+}
+
+func TestMigratorRechecksEveryStep(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	var ups, downs int
+	second := counting(2, &ups, &downs)
+	first := Migration{
+		Version:     1,
+		Description: "migration 1",
+		// Another runner records the second migration while the first one runs
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(collection).InsertOne(ctx, record{Version: 2, Description: second.Description, AppliedAt: primitive.NewDateTimeFromTime(time.Now())})
+			return err
+		},
+		Down: func(context.Context, *mongo.Database) error { return nil },
+	}
+
+	m := newMigrator(db, testLogger, []Migration{first, second})
+	applied, err := m.Up(ctx, false)
+	if err != nil || len(applied) != 1 || applied[0].Version != 1 || ups != 0 {
+		t.Fatalf("expected only migration 1 to be applied, applied %v and ran %d other steps, error %v", applied, ups, err)
+	}
+}
+
+func TestMigratorBaseline(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	var ups, downs int
+	m := newMigrator(db, testLogger, []Migration{counting(1, &ups, &downs)})
+
+	// A fresh database has nothing to migrate
+	if fresh, err := m.Baseline(ctx); err != nil || !fresh {
+		t.Fatalf("expected a fresh database, got %v, error %v", fresh, err)
+	}
+	if pending, err := m.Pending(ctx); err != nil || len(pending) != 0 || ups != 0 {
+		t.Fatalf("expected no pending migrations, got %v and %d steps, error %v", pending, ups, err)
+	}
+
+	// A database holding documents is left to the migrations
+	other := testDatabase(t)
+	if _, err := other.Collection("Orders").InsertOne(ctx, bson.M{"total": 10.0}); err != nil {
+		t.Fatalf("failed to insert order: %v", err)
+	}
+	m = newMigrator(other, testLogger, []Migration{counting(1, &ups, &downs)})
+	if fresh, err := m.Baseline(ctx); err != nil || fresh {
+		t.Fatalf("expected a database in use, got %v, error %v", fresh, err)
+	}
+	if pending, err := m.Pending(ctx); err != nil || len(pending) != 1 {
+		t.Fatalf("expected the migration to be pending, got %v, error %v", pending, err)
+	}
+}
+
+func TestMigratorLock(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	var ups, downs int
+	holder := newMigrator(db, testLogger, nil)
+	other := newMigrator(db, testLogger, []Migration{counting(1, &ups, &downs)})
+
+	if err := holder.lock(ctx); err != nil {
+		t.Fatalf("failed to take the lock: %v", err)
+	}
+	if _, err := other.Up(ctx, false); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked while the lock is held, got %v", err)
+	}
+
+	// An expired lock is taken over
+	_, err := db.Collection(collection).UpdateOne(ctx, bson.M{"_id": lockID}, bson.M{"$set": bson.M{"expiresAt": time.Now().Add(-time.Minute)}})
+	if err != nil {
+		t.Fatalf("failed to expire the lock: %v", err)
+	}
+	if _, err := other.Up(ctx, false); err != nil || ups != 1 {
+		t.Fatalf("expected the expired lock to be taken over, ran %d steps, error %v", ups, err)
+	}
+}
+
+func TestOrderItems(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	orders := db.Collection("Orders")
+
+	legacy := bson.M{"productId": "p1", "quantity": 4, "status": "pending", "total": 10.0}
+	if _, err := orders.InsertOne(ctx, legacy); err != nil {
+		t.Fatalf("failed to insert order: %v", err)
+	}
+
+	// Running a step twice must be harmless
+	for range 2 {
+		if err := orderItemsUp(ctx, db); err != nil {
+			t.Fatalf("up failed: %v", err)
+		}
+	}
+
+	var order bson.M
+	if err := orders.FindOne(ctx, bson.M{}).Decode(&order); err != nil {
+		t.Fatalf("failed to read order: %v", err)
+	}
+	items, _ := order["items"].(bson.A)
+	if len(items) != 1 || order["productId"] != nil {
+		t.Fatalf("expected a single item and no productId, got %v", order)
+	}
+	if item := items[0].(bson.M); item["unitPrice"] != 2.5 || item["lineTotal"] != 10.0 {
+		t.Fatalf("expected the total to be split into the line, got %v", item)
+	}
+
+	for range 2 {
+		if err := orderItemsDown(ctx, db); err != nil {
+			t.Fatalf("down failed: %v", err)
+		}
+	}
+	if err := orders.FindOne(ctx, bson.M{"productId": "p1", "quantity": 4, "items": bson.M{"$exists": false}}).Err(); err != nil {
+		t.Fatalf("expected the order to be converted back: %v", err)
+	}
+}
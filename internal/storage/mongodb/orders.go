@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
 	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -29,25 +31,41 @@ func NewOrderStorage(db *mongo.Database, logger *slog.Logger, cfg *config.Config
 	}
 }
 
-// CreateOrder creates a new order in the database
-func (o *OrderStorage) CreateOrder(ctx context.Context, total float64, order *models.OrderCreate) (string, error) {
-	o.logger.Info("starting order creation", "ProductID", order.ProductID)
+// CreateOrder stores a priced order, every order starts its lifecycle as pending
+func (o *OrderStorage) CreateOrder(ctx context.Context, order *models.Order) (string, error) {
+	o.logger.Info("starting order creation", "userID", order.UserID, "items", len(order.Items))
 
-	created_at := time.Now()
+	created_at := primitive.NewDateTimeFromTime(time.Now())
 
 	// Insert the order into MongoDB
 	var newOrder = models.Order{
-		UserID:    order.UserID,
-		ProductID: order.ProductID,
-		Quantity:  order.Quantity,
-		Status:    order.Status,
-		Total:     total,
-		CreatedAt: primitive.NewDateTimeFromTime(created_at),
-		UpdatedAt: primitive.NewDateTimeFromTime(created_at),
+		UserID: order.UserID,
+		Items:  order.Items,
+		Status: models.OrderStatusPending,
+		StatusHistory: []models.StatusChange{
+			{To: models.OrderStatusPending, At: created_at},
+		},
+		Subtotal:  order.Subtotal,
+		Discounts: nonNil(order.Discounts),
+		Discount:  order.Discount,
+		TaxRegion: order.TaxRegion,
+		TaxMode:   order.TaxMode,
+		Taxes:     nonNil(order.Taxes),
+		Net:       order.Net,
+		Tax:       order.Tax,
+		Total:     order.Total,
+		Currency:  order.Currency,
+		Version:   1,
+		CreatedAt: created_at,
+		UpdatedAt: created_at,
 	}
 
 	result, err := o.db.InsertOne(ctx, newOrder)
 	if err != nil {
+		if hasErrorCode(err, codeDocumentValidationFailure) {
+			o.logger.Warn("order rejected by the collection validator", "error", err)
+			return "", errs.Validation("order doesn't match the order schema")
+		}
 		o.logger.Error("failed to insert order", "error", err)
 		return "", fmt.Errorf("failed to insert order: %w", err)
 	}
@@ -71,7 +89,7 @@ func (o *OrderStorage) GetOrderByID(ctx context.Context, orderID string) (*model
 	objectID, err := primitive.ObjectIDFromHex(orderID)
 	if err != nil {
 		o.logger.Error("invalid order ID format", "error", err)
-		return nil, fmt.Errorf("invalid order ID format: %w", err)
+		return nil, errs.InvalidID("invalid order ID format", err)
 	}
 
 	// Find the order in MongoDB
@@ -80,7 +98,7 @@ func (o *OrderStorage) GetOrderByID(ctx context.Context, orderID string) (*model
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			o.logger.Warn("order not found", "orderID", orderID)
-			return nil, errors.New("order not found")
+			return nil, errs.NotFound("order not found")
 		}
 		o.logger.Error("failed to fetch order from database", "error", err)
 		return nil, fmt.Errorf("failed to fetch order: %w", err)
@@ -90,142 +108,275 @@ func (o *OrderStorage) GetOrderByID(ctx context.Context, orderID string) (*model
 	return &order, nil
 }
 
-// UpdateOrder updates an order in the database
-func (o *OrderStorage) UpdateOrder(ctx context.Context, total float64, orderID string, updates *models.OrderUpdate) (string, error) {
-	o.logger.Info("updating order", "orderID", orderID)
+// UpdateOrder updates an order in the database, as long as it is still at the expected version
+func (o *OrderStorage) UpdateOrder(ctx context.Context, orderID string, version int64, updates *models.UpdatedOrder) error {
+	o.logger.Info("updating order", "orderID", orderID, "version", version)
 
 	// Convert string ID to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(orderID)
 	if err != nil {
 		o.logger.Error("invalid order ID format", "error", err)
-		return "", fmt.Errorf("invalid order ID format: %w", err)
+		return errs.InvalidID("invalid order ID format", err)
 	}
 
 	var newOrder = models.UpdatedOrder{
-		UserID:    updates.UserID,
-		ProductID: updates.ProductID,
-		Quantity:  updates.Quantity,
-		Status:    updates.Status,
-		Total:     total,
+		Items:     updates.Items,
+		Subtotal:  updates.Subtotal,
+		Discounts: nonNil(updates.Discounts),
+		Discount:  updates.Discount,
+		Taxes:     nonNil(updates.Taxes),
+		Net:       updates.Net,
+		Tax:       updates.Tax,
+		Total:     updates.Total,
 		UpdatedAt: primitive.NewDateTimeFromTime(time.Now())}
 
 	// Update the order in MongoDB
-	result, err := o.db.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": newOrder})
+	result, err := o.db.UpdateOne(ctx, versioned(objectID, version), bson.M{"$set": newOrder, "$inc": bumpVersion})
 	if err != nil {
+		if hasErrorCode(err, codeDocumentValidationFailure) {
+			o.logger.Warn("order rejected by the collection validator", "error", err)
+			return errs.Validation("order doesn't match the order schema")
+		}
 		o.logger.Error("failed to update order", "error", err)
-		return "", fmt.Errorf("failed to update order: %w", err)
+		return fmt.Errorf("failed to update order: %w", err)
 	}
 
 	if result.MatchedCount == 0 {
-		o.logger.Warn("no order found to update", "orderID", orderID)
-		return "", errors.New("no order found to update")
+		return o.missingOrStale(ctx, objectID, orderID, version)
 	}
 
-	o.logger.Info("order updated successfully", "orderID", orderID, "updatedFields", updates)
-	return "", nil
+	o.logger.Info("order updated successfully", "orderID", orderID, "items", len(updates.Items))
+	return nil
 }
 
-// DeleteOrder deletes an order by its ID
-func (o *OrderStorage) DeleteOrder(ctx context.Context, orderID string) error {
-	o.logger.Info("deleting order", "orderID", orderID)
+// DeleteOrder deletes an order by its ID, as long as it is still at the expected version
+func (o *OrderStorage) DeleteOrder(ctx context.Context, orderID string, version int64) error {
+	o.logger.Info("deleting order", "orderID", orderID, "version", version)
 
 	// Convert string ID to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(orderID)
 	if err != nil {
 		o.logger.Error("invalid order ID format", "error", err)
-		return fmt.Errorf("invalid order ID format: %w", err)
+		return errs.InvalidID("invalid order ID format", err)
 	}
 
 	// Delete the order in MongoDB
-	result, err := o.db.DeleteOne(ctx, bson.M{"_id": objectID})
+	result, err := o.db.DeleteOne(ctx, versioned(objectID, version))
 	if err != nil {
 		o.logger.Error("failed to delete order", "error", err)
 		return fmt.Errorf("failed to delete order: %w", err)
 	}
 
 	if result.DeletedCount == 0 {
-		o.logger.Warn("no order found to delete", "orderID", orderID)
-		return errors.New("no order found to delete")
+		return o.missingOrStale(ctx, objectID, orderID, version)
 	}
 
 	o.logger.Info("order deleted successfully", "orderID", orderID)
 	return nil
 }
 
-// ListOrders fetches all orders from the database with pagination
-func (o *OrderStorage) ListOrders(ctx context.Context, pagination *models.Pagination) ([]models.Order, error) {
-	o.logger.Info("fetching all orders with pagination")
+// missingOrStale tells why a conditional write matched no order: it is gone or its version moved on
+func (o *OrderStorage) missingOrStale(ctx context.Context, objectID primitive.ObjectID, orderID string, version int64) error {
+	count, err := o.db.CountDocuments(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		o.logger.Error("failed to check order existence", "error", err)
+		return fmt.Errorf("failed to check order existence: %w", err)
+	}
+	if count == 0 {
+		o.logger.Warn("order not found", "orderID", orderID)
+		return errs.NotFound("order not found")
+	}
 
-	// Find all orders in MongoDB
-	skip := (pagination.Page - 1) * pagination.PageSize
-	options := options.Find().
-		SetSkip(int64(skip)).
-		SetLimit(int64(pagination.PageSize))
+	o.logger.Warn("order version mismatch", "orderID", orderID, "expected", version)
+	return errs.PreconditionFailed("order was changed since it was read")
+}
 
-	cursor, err := o.db.Find(ctx, bson.M{}, options)
+// TransitionOrderStatus moves an order from one status to another and records the change in its history.
+// The update only applies while the order is still in the from status, so concurrent transitions can't both win.
+func (o *OrderStorage) TransitionOrderStatus(ctx context.Context, orderID, from, to string) (*models.Order, error) {
+	o.logger.Info("transitioning order status", "orderID", orderID, "from", from, "to", to)
+
+	// Convert string ID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(orderID)
 	if err != nil {
-		o.logger.Error("failed to fetch orders from database", "error", err)
-		return nil, fmt.Errorf("failed to fetch orders: %w", err)
+		o.logger.Error("invalid order ID format", "error", err)
+		return nil, errs.InvalidID("invalid order ID format", err)
 	}
-	defer cursor.Close(ctx)
 
-	// Iterate through the cursor and decode each order
-	var orders []models.Order
-	for cursor.Next(ctx) {
-		var order models.Order
-		if err := cursor.Decode(&order); err != nil {
-			o.logger.Error("failed to decode order", "error", err)
-			return nil, fmt.Errorf("failed to decode order: %w", err)
+	now := primitive.NewDateTimeFromTime(time.Now())
+	filter := bson.M{"_id": objectID, "status": from}
+	update := bson.M{
+		"$set":  bson.M{"status": to, "updatedAt": now},
+		"$inc":  bumpVersion,
+		"$push": bson.M{"statusHistory": models.StatusChange{From: from, To: to, At: now}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var order models.Order
+	err = o.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(&order)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			o.logger.Error("failed to transition order status", "error", err)
+			return nil, fmt.Errorf("failed to transition order status: %w", err)
+		}
+
+		// Tell a missing order apart from one whose status moved on in the meantime
+		count, err := o.db.CountDocuments(ctx, bson.M{"_id": objectID})
+		if err != nil {
+			o.logger.Error("failed to check order existence", "error", err)
+			return nil, fmt.Errorf("failed to check order existence: %w", err)
 		}
-		orders = append(orders, order)
+		if count == 0 {
+			o.logger.Warn("order not found", "orderID", orderID)
+			return nil, errs.NotFound("order not found")
+		}
+
+		o.logger.Warn("order status changed concurrently", "orderID", orderID, "expected", from)
+		return nil, errs.Conflict("order status was changed concurrently, try again")
 	}
 
-	// Check for errors that occurred during iteration
-	if err := cursor.Err(); err != nil {
-		o.logger.Error("cursor error", "error", err)
-		return nil, fmt.Errorf("cursor error: %w", err)
+	o.logger.Info("order status transitioned successfully", "orderID", orderID, "status", to)
+	return &order, nil
+}
+
+// ListOrders fetches all orders from the database with pagination, newest first
+func (o *OrderStorage) ListOrders(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Order], error) {
+	o.logger.Info("fetching all orders with pagination")
+
+	page, err := findPage(ctx, o.db, bson.M{}, cursor.NewestFirst, pagination, orderKey)
+	if err != nil {
+		o.logger.Error("failed to fetch orders from database", "error", err)
+		return nil, err
 	}
 
-	o.logger.Info("successfully fetched orders with pagination", "orderCount", len(orders))
-	return orders, nil
+	o.logger.Info("successfully fetched orders with pagination", "orderCount", len(page.Items))
+	return page, nil
 }
 
-// ListOrdersByDateRange - Get orders by date range (from startDate to endDate) with pagination
-func (s *OrderStorage) ListOrdersByDateRange(ctx context.Context, order int8, pagination *models.Pagination, startDate, endDate time.Time) ([]models.Order, error) {
-	var orders []models.Order
+// ListOrdersByUser fetches the orders placed by a single user with pagination, newest first
+func (o *OrderStorage) ListOrdersByUser(ctx context.Context, userID string, pagination *models.Pagination) (*models.Page[models.Order], error) {
+	o.logger.Info("fetching orders of user", "userID", userID)
+
+	// Convert string ID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		o.logger.Error("invalid user ID format", "error", err)
+		return nil, errs.InvalidID("invalid user ID format", err)
+	}
+
+	page, err := findPage(ctx, o.db, bson.M{"userId": objectID}, cursor.NewestFirst, pagination, orderKey)
+	if err != nil {
+		o.logger.Error("failed to fetch orders from database", "error", err)
+		return nil, err
+	}
+
+	o.logger.Info("successfully fetched orders of user", "userID", userID, "orderCount", len(page.Items))
+	return page, nil
+}
+
+// ListOrdersWithUsers fetches orders together with the name of the user who placed them
+func (o *OrderStorage) ListOrdersWithUsers(ctx context.Context, pagination *models.Pagination) (*models.Page[models.OrderWithUser], error) {
+	o.logger.Info("fetching orders with users", "page", pagination.Page, "pageSize", pagination.PageSize)
 
-	// Calculate skip and limit for pagination
 	skip := (pagination.Page - 1) * pagination.PageSize
-	limit := pagination.PageSize
 
-	// Aggregation pipeline to filter orders by date range and apply pagination
+	// Count all orders and fetch the requested page in one round trip,
+	// paginating first so the $lookup only runs for the orders on the page
 	pipeline := mongo.Pipeline{
-		bson.D{{Key: "$match", Value: bson.D{
-			{Key: "createdAt", Value: bson.D{
-				{Key: "$gte", Value: startDate}, // greater than or equal to startDate
-				{Key: "$lte", Value: endDate},   // less than or equal to endDate
+		bson.D{{Key: "$facet", Value: bson.D{
+			{Key: "total", Value: bson.A{
+				bson.D{{Key: "$count", Value: "count"}},
+			}},
+			{Key: "items", Value: bson.A{
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}}},
+				bson.D{{Key: "$skip", Value: skip}},
+				bson.D{{Key: "$limit", Value: pagination.PageSize}},
+				bson.D{{Key: "$lookup", Value: bson.D{
+					{Key: "from", Value: "Users"},
+					{Key: "localField", Value: "userId"},
+					{Key: "foreignField", Value: "_id"},
+					{Key: "as", Value: "user"},
+				}}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "user_id", Value: "$userId"},
+					{Key: "user_name", Value: bson.D{{Key: "$ifNull", Value: bson.A{
+						bson.D{{Key: "$arrayElemAt", Value: bson.A{"$user.name", 0}}},
+						"",
+					}}}},
+					{Key: "items", Value: 1},
+					{Key: "status", Value: 1},
+					{Key: "total", Value: 1},
+					{Key: "currency", Value: 1},
+					{Key: "created_at", Value: "$createdAt"},
+				}}},
 			}},
 		}}},
-		bson.D{{Key: "$sort", Value: bson.D{
-			{Key: "createdAt", Value: int(order)}, // Sort by createdAt in ascending or descending order
-		}}},
-		bson.D{{Key: "$skip", Value: skip}},   // Skip the number of records for pagination
-		bson.D{{Key: "$limit", Value: limit}}, // Limit the number of records per page
 	}
 
-	cursor, err := s.db.Aggregate(ctx, pipeline)
+	cursor, err := o.db.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		o.logger.Error("failed to aggregate orders with users", "error", err)
+		return nil, fmt.Errorf("failed to fetch orders with users: %w", err)
 	}
-	defer cursor.Close(ctx) // Close the cursor once done
+	defer cursor.Close(ctx)
 
-	if err := cursor.All(ctx, &orders); err != nil {
+	var result []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Items []models.OrderWithUser `bson:"items"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		o.logger.Error("failed to decode orders with users", "error", err)
+		return nil, fmt.Errorf("failed to decode orders with users: %w", err)
+	}
+
+	page := &models.Page[models.OrderWithUser]{
+		Items:    []models.OrderWithUser{},
+		Page:     pagination.Page,
+		PageSize: pagination.PageSize,
+	}
+	// $facet always yields one document, its count is missing when there are no orders
+	if len(result) > 0 {
+		if len(result[0].Total) > 0 {
+			page.Total = result[0].Total[0].Count
+		}
+		if result[0].Items != nil {
+			page.Items = result[0].Items
+		}
+	}
+	page.HasNext = int64(skip+len(page.Items)) < page.Total
+
+	o.logger.Info("successfully fetched orders with users", "orderCount", len(page.Items), "total", page.Total)
+	return page, nil
+}
+
+// ListOrdersByDateRange - Get orders by date range (from startDate to endDate) with pagination
+func (s *OrderStorage) ListOrdersByDateRange(ctx context.Context, order int8, pagination *models.Pagination, startDate, endDate time.Time) (*models.Page[models.Order], error) {
+	filter := bson.M{
+		"createdAt": bson.M{
+			"$gte": startDate, // greater than or equal to startDate
+			"$lte": endDate,   // less than or equal to endDate
+		},
+	}
+
+	// Sort by createdAt in ascending or descending order
+	page, err := findPage(ctx, s.db, filter, cursor.OldestFirst.WithDirection(order), pagination, orderKey)
+	if err != nil {
 		return nil, err
 	}
 
-	if len(orders) == 0 {
-		return nil, errors.New("no orders found in the given date range")
+	if len(page.Items) == 0 {
+		return nil, errs.NotFound("no orders found in the given date range")
 	}
 
-	return orders, nil
+	return page, nil
+}
+
+// nonNil returns an empty slice for a nil one, nil slices are stored as null and the validator expects an array
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
 }
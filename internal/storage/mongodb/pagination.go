@@ -0,0 +1,88 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// keyValue returns the sort key stored in a cursor in the type the documents store it in
+func keyValue(key string, c *cursor.Cursor) any {
+	if key == cursor.KeyPrice {
+		return c.Price
+	}
+	return primitive.NewDateTimeFromTime(c.CreatedAt)
+}
+
+// findPage fetches one page of the documents matching filter in the given order, together with their total count.
+// With a cursor the page continues right after (or before) the document the cursor was issued for,
+// otherwise page and page size select the page by offset like they always did.
+// One extra document is fetched to tell whether there is anything beyond the page.
+func findPage[T any](ctx context.Context, coll *mongo.Collection, filter bson.M, order cursor.Order, pagination *models.Pagination, keyOf func(*T) cursor.Cursor) (*models.Page[T], error) {
+	from, direction, err := order.Resume(pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	opts := options.Find().SetLimit(int64(pagination.PageSize + 1))
+	if from != nil {
+		id, err := primitive.ObjectIDFromHex(from.ID)
+		if err != nil {
+			return nil, cursor.ErrInvalid
+		}
+
+		op := "$gt"
+		if direction < 0 {
+			op = "$lt"
+		}
+		value := keyValue(order.Key, from)
+		filter = bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{order.Key: bson.M{op: value}},
+			bson.M{order.Key: value, "_id": bson.M{op: id}},
+		}}}}
+	} else {
+		opts.SetSkip(int64((pagination.Page - 1) * pagination.PageSize))
+	}
+	opts.SetSort(bson.D{{Key: order.Key, Value: direction}, {Key: "_id", Value: direction}})
+
+	cur, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	items := []T{}
+	if err := cur.All(ctx, &items); err != nil {
+		return nil, fmt.Errorf("failed to decode page: %w", err)
+	}
+
+	return cursor.NewPage(items, total, from, order, pagination, keyOf), nil
+}
+
+// productKey, orderKey, userKey and promotionKey position a product, an order, a user or a promotion within a listing
+func productKey(p *models.Product) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: p.CreatedAt.Time(), Price: p.Price, ID: p.ID.Hex()}
+}
+
+func orderKey(o *models.Order) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: o.CreatedAt.Time(), Price: o.Total, ID: o.ID.Hex()}
+}
+
+func userKey(u *models.User) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: u.CreatedAt.Time(), ID: u.ID.Hex()}
+}
+
+func promotionKey(p *models.Promotion) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: p.CreatedAt.Time(), ID: p.ID.Hex()}
+}
@@ -8,12 +8,13 @@ import (
 	"time"
 
 	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
 	"github.com/abdulazizax/udevslab-lesson3/internal/models"
 	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ProductStorage struct {
@@ -41,12 +42,19 @@ func (p *ProductStorage) CreateProduct(ctx context.Context, product *models.Prod
 		Name:        product.Name,
 		Description: product.Description,
 		Price:       product.Price,
+		Currency:    product.Currency,
+		TaxClass:    product.TaxClass,
 		Stock:       product.Stock,
+		Version:     1,
 		CreatedAt:   primitive.NewDateTimeFromTime(created_at),
 		UpdatedAt:   primitive.NewDateTimeFromTime(created_at),
 	}
 	result, err := p.db.InsertOne(ctx, newProduct)
 	if err != nil {
+		if hasErrorCode(err, codeDocumentValidationFailure) {
+			p.logger.Warn("product rejected by the collection validator", "error", err)
+			return "", errs.Validation("product doesn't match the product schema")
+		}
 		p.logger.Error("failed to insert product", "error", err)
 		return "", fmt.Errorf("failed to insert product: %w", err)
 	}
@@ -70,7 +78,7 @@ func (p *ProductStorage) GetProductByID(ctx context.Context, productID string) (
 	objectID, err := primitive.ObjectIDFromHex(productID)
 	if err != nil {
 		p.logger.Error("invalid product ID format", "error", err)
-		return nil, fmt.Errorf("invalid product ID format: %w", err)
+		return nil, errs.InvalidID("invalid product ID format", err)
 	}
 
 	// Find the product in MongoDB
@@ -79,7 +87,7 @@ func (p *ProductStorage) GetProductByID(ctx context.Context, productID string) (
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			p.logger.Warn("product not found", "productID", productID)
-			return nil, errors.New("product not found")
+			return nil, errs.NotFound("product not found")
 		}
 		p.logger.Error("failed to fetch product from database", "error", err)
 		return nil, fmt.Errorf("failed to fetch product: %w", err)
@@ -89,115 +97,233 @@ func (p *ProductStorage) GetProductByID(ctx context.Context, productID string) (
 	return &product, nil
 }
 
-// UpdateProduct updates a product in the database
-func (p *ProductStorage) UpdateProduct(ctx context.Context, productID string, updates *models.ProductUpdate) error {
-	p.logger.Info("updating product", "productID", productID)
+// UpdateProduct updates a product in the database, as long as it is still at the expected version
+func (p *ProductStorage) UpdateProduct(ctx context.Context, productID string, version int64, updates *models.ProductUpdate) error {
+	p.logger.Info("updating product", "productID", productID, "version", version)
 
 	// Convert string ID to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(productID)
 	if err != nil {
 		p.logger.Error("invalid product ID format", "error", err)
-		return fmt.Errorf("invalid product ID format: %w", err)
+		return errs.InvalidID("invalid product ID format", err)
 	}
 
 	var newProduct = models.UpdatedProduct{
 		Name:        updates.Name,
 		Description: updates.Description,
 		Price:       updates.Price,
+		Currency:    updates.Currency,
+		TaxClass:    updates.TaxClass,
 		Stock:       updates.Stock,
 		UpdatedAt:   primitive.NewDateTimeFromTime(time.Now()),
 	}
 
 	// Update the product in MongoDB
-	result, err := p.db.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": newProduct})
+	result, err := p.db.UpdateOne(ctx, versioned(objectID, version), bson.M{"$set": newProduct, "$inc": bumpVersion})
 	if err != nil {
+		if hasErrorCode(err, codeDocumentValidationFailure) {
+			p.logger.Warn("product rejected by the collection validator", "error", err)
+			return errs.Validation("product doesn't match the product schema")
+		}
 		p.logger.Error("failed to update product", "error", err)
 		return fmt.Errorf("failed to update product: %w", err)
 	}
 
 	if result.MatchedCount == 0 {
-		p.logger.Warn("no product found to update", "productID", productID)
-		return errors.New("no product found to update")
+		return p.missingOrStale(ctx, objectID, productID, version)
 	}
 
 	p.logger.Info("product updated successfully", "productID", productID, "updatedFields", updates)
 	return nil
 }
 
-// DeleteProduct deletes a product by its ID
-func (p *ProductStorage) DeleteProduct(ctx context.Context, productID string) error {
-	p.logger.Info("deleting product", "productID", productID)
+// PatchProduct sets the fields present in the patch, as long as the product is still at the expected version
+func (p *ProductStorage) PatchProduct(ctx context.Context, productID string, version int64, patch *models.ProductPatch) error {
+	p.logger.Info("patching product", "productID", productID, "version", version)
+
+	// Convert string ID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(productID)
+	if err != nil {
+		p.logger.Error("invalid product ID format", "error", err)
+		return errs.InvalidID("invalid product ID format", err)
+	}
+
+	set := bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())}
+	if patch.Name != nil {
+		set["name"] = *patch.Name
+	}
+	if patch.Description != nil {
+		set["description"] = *patch.Description
+	}
+	if patch.Price != nil {
+		set["price"] = *patch.Price
+	}
+	if patch.Currency != nil {
+		set["currency"] = *patch.Currency
+	}
+	if patch.TaxClass != nil {
+		set["taxClass"] = *patch.TaxClass
+	}
+	if patch.Stock != nil {
+		set["stock"] = *patch.Stock
+	}
+
+	// Update only the patched fields in MongoDB
+	result, err := p.db.UpdateOne(ctx, versioned(objectID, version), bson.M{"$set": set, "$inc": bumpVersion})
+	if err != nil {
+		if hasErrorCode(err, codeDocumentValidationFailure) {
+			p.logger.Warn("product rejected by the collection validator", "error", err)
+			return errs.Validation("product doesn't match the product schema")
+		}
+		p.logger.Error("failed to patch product", "error", err)
+		return fmt.Errorf("failed to patch product: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return p.missingOrStale(ctx, objectID, productID, version)
+	}
+
+	p.logger.Info("product patched successfully", "productID", productID, "fields", len(set)-1)
+	return nil
+}
+
+// DeleteProduct deletes a product by its ID, as long as it is still at the expected version
+func (p *ProductStorage) DeleteProduct(ctx context.Context, productID string, version int64) error {
+	p.logger.Info("deleting product", "productID", productID, "version", version)
 
 	// Convert string ID to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(productID)
 	if err != nil {
 		p.logger.Error("invalid product ID format", "error", err)
-		return fmt.Errorf("invalid product ID format: %w", err)
+		return errs.InvalidID("invalid product ID format", err)
 	}
 
 	// Delete the product in MongoDB
-	result, err := p.db.DeleteOne(ctx, bson.M{"_id": objectID})
+	result, err := p.db.DeleteOne(ctx, versioned(objectID, version))
 	if err != nil {
 		p.logger.Error("failed to delete product", "error", err)
 		return fmt.Errorf("failed to delete product: %w", err)
 	}
 
 	if result.DeletedCount == 0 {
-		p.logger.Warn("no product found to delete", "productID", productID)
-		return errors.New("no product found to delete")
+		return p.missingOrStale(ctx, objectID, productID, version)
 	}
 
 	p.logger.Info("product deleted successfully", "productID", productID)
 	return nil
 }
 
-// ListProducts fetches a paginated list of products from the database
-func (p *ProductStorage) ListProducts(ctx context.Context, pagination *models.Pagination) ([]models.Product, error) {
-	p.logger.Info("fetching list of products", "page", pagination.Page, "pageSize", pagination.PageSize)
+// missingOrStale tells why a conditional write matched no product: it is gone or its version moved on
+func (p *ProductStorage) missingOrStale(ctx context.Context, objectID primitive.ObjectID, productID string, version int64) error {
+	count, err := p.db.CountDocuments(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		p.logger.Error("failed to check product existence", "error", err)
+		return fmt.Errorf("failed to check product existence: %w", err)
+	}
+	if count == 0 {
+		p.logger.Warn("product not found", "productID", productID)
+		return errs.NotFound("product not found")
+	}
 
-	// Calculate: how many records to skip
-	skip := (pagination.Page - 1) * pagination.PageSize
+	p.logger.Warn("product version mismatch", "productID", productID, "expected", version)
+	return errs.PreconditionFailed("product was changed since it was read")
+}
 
-	// Options for MongoDB query
-	findOptions := options.Find().
-		SetSkip(int64(skip)).
-		SetLimit(int64(pagination.PageSize))
+// ReserveStock atomically takes quantity units out of the product stock.
+// The update only matches while enough units are left, so concurrent orders can't oversell.
+// Stock movements leave the version alone, it only guards the fields admins edit.
+func (p *ProductStorage) ReserveStock(ctx context.Context, productID string, quantity int) error {
+	p.logger.Info("reserving stock", "productID", productID, "quantity", quantity)
 
-	// Find products with pagination
-	cursor, err := p.db.Find(ctx, bson.M{}, findOptions)
+	// Convert string ID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(productID)
 	if err != nil {
-		p.logger.Error("failed to fetch products from database", "error", err)
-		return nil, fmt.Errorf("failed to fetch products: %w", err)
+		p.logger.Error("invalid product ID format", "error", err)
+		return errs.InvalidID("invalid product ID format", err)
+	}
+
+	filter := bson.M{
+		"_id":   objectID,
+		"stock": bson.M{"$gte": quantity},
 	}
-	defer func() {
-		if cerr := cursor.Close(ctx); cerr != nil {
-			p.logger.Error("failed to close cursor", "error", cerr)
+	update := bson.M{
+		"$inc": bson.M{"stock": -quantity},
+		"$set": bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())},
+	}
+
+	result, err := p.db.UpdateOne(ctx, filter, update)
+	if err != nil {
+		p.logger.Error("failed to reserve stock", "error", err)
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		// Tell a missing product apart from one that ran out of stock
+		count, err := p.db.CountDocuments(ctx, bson.M{"_id": objectID})
+		if err != nil {
+			p.logger.Error("failed to check product existence", "error", err)
+			return fmt.Errorf("failed to check product existence: %w", err)
 		}
-	}()
-
-	// Load data into array
-	var products []models.Product
-	for cursor.Next(ctx) {
-		var product models.Product
-		if err := cursor.Decode(&product); err != nil {
-			p.logger.Error("failed to decode product", "error", err)
-			return nil, fmt.Errorf("failed to decode product: %w", err)
+		if count == 0 {
+			p.logger.Warn("product not found", "productID", productID)
+			return errs.NotFound("product not found")
 		}
-		products = append(products, product)
+
+		p.logger.Warn("insufficient stock", "productID", productID, "quantity", quantity)
+		return &errs.InsufficientStockError{ProductID: productID, Requested: quantity}
+	}
+
+	p.logger.Info("stock reserved successfully", "productID", productID, "quantity", quantity)
+	return nil
+}
+
+// ReleaseStock puts quantity previously reserved units back into the product stock
+func (p *ProductStorage) ReleaseStock(ctx context.Context, productID string, quantity int) error {
+	p.logger.Info("releasing stock", "productID", productID, "quantity", quantity)
+
+	// Convert string ID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(productID)
+	if err != nil {
+		p.logger.Error("invalid product ID format", "error", err)
+		return errs.InvalidID("invalid product ID format", err)
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"stock": quantity},
+		"$set": bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())},
+	}
+
+	result, err := p.db.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		p.logger.Error("failed to release stock", "error", err)
+		return fmt.Errorf("failed to release stock: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		p.logger.Warn("product not found", "productID", productID)
+		return errs.NotFound("product not found")
 	}
 
-	// Check for iteration error
-	if err := cursor.Err(); err != nil {
-		p.logger.Error("cursor iteration error", "error", err)
-		return nil, fmt.Errorf("cursor iteration error: %w", err)
+	p.logger.Info("stock released successfully", "productID", productID, "quantity", quantity)
+	return nil
+}
+
+// ListProducts fetches a paginated list of products from the database, oldest first
+func (p *ProductStorage) ListProducts(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	p.logger.Info("fetching list of products", "page", pagination.Page, "pageSize", pagination.PageSize, "cursor", pagination.Cursor)
+
+	page, err := findPage(ctx, p.db, bson.M{}, cursor.OldestFirst, pagination, productKey)
+	if err != nil {
+		p.logger.Error("failed to fetch products from database", "error", err)
+		return nil, err
 	}
 
-	p.logger.Info("successfully fetched products", "productCount", len(products))
-	return products, nil
+	p.logger.Info("successfully fetched products", "productCount", len(page.Items))
+	return page, nil
 }
 
 // SearchProducts searches for products by name with pagination
-func (p *ProductStorage) SearchProductsByName(ctx context.Context, name string, pagination *models.Pagination) ([]models.Product, error) {
+func (p *ProductStorage) SearchProductsByName(ctx context.Context, name string, pagination *models.Pagination) (*models.Page[models.Product], error) {
 	// Define the search criteria using $regex for partial matching
 	filter := bson.M{
 		"name": bson.M{
@@ -206,84 +332,35 @@ func (p *ProductStorage) SearchProductsByName(ctx context.Context, name string,
 		},
 	}
 
-	// Pagination settings
-	skip := (pagination.Page - 1) * pagination.PageSize
-	opts := options.Find().
-		SetSkip(int64(skip)).
-		SetLimit(int64(pagination.PageSize))
-
-	// Execute the query
-	cursor, err := p.db.Find(ctx, filter, opts)
+	page, err := findPage(ctx, p.db, filter, cursor.OldestFirst, pagination, productKey)
 	if err != nil {
 		p.logger.Error("failed to search products from database", "error", err)
-		return nil, fmt.Errorf("failed to search products: %w", err)
-	}
-	defer cursor.Close(ctx)
-
-	// Decode the results
-	var products []models.Product
-	if err := cursor.All(ctx, &products); err != nil {
-		p.logger.Error("failed to decode search results", "error", err)
-		return nil, fmt.Errorf("failed to decode search results: %w", err)
+		return nil, err
 	}
 
-	p.logger.Info("successfully fetched search results", "productCount", len(products))
-	return products, nil
+	p.logger.Info("successfully fetched search results", "productCount", len(page.Items))
+	return page, nil
 }
 
-// ExactSearchProducts searches for products by price with pagination
-func (s *ProductStorage) ExactSearchProductsByPrice(ctx context.Context, price float64, pagination *models.Pagination) ([]models.Product, error) {
-	// Create filter for exact price match
-	filter := bson.M{"price": price}
+// ExactSearchProducts searches for products priced in currency by price with pagination, newest first
+func (s *ProductStorage) ExactSearchProductsByPrice(ctx context.Context, price models.Money, currency string, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	// Create filter for exact price match, prices in other currencies don't compare
+	filter := bson.M{"currency": currency, "price": price}
 
-	// Pagination and sorting logic
-	skip := (pagination.Page - 1) * pagination.PageSize
-	options := options.Find().
-		SetSkip(int64(skip)).
-		SetLimit(int64(pagination.PageSize)).
-		SetSort(bson.D{{Key: "createdAt", Value: -1}}) // Sort by createdAt, descending
-
-	cursor, err := s.db.Find(ctx, filter, options)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search products: %w", err)
-	}
-	defer cursor.Close(ctx)
-
-	var products []models.Product
-	if err := cursor.All(ctx, &products); err != nil {
-		return nil, fmt.Errorf("failed to decode products: %w", err)
-	}
-
-	return products, nil
+	return findPage(ctx, s.db, filter, cursor.NewestFirst, pagination, productKey)
 }
 
-// SearchProductsByPriceRangeInc searches for products by price with pagination
-func (s *ProductStorage) SearchProductsByPriceRange(ctx context.Context, order int8, minPrice, maxPrice float64, pagination *models.Pagination) ([]models.Product, error) {
-	// Create filter for price range match
+// SearchProductsByPriceRangeInc searches for products priced in currency by price with pagination
+func (s *ProductStorage) SearchProductsByPriceRange(ctx context.Context, order int8, minPrice, maxPrice models.Money, currency string, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	// Create filter for price range match, prices in other currencies don't compare
 	filter := bson.M{
+		"currency": currency,
 		"price": bson.M{
 			"$gte": minPrice, // Greater than or equal to minPrice
 			"$lte": maxPrice, // Less than or equal to maxPrice
 		},
 	}
 
-	// Pagination and sorting logic
-	skip := (pagination.Page - 1) * pagination.PageSize
-	options := options.Find().
-		SetSkip(int64(skip)).
-		SetLimit(int64(pagination.PageSize)).
-		SetSort(bson.D{{Key: "price", Value: int(order)}}) // Sort by price in ascending order
-
-	cursor, err := s.db.Find(ctx, filter, options)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search products: %w", err)
-	}
-	defer cursor.Close(ctx)
-
-	var products []models.Product
-	if err := cursor.All(ctx, &products); err != nil {
-		return nil, fmt.Errorf("failed to decode products: %w", err)
-	}
-
-	return products, nil
+	// Sort by price in ascending or descending order
+	return findPage(ctx, s.db, filter, cursor.CheapestFirst.WithDirection(order), pagination, productKey)
 }
@@ -0,0 +1,299 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PromotionStorage struct {
+	db          *mongo.Collection
+	redemptions *mongo.Collection // Uses of a promotion by a user, keyed by both IDs
+	logger      *slog.Logger
+	cfg         *config.Config
+}
+
+func NewPromotionStorage(db *mongo.Database, logger *slog.Logger, cfg *config.Config) repos.PromotionRepo {
+	return &PromotionStorage{
+		db:          db.Collection("Promotions"),
+		redemptions: db.Collection("PromotionRedemptions"),
+		logger:      logger,
+		cfg:         cfg,
+	}
+}
+
+// CreatePromotion creates a new promotion in the database, the unique index on the code rejects a taken one
+func (p *PromotionStorage) CreatePromotion(ctx context.Context, promotion *models.PromotionCreate) (string, error) {
+	p.logger.Info("starting promotion creation", "code", promotion.Code)
+
+	created_at := primitive.NewDateTimeFromTime(time.Now())
+
+	var newPromotion = models.Promotion{
+		Code:          promotion.Code,
+		Description:   promotion.Description,
+		Type:          promotion.Type,
+		Percent:       promotion.Percent,
+		Amount:        promotion.Amount,
+		ProductID:     promotion.ProductID,
+		BuyQuantity:   promotion.BuyQuantity,
+		FreeQuantity:  promotion.FreeQuantity,
+		MinOrderValue: promotion.MinOrderValue,
+		Currency:      promotion.Currency,
+		StartsAt:      promotion.StartsAt,
+		EndsAt:        promotion.EndsAt,
+		UsageLimit:    promotion.UsageLimit,
+		PerUserLimit:  promotion.PerUserLimit,
+		CreatedAt:     created_at,
+		UpdatedAt:     created_at,
+	}
+	result, err := p.db.InsertOne(ctx, newPromotion)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			p.logger.Warn("promotion code already exists", "code", promotion.Code)
+			return "", errs.Conflict("promotion code already exists")
+		}
+		if hasErrorCode(err, codeDocumentValidationFailure) {
+			p.logger.Warn("promotion rejected by the collection validator", "error", err)
+			return "", errs.Validation("promotion doesn't match the promotion schema")
+		}
+		p.logger.Error("failed to insert promotion", "error", err)
+		return "", fmt.Errorf("failed to insert promotion: %w", err)
+	}
+
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		p.logger.Error("failed to convert inserted ID to ObjectID")
+		return "", errors.New("failed to convert inserted ID to ObjectID")
+	}
+
+	p.logger.Info("promotion creation successful", "promotionID", insertedID.Hex())
+	return insertedID.Hex(), nil
+}
+
+// GetPromotionByID fetches a promotion by its ID
+func (p *PromotionStorage) GetPromotionByID(ctx context.Context, promotionID string) (*models.Promotion, error) {
+	p.logger.Info("fetching promotion by ID", "promotionID", promotionID)
+
+	objectID, err := primitive.ObjectIDFromHex(promotionID)
+	if err != nil {
+		p.logger.Error("invalid promotion ID format", "error", err)
+		return nil, errs.InvalidID("invalid promotion ID format", err)
+	}
+
+	return p.get(ctx, bson.M{"_id": objectID})
+}
+
+// GetPromotionByCode fetches a promotion by its code
+func (p *PromotionStorage) GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	p.logger.Info("fetching promotion by code", "code", code)
+
+	return p.get(ctx, bson.M{"code": code})
+}
+
+// DeletePromotion deletes a promotion and the uses counted for it, the orders it was applied to keep their discounts
+func (p *PromotionStorage) DeletePromotion(ctx context.Context, promotionID string) error {
+	p.logger.Info("deleting promotion", "promotionID", promotionID)
+
+	objectID, err := primitive.ObjectIDFromHex(promotionID)
+	if err != nil {
+		p.logger.Error("invalid promotion ID format", "error", err)
+		return errs.InvalidID("invalid promotion ID format", err)
+	}
+
+	result, err := p.db.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		p.logger.Error("failed to delete promotion", "error", err)
+		return fmt.Errorf("failed to delete promotion: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		p.logger.Warn("no promotion found to delete", "promotionID", promotionID)
+		return errs.NotFound("promotion not found")
+	}
+
+	// Left over counts would only be found again by a promotion reusing the ID, which can't happen
+	if _, err := p.redemptions.DeleteMany(ctx, bson.M{"_id.promotionId": objectID}); err != nil {
+		p.logger.Error("failed to delete promotion redemptions", "promotionID", promotionID, "error", err)
+	}
+
+	p.logger.Info("promotion deleted successfully", "promotionID", promotionID)
+	return nil
+}
+
+// ListPromotions fetches a paginated list of promotions, oldest first
+func (p *PromotionStorage) ListPromotions(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Promotion], error) {
+	p.logger.Info("fetching list of promotions", "page", pagination.Page, "pageSize", pagination.PageSize)
+
+	page, err := findPage(ctx, p.db, bson.M{}, cursor.OldestFirst, pagination, promotionKey)
+	if err != nil {
+		p.logger.Error("failed to fetch promotions from database", "error", err)
+		return nil, err
+	}
+
+	p.logger.Info("successfully fetched promotions", "promotionCount", len(page.Items))
+	return page, nil
+}
+
+// RedeemPromotion counts a use of the promotion by the user. Each count is a single update that only matches
+// while it is below its limit, so concurrent orders can't use a promotion more often than allowed.
+// When the user has no uses left the usage count is taken back, outside a transaction that is a separate write.
+func (p *PromotionStorage) RedeemPromotion(ctx context.Context, promotionID, userID string) error {
+	p.logger.Info("redeeming promotion", "promotionID", promotionID, "userID", userID)
+
+	objectID, err := primitive.ObjectIDFromHex(promotionID)
+	if err != nil {
+		p.logger.Error("invalid promotion ID format", "error", err)
+		return errs.InvalidID("invalid promotion ID format", err)
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		p.logger.Error("invalid user ID format", "error", err)
+		return errs.InvalidID("invalid user ID format", err)
+	}
+
+	var promotion models.Promotion
+	err = p.db.FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "$expr": bson.M{"$or": bson.A{
+			bson.M{"$eq": bson.A{"$usageLimit", 0}},
+			bson.M{"$lt": bson.A{"$usageCount", "$usageLimit"}},
+		}}},
+		bson.M{"$inc": bson.M{"usageCount": 1}},
+	).Decode(&promotion)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			p.logger.Error("failed to redeem promotion", "error", err)
+			return fmt.Errorf("failed to redeem promotion: %w", err)
+		}
+
+		// Tell a missing promotion apart from one that was used up
+		count, err := p.db.CountDocuments(ctx, bson.M{"_id": objectID})
+		if err != nil {
+			p.logger.Error("failed to check promotion existence", "error", err)
+			return fmt.Errorf("failed to check promotion existence: %w", err)
+		}
+		if count == 0 {
+			p.logger.Warn("promotion not found", "promotionID", promotionID)
+			return errs.NotFound("promotion not found")
+		}
+
+		p.logger.Warn("promotion used up", "promotionID", promotionID)
+		return errs.Conflict("promotion has reached its usage limit")
+	}
+
+	counted, err := p.countUse(ctx, objectID, userObjectID, promotion.PerUserLimit)
+	if err != nil || !counted {
+		if _, undoErr := p.db.UpdateOne(ctx, bson.M{"_id": objectID, "usageCount": bson.M{"$gt": 0}}, bson.M{"$inc": bson.M{"usageCount": -1}}); undoErr != nil {
+			p.logger.Error("failed to take back promotion use", "promotionID", promotionID, "error", undoErr)
+		}
+	}
+	if err != nil {
+		p.logger.Error("failed to redeem promotion", "error", err)
+		return fmt.Errorf("failed to redeem promotion: %w", err)
+	}
+	if !counted {
+		p.logger.Warn("promotion used up by user", "promotionID", promotionID, "userID", userID)
+		return errs.Conflict("promotion has reached its usage limit for this user")
+	}
+
+	p.logger.Info("promotion redeemed successfully", "promotionID", promotionID, "userID", userID)
+	return nil
+}
+
+// countUse adds a use to the count of the user unless it reached limit, a limit of 0 means there is none.
+// The count is created first by an upsert matching on _id alone, which MongoDB retries itself when it races
+// another one, so the conditional increment never has to insert and can't fail on a duplicate key.
+func (p *PromotionStorage) countUse(ctx context.Context, promotionID, userID primitive.ObjectID, limit int) (bool, error) {
+	id := bson.D{{Key: "promotionId", Value: promotionID}, {Key: "userId", Value: userID}}
+
+	_, err := p.redemptions.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$setOnInsert": bson.M{"count": 0}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"_id": id}
+	if limit > 0 {
+		filter["count"] = bson.M{"$lt": limit}
+	}
+	result, err := p.redemptions.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"count": 1}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// ReleasePromotion takes back a use of the promotion by the user, the counts never go below zero
+func (p *PromotionStorage) ReleasePromotion(ctx context.Context, promotionID, userID string) error {
+	p.logger.Info("releasing promotion", "promotionID", promotionID, "userID", userID)
+
+	objectID, err := primitive.ObjectIDFromHex(promotionID)
+	if err != nil {
+		p.logger.Error("invalid promotion ID format", "error", err)
+		return errs.InvalidID("invalid promotion ID format", err)
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		p.logger.Error("invalid user ID format", "error", err)
+		return errs.InvalidID("invalid user ID format", err)
+	}
+
+	result, err := p.db.UpdateOne(ctx, bson.M{"_id": objectID, "usageCount": bson.M{"$gt": 0}}, bson.M{"$inc": bson.M{"usageCount": -1}})
+	if err != nil {
+		p.logger.Error("failed to release promotion", "error", err)
+		return fmt.Errorf("failed to release promotion: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		count, err := p.db.CountDocuments(ctx, bson.M{"_id": objectID})
+		if err != nil {
+			p.logger.Error("failed to check promotion existence", "error", err)
+			return fmt.Errorf("failed to check promotion existence: %w", err)
+		}
+		if count == 0 {
+			p.logger.Warn("promotion not found", "promotionID", promotionID)
+			return errs.NotFound("promotion not found")
+		}
+	}
+
+	_, err = p.redemptions.UpdateOne(ctx,
+		bson.M{"_id": bson.D{{Key: "promotionId", Value: objectID}, {Key: "userId", Value: userObjectID}}, "count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"count": -1}},
+	)
+	if err != nil {
+		p.logger.Error("failed to release promotion", "error", err)
+		return fmt.Errorf("failed to release promotion: %w", err)
+	}
+
+	p.logger.Info("promotion released successfully", "promotionID", promotionID, "userID", userID)
+	return nil
+}
+
+// get fetches the promotion matching filter
+func (p *PromotionStorage) get(ctx context.Context, filter bson.M) (*models.Promotion, error) {
+	var promotion models.Promotion
+	err := p.db.FindOne(ctx, filter).Decode(&promotion)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			p.logger.Warn("promotion not found", "filter", filter)
+			return nil, errs.NotFound("promotion not found")
+		}
+		p.logger.Error("failed to fetch promotion from database", "error", err)
+		return nil, fmt.Errorf("failed to fetch promotion: %w", err)
+	}
+
+	return &promotion, nil
+}
@@ -1 +1,192 @@
 package mongodb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// salesMatch skips orders that never turned into revenue
+var salesMatch = bson.D{{Key: "$match", Value: bson.D{
+	{Key: "status", Value: bson.D{{Key: "$nin", Value: bson.A{models.OrderStatusCancelled, models.OrderStatusRefunded}}}},
+}}}
+
+type ReportStorage struct {
+	products *mongo.Collection
+	orders   *mongo.Collection
+	logger   *slog.Logger
+	cfg      *config.Config
+}
+
+func NewReportStorage(db *mongo.Database, logger *slog.Logger, cfg *config.Config) repos.ReportRepo {
+	return &ReportStorage{
+		products: db.Collection("Products"),
+		orders:   db.Collection("Orders"),
+		logger:   logger,
+		cfg:      cfg,
+	}
+}
+
+// GetReport calculates the overall number of products, orders and the revenue with and without the tax per currency.
+// Cancelled and refunded orders don't count towards the sales figures.
+func (r *ReportStorage) GetReport(ctx context.Context) (*models.Report, error) {
+	r.logger.Info("building sales report")
+
+	totalProducts, err := r.products.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		r.logger.Error("failed to count products", "error", err)
+		return nil, fmt.Errorf("failed to count products: %w", err)
+	}
+
+	// Count orders and sum their totals per currency in a single pass
+	pipeline := mongo.Pipeline{
+		salesMatch,
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$currency"},
+			{Key: "totalOrders", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "totalRevenue", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+			{Key: "netRevenue", Value: bson.D{{Key: "$sum", Value: "$net"}}},
+			{Key: "totalTax", Value: bson.D{{Key: "$sum", Value: "$tax"}}},
+		}}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "currency", Value: "$_id"}}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "currency", Value: 1}}}},
+	}
+
+	cursor, err := r.orders.Aggregate(ctx, pipeline)
+	if err != nil {
+		r.logger.Error("failed to aggregate orders", "error", err)
+		return nil, fmt.Errorf("failed to aggregate orders: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	report := &models.Report{TotalProducts: int(totalProducts), Revenue: []models.Revenue{}}
+	if err := cursor.All(ctx, &report.Revenue); err != nil {
+		r.logger.Error("failed to decode order totals", "error", err)
+		return nil, fmt.Errorf("failed to decode order totals: %w", err)
+	}
+	for _, revenue := range report.Revenue {
+		report.TotalOrders += revenue.TotalOrders
+	}
+
+	r.logger.Info("successfully built sales report", "totalOrders", report.TotalOrders)
+	return report, nil
+}
+
+// GetTopProducts returns the best selling products per currency, ranked by units sold and then by revenue
+func (r *ReportStorage) GetTopProducts(ctx context.Context, limit int) ([]models.TopProduct, error) {
+	r.logger.Info("fetching top products", "limit", limit)
+
+	pipeline := mongo.Pipeline{
+		salesMatch,
+		// Every line of an order counts towards its own product
+		bson.D{{Key: "$unwind", Value: "$items"}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "productId", Value: "$items.productId"},
+				{Key: "currency", Value: "$currency"},
+			}},
+			{Key: "total_sold", Value: bson.D{{Key: "$sum", Value: "$items.quantity"}}},
+			{Key: "total_revenue", Value: bson.D{{Key: "$sum", Value: "$items.lineTotal"}}},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{
+			{Key: "total_sold", Value: -1},
+			{Key: "total_revenue", Value: -1},
+			{Key: "_id.productId", Value: 1},
+			{Key: "_id.currency", Value: 1},
+		}}},
+		bson.D{{Key: "$limit", Value: limit}},
+		// Attach the product name, products that were deleted keep an empty name
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "Products"},
+			{Key: "localField", Value: "_id.productId"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "product"},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "product_id", Value: "$_id.productId"},
+			{Key: "currency", Value: "$_id.currency"},
+			{Key: "total_sold", Value: 1},
+			{Key: "total_revenue", Value: 1},
+			{Key: "name", Value: bson.D{{Key: "$ifNull", Value: bson.A{
+				bson.D{{Key: "$arrayElemAt", Value: bson.A{"$product.name", 0}}},
+				"",
+			}}}},
+		}}},
+	}
+
+	cursor, err := r.orders.Aggregate(ctx, pipeline)
+	if err != nil {
+		r.logger.Error("failed to aggregate top products", "error", err)
+		return nil, fmt.Errorf("failed to aggregate top products: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var products []models.TopProduct
+	if err := cursor.All(ctx, &products); err != nil {
+		r.logger.Error("failed to decode top products", "error", err)
+		return nil, fmt.Errorf("failed to decode top products: %w", err)
+	}
+
+	r.logger.Info("successfully fetched top products", "productCount", len(products))
+	return products, nil
+}
+
+// GetDailyOrderAggregates groups the orders created between startDate and endDate by day and currency
+func (r *ReportStorage) GetDailyOrderAggregates(ctx context.Context, startDate, endDate time.Time) ([]models.OrderAggregate, error) {
+	r.logger.Info("fetching daily order aggregates", "startDate", startDate, "endDate", endDate)
+
+	pipeline := mongo.Pipeline{
+		salesMatch,
+		bson.D{{Key: "$match", Value: bson.D{
+			{Key: "createdAt", Value: bson.D{
+				{Key: "$gte", Value: startDate},
+				{Key: "$lte", Value: endDate},
+			}},
+		}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "date", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+					{Key: "format", Value: "%Y-%m-%d"},
+					{Key: "date", Value: "$createdAt"},
+				}}}},
+				{Key: "currency", Value: "$currency"},
+			}},
+			{Key: "total_orders", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "total_revenue", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+			{Key: "net_revenue", Value: bson.D{{Key: "$sum", Value: "$net"}}},
+			{Key: "total_tax", Value: bson.D{{Key: "$sum", Value: "$tax"}}},
+		}}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "date", Value: "$_id.date"},
+			{Key: "currency", Value: "$_id.currency"},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{
+			{Key: "date", Value: 1},
+			{Key: "currency", Value: 1},
+		}}},
+	}
+
+	cursor, err := r.orders.Aggregate(ctx, pipeline)
+	if err != nil {
+		r.logger.Error("failed to aggregate daily orders", "error", err)
+		return nil, fmt.Errorf("failed to aggregate daily orders: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var aggregates []models.OrderAggregate
+	if err := cursor.All(ctx, &aggregates); err != nil {
+		r.logger.Error("failed to decode daily aggregates", "error", err)
+		return nil, fmt.Errorf("failed to decode daily aggregates: %w", err)
+	}
+
+	r.logger.Info("successfully fetched daily order aggregates", "days", len(aggregates))
+	return aggregates, nil
+}
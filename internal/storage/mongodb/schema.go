@@ -0,0 +1,255 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDB error codes the repositories translate into domain errors
+const (
+	codeNamespaceExists           = 48
+	codeDocumentValidationFailure = 121
+)
+
+// collectionIndexes are the indexes of a collection. Listings sort by createdAt or price and break ties
+// by _id, so the sort keys are indexed together with _id and a page is read straight off the index.
+type collectionIndexes struct {
+	collection string
+	models     []mongo.IndexModel
+}
+
+var indexes = []collectionIndexes{
+	{
+		collection: "Products",
+		models: []mongo.IndexModel{
+			// A case-insensitive $regex can't seek in an index, but scanning the names in the index
+			// still beats loading every product
+			{Keys: bson.D{{Key: "name", Value: 1}}},
+			// Prices are only searched within their currency
+			{Keys: bson.D{{Key: "currency", Value: 1}, {Key: "price", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}},
+		},
+	},
+	{
+		collection: "Orders",
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "items.productId", Value: 1}}},
+		},
+	},
+	{
+		collection: "Users",
+		models: []mongo.IndexModel{
+			// CreateUser and UpdateUser rely on the duplicate key error to reject a taken email
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}},
+		},
+	},
+	{
+		collection: "Carts",
+		models: []mongo.IndexModel{
+			// A user has at most one cart, and carts are removed by MongoDB once their expiresAt has passed
+			{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+	},
+	{
+		collection: "Promotions",
+		models: []mongo.IndexModel{
+			// CreatePromotion relies on the duplicate key error to reject a taken code
+			{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}},
+		},
+	},
+}
+
+// currencySchema accepts the form of an ISO 4217 code
+var currencySchema = bson.M{"bsonType": "string", "pattern": "^[A-Z]{3}$"}
+
+// validators are the $jsonSchema validators of the collections, they only check the shape of the documents,
+// the business rules stay in the service layer
+var validators = []struct {
+	collection string
+	schema     bson.M
+}{
+	{
+		collection: "Products",
+		schema: bson.M{
+			"bsonType": "object",
+			"required": bson.A{"name", "price", "currency", "stock", "createdAt", "updatedAt"},
+			"properties": bson.M{
+				"name":        bson.M{"bsonType": "string"},
+				"description": bson.M{"bsonType": "string"},
+				"price":       bson.M{"bsonType": "long", "minimum": 0}, // Minor units, see models.Money
+				"currency":    currencySchema,
+				"taxClass":    bson.M{"bsonType": "string"},
+				"stock":       bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0},
+				"version":     bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 1},
+				"createdAt":   bson.M{"bsonType": "date"},
+				"updatedAt":   bson.M{"bsonType": "date"},
+			},
+		},
+	},
+	{
+		collection: "Orders",
+		schema: bson.M{
+			"bsonType": "object",
+			"required": bson.A{"userId", "items", "status", "total", "currency", "createdAt", "updatedAt"},
+			"properties": bson.M{
+				"userId": bson.M{"bsonType": "objectId"},
+				"items": bson.M{
+					"bsonType": "array",
+					"items": bson.M{
+						"bsonType": "object",
+						"required": bson.A{"productId", "quantity", "unitPrice", "lineTotal", "priceCurrency", "exchangeRate"},
+						"properties": bson.M{
+							"productId":     bson.M{"bsonType": "objectId"},
+							"quantity":      bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 1},
+							"unitPrice":     bson.M{"bsonType": "long", "minimum": 0},
+							"lineTotal":     bson.M{"bsonType": "long", "minimum": 0},
+							"priceCurrency": currencySchema,
+							"exchangeRate":  bson.M{"bsonType": "long", "minimum": 1}, // See models.Rate
+							"taxClass":      bson.M{"bsonType": "string"},
+						},
+					},
+				},
+				"status": bson.M{"enum": bson.A{
+					models.OrderStatusPending,
+					models.OrderStatusPaid,
+					models.OrderStatusShipped,
+					models.OrderStatusDelivered,
+					models.OrderStatusCancelled,
+					models.OrderStatusRefunded,
+				}},
+				"statusHistory": bson.M{"bsonType": "array"},
+				"subtotal":      bson.M{"bsonType": "long", "minimum": 0},
+				"discounts": bson.M{
+					"bsonType": "array",
+					"items": bson.M{
+						"bsonType": "object",
+						"required": bson.A{"promotionId", "code", "type", "amount"},
+						"properties": bson.M{
+							"promotionId": bson.M{"bsonType": "objectId"},
+							"code":        bson.M{"bsonType": "string"},
+							"type":        bson.M{"bsonType": "string"},
+							"amount":      bson.M{"bsonType": "long", "minimum": 0},
+						},
+					},
+				},
+				"discount":  bson.M{"bsonType": "long", "minimum": 0},
+				"taxRegion": bson.M{"bsonType": "string"},
+				"taxMode":   bson.M{"enum": bson.A{models.TaxInclusive, models.TaxExclusive}},
+				"taxes": bson.M{
+					"bsonType": "array",
+					"items": bson.M{
+						"bsonType": "object",
+						"required": bson.A{"taxClass", "rate", "net", "tax"},
+						"properties": bson.M{
+							"taxClass": bson.M{"bsonType": "string"},
+							"rate":     bson.M{"bsonType": "long", "minimum": 0}, // See models.TaxRate
+							"net":      bson.M{"bsonType": "long", "minimum": 0},
+							"tax":      bson.M{"bsonType": "long", "minimum": 0},
+						},
+					},
+				},
+				"net":       bson.M{"bsonType": "long", "minimum": 0},
+				"tax":       bson.M{"bsonType": "long", "minimum": 0},
+				"total":     bson.M{"bsonType": "long", "minimum": 0},
+				"currency":  currencySchema,
+				"version":   bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 1},
+				"createdAt": bson.M{"bsonType": "date"},
+				"updatedAt": bson.M{"bsonType": "date"},
+			},
+		},
+	},
+	{
+		collection: "Promotions",
+		schema: bson.M{
+			"bsonType": "object",
+			"required": bson.A{"code", "type", "currency", "usageLimit", "perUserLimit", "usageCount", "createdAt", "updatedAt"},
+			"properties": bson.M{
+				"code": bson.M{"bsonType": "string"},
+				"type": bson.M{"enum": bson.A{
+					models.PromotionPercentage,
+					models.PromotionFixedAmount,
+					models.PromotionBuyXGetY,
+				}},
+				"percent":       bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0, "maximum": 100},
+				"amount":        bson.M{"bsonType": "long", "minimum": 0},
+				"productId":     bson.M{"bsonType": "objectId"},
+				"buyQuantity":   bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0},
+				"freeQuantity":  bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0},
+				"minOrderValue": bson.M{"bsonType": "long", "minimum": 0},
+				"currency":      currencySchema,
+				"startsAt":      bson.M{"bsonType": "date"},
+				"endsAt":        bson.M{"bsonType": "date"},
+				"usageLimit":    bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0},
+				"perUserLimit":  bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0},
+				"usageCount":    bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0},
+				"createdAt":     bson.M{"bsonType": "date"},
+				"updatedAt":     bson.M{"bsonType": "date"},
+			},
+		},
+	},
+}
+
+// EnsureSchema installs the collection validators and builds the indexes the repositories rely on.
+// It runs on every startup: validators are replaced and indexes that already exist are left as they are.
+func EnsureSchema(ctx context.Context, db *mongo.Database, logger *slog.Logger) error {
+	for _, v := range validators {
+		if err := installValidator(ctx, db, v.collection, v.schema); err != nil {
+			logger.Error("failed to install schema validator", "collection", v.collection, "error", err)
+			return err
+		}
+		logger.Info("schema validator installed", "collection", v.collection)
+	}
+
+	for _, c := range indexes {
+		logger.Info("building indexes", "collection", c.collection, "count", len(c.models))
+
+		started := time.Now()
+		names, err := db.Collection(c.collection).Indexes().CreateMany(ctx, c.models)
+		if err != nil {
+			logger.Error("failed to build indexes", "collection", c.collection, "error", err)
+			return fmt.Errorf("failed to create %s indexes: %w", c.collection, err)
+		}
+
+		logger.Info("indexes ready", "collection", c.collection, "indexes", names, "took", time.Since(started))
+	}
+
+	return nil
+}
+
+// installValidator creates the collection if needed and sets its validator. The moderate validation level
+// lets documents written before the validator existed be updated without fixing them first.
+func installValidator(ctx context.Context, db *mongo.Database, collection string, schema bson.M) error {
+	if err := db.CreateCollection(ctx, collection); err != nil && !hasErrorCode(err, codeNamespaceExists) {
+		return fmt.Errorf("failed to create collection %s: %w", collection, err)
+	}
+
+	err := db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: bson.M{"$jsonSchema": schema}},
+		{Key: "validationLevel", Value: "moderate"},
+		{Key: "validationAction", Value: "error"},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to set the validator of %s: %w", collection, err)
+	}
+	return nil
+}
+
+// hasErrorCode reports whether err is a MongoDB server error with the given code
+func hasErrorCode(err error, code int) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(code)
+}
@@ -0,0 +1,111 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// inTransaction marks the contexts of a running transaction, so nested calls join it
+type inTransaction struct{}
+
+// Transactor runs units of work in MongoDB transactions. The session travels in the context,
+// so the repositories take part without knowing about it.
+//
+// Transactions need a replica set or a sharded cluster. On a standalone server, which the API only
+// starts on when told to, the units of work run without a transaction, with a warning logged once.
+type Transactor struct {
+	db     *mongo.Database
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	checked   bool // Whether the server was asked for transaction support yet
+	supported bool
+}
+
+func NewTransactor(db *mongo.Database, logger *slog.Logger) repos.Transactor {
+	return &Transactor{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// WithTransaction runs fn in a transaction. The driver retries the whole transaction on errors labelled
+// TransientTransactionError, such as write conflicts with a concurrent transaction, and retries the
+// commit when its outcome is unknown.
+func (t *Transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(inTransaction{}) != nil {
+		return fn(ctx)
+	}
+
+	supported, err := t.supportsTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return fn(ctx)
+	}
+
+	session, err := t.db.Client().StartSession()
+	if err != nil {
+		t.logger.Error("failed to start session", "error", err)
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(context.Background())
+
+	opts := options.Transaction().
+		SetReadConcern(readconcern.Snapshot()).
+		SetWriteConcern(writeconcern.Majority())
+
+	attempts := 0
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		attempts++
+		if attempts > 1 {
+			t.logger.Warn("retrying transaction", "attempt", attempts)
+		}
+		return nil, fn(context.WithValue(sc, inTransaction{}, true))
+	}, opts)
+	return err
+}
+
+// supportsTransactions asks the server once whether it is part of a replica set or a sharded cluster
+func (t *Transactor) supportsTransactions(ctx context.Context) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.checked {
+		return t.supported, nil
+	}
+
+	supported, err := SupportsTransactions(ctx, t.db)
+	if err != nil {
+		t.logger.Error("failed to check transaction support", "error", err)
+		return false, err
+	}
+
+	t.checked, t.supported = true, supported
+	if !t.supported {
+		t.logger.Warn("MongoDB is a standalone server, units of work run without transactions")
+	}
+	return t.supported, nil
+}
+
+// SupportsTransactions asks the server whether it is part of a replica set or a sharded cluster
+func SupportsTransactions(ctx context.Context, db *mongo.Database) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, fmt.Errorf("failed to check transaction support: %w", err)
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
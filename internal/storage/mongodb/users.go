@@ -0,0 +1,193 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type UserStorage struct {
+	db     *mongo.Collection
+	logger *slog.Logger
+	cfg    *config.Config
+}
+
+func NewUserStorage(db *mongo.Database, logger *slog.Logger, cfg *config.Config) repos.UserRepo {
+	return &UserStorage{
+		db:     db.Collection("Users"),
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// CreateUser creates a new user in the database
+func (u *UserStorage) CreateUser(ctx context.Context, passwordHash string, user *models.UserCreate) (string, error) {
+	u.logger.Info("starting user creation", "email", user.Email)
+
+	created_at := time.Now()
+
+	// Insert the user into MongoDB
+	var newUser = models.User{
+		Name:         user.Name,
+		Email:        user.Email,
+		PasswordHash: passwordHash,
+		Role:         user.Role,
+		CreatedAt:    primitive.NewDateTimeFromTime(created_at),
+		UpdatedAt:    primitive.NewDateTimeFromTime(created_at),
+	}
+	result, err := u.db.InsertOne(ctx, newUser)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			u.logger.Warn("email already registered", "email", user.Email)
+			return "", errs.Conflict("email is already registered")
+		}
+		u.logger.Error("failed to insert user", "error", err)
+		return "", fmt.Errorf("failed to insert user: %w", err)
+	}
+
+	// Get the inserted ID
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		u.logger.Error("failed to convert inserted ID to ObjectID")
+		return "", errors.New("failed to convert inserted ID to ObjectID")
+	}
+
+	u.logger.Info("user creation successful", "userID", insertedID.Hex())
+	return insertedID.Hex(), nil
+}
+
+// GetUserByID fetches a user by its ID
+func (u *UserStorage) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	u.logger.Info("fetching user by ID", "userID", userID)
+
+	// Convert string ID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		u.logger.Error("invalid user ID format", "error", err)
+		return nil, errs.InvalidID("invalid user ID format", err)
+	}
+
+	// Find the user in MongoDB
+	var user models.User
+	err = u.db.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			u.logger.Warn("user not found", "userID", userID)
+			return nil, errs.NotFound("user not found")
+		}
+		u.logger.Error("failed to fetch user from database", "error", err)
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	u.logger.Info("successfully fetched user", "userID", userID)
+	return &user, nil
+}
+
+// GetUserByEmail fetches a user by its email address
+func (u *UserStorage) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	u.logger.Info("fetching user by email", "email", email)
+
+	var user models.User
+	err := u.db.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			u.logger.Warn("user not found", "email", email)
+			return nil, errs.NotFound("user not found")
+		}
+		u.logger.Error("failed to fetch user from database", "error", err)
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	u.logger.Info("successfully fetched user", "userID", user.ID.Hex())
+	return &user, nil
+}
+
+// UpdateUser updates a user in the database
+func (u *UserStorage) UpdateUser(ctx context.Context, userID string, updates *models.UserUpdate) error {
+	u.logger.Info("updating user", "userID", userID)
+
+	// Convert string ID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		u.logger.Error("invalid user ID format", "error", err)
+		return errs.InvalidID("invalid user ID format", err)
+	}
+
+	var newUser = models.UpdatedUser{
+		Name:      updates.Name,
+		Email:     updates.Email,
+		Role:      updates.Role,
+		UpdatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	// Update the user in MongoDB
+	result, err := u.db.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": newUser})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			u.logger.Warn("email already registered", "email", updates.Email)
+			return errs.Conflict("email is already registered")
+		}
+		u.logger.Error("failed to update user", "error", err)
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		u.logger.Warn("no user found to update", "userID", userID)
+		return errs.NotFound("user not found")
+	}
+
+	u.logger.Info("user updated successfully", "userID", userID)
+	return nil
+}
+
+// DeleteUser deletes a user by its ID
+func (u *UserStorage) DeleteUser(ctx context.Context, userID string) error {
+	u.logger.Info("deleting user", "userID", userID)
+
+	// Convert string ID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		u.logger.Error("invalid user ID format", "error", err)
+		return errs.InvalidID("invalid user ID format", err)
+	}
+
+	// Delete the user in MongoDB
+	result, err := u.db.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		u.logger.Error("failed to delete user", "error", err)
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		u.logger.Warn("no user found to delete", "userID", userID)
+		return errs.NotFound("user not found")
+	}
+
+	u.logger.Info("user deleted successfully", "userID", userID)
+	return nil
+}
+
+// ListUsers fetches a paginated list of users from the database
+func (u *UserStorage) ListUsers(ctx context.Context, pagination *models.Pagination) (*models.Page[models.User], error) {
+	u.logger.Info("fetching list of users", "page", pagination.Page, "pageSize", pagination.PageSize)
+
+	page, err := findPage(ctx, u.db, bson.M{}, cursor.OldestFirst, pagination, userKey)
+	if err != nil {
+		u.logger.Error("failed to fetch users from database", "error", err)
+		return nil, err
+	}
+
+	u.logger.Info("successfully fetched users", "userCount", len(page.Items))
+	return page, nil
+}
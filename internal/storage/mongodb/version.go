@@ -0,0 +1,20 @@
+package mongodb
+
+import (
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// versioned returns the filter of a write to the document with the given ID, which only matches
+// while the document is at the expected version unless that is models.AnyVersion
+func versioned(id primitive.ObjectID, version int64) bson.M {
+	filter := bson.M{"_id": id}
+	if version != models.AnyVersion {
+		filter["version"] = version
+	}
+	return filter
+}
+
+// bumpVersion is the part of an update that records a write in the version of the document
+var bumpVersion = bson.M{"version": 1}
@@ -0,0 +1,78 @@
+-- IDs keep the 24 hex character form used by the API, the "C" collation sorts them by their bytes
+-- so ties in a listing are broken the same way as in MongoDB.
+-- Orders and carts reference users and products without foreign keys: an order outlives the
+-- products and the user it refers to, exactly like the documents did.
+
+CREATE TABLE products (
+    id          CHAR(24) COLLATE "C" PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price       DOUBLE PRECISION NOT NULL,
+    stock       INTEGER NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX products_created_at_idx ON products (created_at, id);
+CREATE INDEX products_price_idx ON products (price, id);
+
+CREATE TABLE users (
+    id            CHAR(24) COLLATE "C" PRIMARY KEY,
+    name          TEXT NOT NULL,
+    email         TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    role          TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL,
+    updated_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX users_created_at_idx ON users (created_at, id);
+
+CREATE TABLE orders (
+    id         CHAR(24) COLLATE "C" PRIMARY KEY,
+    user_id    CHAR(24) COLLATE "C" NOT NULL,
+    status     TEXT NOT NULL,
+    total      DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX orders_created_at_idx ON orders (created_at, id);
+CREATE INDEX orders_user_id_idx ON orders (user_id, created_at, id);
+
+CREATE TABLE order_items (
+    order_id   CHAR(24) COLLATE "C" NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    position   INTEGER NOT NULL,
+    product_id CHAR(24) COLLATE "C" NOT NULL,
+    quantity   INTEGER NOT NULL,
+    unit_price DOUBLE PRECISION NOT NULL,
+    line_total DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (order_id, position)
+);
+
+CREATE INDEX order_items_product_id_idx ON order_items (product_id);
+
+CREATE TABLE order_status_history (
+    order_id    CHAR(24) COLLATE "C" NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    from_status TEXT NOT NULL,
+    to_status   TEXT NOT NULL,
+    changed_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (order_id, position)
+);
+
+CREATE TABLE carts (
+    user_id    CHAR(24) COLLATE "C" PRIMARY KEY,
+    id         CHAR(24) COLLATE "C" NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE cart_items (
+    user_id    CHAR(24) COLLATE "C" NOT NULL REFERENCES carts (user_id) ON DELETE CASCADE,
+    product_id CHAR(24) COLLATE "C" NOT NULL,
+    quantity   INTEGER NOT NULL,
+    position   BIGINT GENERATED ALWAYS AS IDENTITY,
+    PRIMARY KEY (user_id, product_id)
+);
@@ -0,0 +1,5 @@
+-- Every write to a product or an order increments its version, conditional writes compare it.
+-- Rows written before start at version 1, like new ones do.
+
+ALTER TABLE products ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE orders ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,14 @@
+-- Amounts are stored as integers in minor units, the hundredths of the currency unit, so they add up exactly.
+-- Amounts written before were in currency units, they are converted and priced in USD, the default currency.
+
+ALTER TABLE products
+    ALTER COLUMN price TYPE BIGINT USING round(price * 100)::BIGINT,
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+ALTER TABLE orders
+    ALTER COLUMN total TYPE BIGINT USING round(total * 100)::BIGINT,
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+ALTER TABLE order_items
+    ALTER COLUMN unit_price TYPE BIGINT USING round(unit_price * 100)::BIGINT,
+    ALTER COLUMN line_total TYPE BIGINT USING round(line_total * 100)::BIGINT;
@@ -0,0 +1,8 @@
+-- Order lines record the currency their product was priced in and the exchange rate that converted the price
+-- to the currency of the order, in units of 10^-10. Lines written before were priced in the currency of their order.
+
+ALTER TABLE order_items
+    ADD COLUMN price_currency CHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN exchange_rate BIGINT NOT NULL DEFAULT 10000000000;
+
+UPDATE order_items SET price_currency = orders.currency FROM orders WHERE orders.id = order_items.order_id;
@@ -0,0 +1,52 @@
+-- Promotions are redeemed with their code, stored in upper case. The usage count holds the orders currently
+-- using a promotion and never exceeds its usage limit, a limit of 0 means there is none. Redemptions count the
+-- uses of every user against the per user limit.
+-- Orders keep the subtotal of their lines and the discounts applied to it, the total is what is left.
+-- Orders placed before had no discounts, their subtotal is their total.
+
+CREATE TABLE promotions (
+    id              CHAR(24) COLLATE "C" PRIMARY KEY,
+    code            TEXT NOT NULL UNIQUE,
+    description     TEXT NOT NULL DEFAULT '',
+    type            TEXT NOT NULL,
+    percent         INTEGER NOT NULL DEFAULT 0,
+    amount          BIGINT NOT NULL DEFAULT 0,
+    product_id      CHAR(24) COLLATE "C",
+    buy_quantity    INTEGER NOT NULL DEFAULT 0,
+    free_quantity   INTEGER NOT NULL DEFAULT 0,
+    min_order_value BIGINT NOT NULL DEFAULT 0,
+    currency        CHAR(3) NOT NULL,
+    starts_at       TIMESTAMPTZ,
+    ends_at         TIMESTAMPTZ,
+    usage_limit     INTEGER NOT NULL DEFAULT 0,
+    per_user_limit  INTEGER NOT NULL DEFAULT 0,
+    usage_count     INTEGER NOT NULL DEFAULT 0 CHECK (usage_count >= 0 AND (usage_limit = 0 OR usage_count <= usage_limit)),
+    created_at      TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX promotions_created_at_idx ON promotions (created_at, id);
+
+CREATE TABLE promotion_redemptions (
+    promotion_id CHAR(24) COLLATE "C" NOT NULL REFERENCES promotions (id) ON DELETE CASCADE,
+    user_id      CHAR(24) COLLATE "C" NOT NULL,
+    count        INTEGER NOT NULL CHECK (count >= 0),
+    PRIMARY KEY (promotion_id, user_id)
+);
+
+ALTER TABLE orders
+    ADD COLUMN subtotal BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN discount BIGINT NOT NULL DEFAULT 0;
+
+UPDATE orders SET subtotal = total;
+
+-- Discounts outlive the promotions they came from, like order lines outlive their products
+CREATE TABLE order_discounts (
+    order_id     CHAR(24) COLLATE "C" NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    position     INTEGER NOT NULL,
+    promotion_id CHAR(24) COLLATE "C" NOT NULL,
+    code         TEXT NOT NULL,
+    type         TEXT NOT NULL,
+    amount       BIGINT NOT NULL,
+    PRIMARY KEY (order_id, position)
+);
@@ -0,0 +1,26 @@
+-- Products have a tax class, the products stored before get the standard one.
+-- Order lines keep the tax class they were taxed in, orders keep the region and the mode they were taxed with
+-- and a breakdown of the tax by class. The net is the total without the tax.
+-- Orders placed before weren't taxed, their net is their total.
+
+ALTER TABLE products ADD COLUMN tax_class TEXT NOT NULL DEFAULT 'standard';
+
+ALTER TABLE order_items ADD COLUMN tax_class TEXT NOT NULL DEFAULT 'standard';
+
+ALTER TABLE orders
+    ADD COLUMN tax_region TEXT NOT NULL DEFAULT '',
+    ADD COLUMN tax_mode TEXT NOT NULL DEFAULT 'exclusive',
+    ADD COLUMN net BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax BIGINT NOT NULL DEFAULT 0;
+
+UPDATE orders SET net = total;
+
+CREATE TABLE order_taxes (
+    order_id  CHAR(24) COLLATE "C" NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    position  INTEGER NOT NULL,
+    tax_class TEXT NOT NULL,
+    rate      INTEGER NOT NULL,
+    net       BIGINT NOT NULL,
+    tax       BIGINT NOT NULL,
+    PRIMARY KEY (order_id, position)
+);
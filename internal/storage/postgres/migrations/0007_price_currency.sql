@@ -0,0 +1,5 @@
+-- Prices are only compared within their currency, the price searches filter on the currency
+-- and sort by the price, so the currency leads the index on the product prices.
+
+DROP INDEX products_price_idx;
+CREATE INDEX products_price_idx ON products (currency, price, id);
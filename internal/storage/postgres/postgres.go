@@ -0,0 +1,92 @@
+// Package postgres opens and migrates a PostgreSQL database for the sqlstore repositories.
+//
+// The schema is created and upgraded by the migrations embedded in the binary, see Migrate.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/sqlstore"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" database/sql driver
+)
+
+// PostgreSQL error codes the dialect tells apart
+const (
+	codeUniqueViolation      = "23505"
+	codeInvalidRegularExp    = "2201B"
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+)
+
+// ConnectDB opens the database in POSTGRES_DSN, checks that it is reachable and applies the pending migrations
+func ConnectDB(cfg *config.Config) (*sql.DB, error) {
+	db, err := sql.Open("pgx", cfg.Postgres.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Dialect is the SQL of PostgreSQL
+type Dialect struct{}
+
+var _ sqlstore.Dialect = Dialect{}
+
+func (Dialect) Timestamp(t time.Time) any {
+	return t
+}
+
+// Sum casts the NUMERIC sum of a BIGINT column back into a BIGINT
+func (Dialect) Sum(expr string) string {
+	return "SUM(" + expr + ")::BIGINT"
+}
+
+func (Dialect) Day(column string) string {
+	return "to_char(" + column + " AT TIME ZONE 'UTC', 'YYYY-MM-DD')"
+}
+
+func (Dialect) MatchesPattern(column, pattern string, arg func(value any) string) (string, error) {
+	return column + " ~* " + arg(pattern), nil
+}
+
+func (Dialect) IsInvalidPattern(err error) bool {
+	return hasCode(err, codeInvalidRegularExp)
+}
+
+func (Dialect) IsUniqueViolation(err error) bool {
+	return hasCode(err, codeUniqueViolation)
+}
+
+func (Dialect) IsRetryable(err error) bool {
+	return hasCode(err, codeSerializationFailure) || hasCode(err, codeDeadlockDetected)
+}
+
+// CartItemOrder is the identity column numbering the lines of the carts
+func (Dialect) CartItemOrder() string {
+	return "position"
+}
+
+// hasCode reports whether err is a PostgreSQL error with the given code
+func hasCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
@@ -0,0 +1,80 @@
+-- IDs keep the 24 hex character form used by the API and timestamps are stored as milliseconds
+-- since the Unix epoch, the precision of the MongoDB dates, so both sort like they do in MongoDB.
+-- Orders and carts reference users and products without foreign keys: an order outlives the
+-- products and the user it refers to, exactly like the documents did.
+-- Databases created before the migrations were recorded already have these tables, so every
+-- statement leaves existing objects alone.
+
+CREATE TABLE IF NOT EXISTS products (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price       REAL NOT NULL,
+    stock       INTEGER NOT NULL,
+    created_at  INTEGER NOT NULL,
+    updated_at  INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS products_created_at_idx ON products (created_at, id);
+CREATE INDEX IF NOT EXISTS products_price_idx ON products (price, id);
+
+CREATE TABLE IF NOT EXISTS users (
+    id            TEXT PRIMARY KEY,
+    name          TEXT NOT NULL,
+    email         TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    role          TEXT NOT NULL,
+    created_at    INTEGER NOT NULL,
+    updated_at    INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at, id);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL,
+    status     TEXT NOT NULL,
+    total      REAL NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON orders (created_at, id);
+CREATE INDEX IF NOT EXISTS orders_user_id_idx ON orders (user_id, created_at, id);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    order_id   TEXT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    position   INTEGER NOT NULL,
+    product_id TEXT NOT NULL,
+    quantity   INTEGER NOT NULL,
+    unit_price REAL NOT NULL,
+    line_total REAL NOT NULL,
+    PRIMARY KEY (order_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS order_items_product_id_idx ON order_items (product_id);
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    order_id    TEXT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    from_status TEXT NOT NULL,
+    to_status   TEXT NOT NULL,
+    changed_at  INTEGER NOT NULL,
+    PRIMARY KEY (order_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS carts (
+    user_id    TEXT PRIMARY KEY,
+    id         TEXT NOT NULL UNIQUE,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+
+-- Lines keep the order they were added in through the rowid, updating the quantity keeps it
+CREATE TABLE IF NOT EXISTS cart_items (
+    user_id    TEXT NOT NULL REFERENCES carts (user_id) ON DELETE CASCADE,
+    product_id TEXT NOT NULL,
+    quantity   INTEGER NOT NULL,
+    PRIMARY KEY (user_id, product_id)
+);
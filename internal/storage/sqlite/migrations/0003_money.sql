@@ -0,0 +1,26 @@
+-- Amounts are stored as integers in minor units, the hundredths of the currency unit, so they add up exactly.
+-- Amounts written before were in currency units, they are converted and priced in USD, the default currency.
+-- SQLite can't change the type of a column: every amount moves to a new integer column that takes the place
+-- of the old one, the index on the product prices is built again on the new column.
+
+ALTER TABLE products ADD COLUMN price_minor INTEGER NOT NULL DEFAULT 0;
+UPDATE products SET price_minor = CAST(round(price * 100) AS INTEGER);
+DROP INDEX products_price_idx;
+ALTER TABLE products DROP COLUMN price;
+ALTER TABLE products RENAME COLUMN price_minor TO price;
+CREATE INDEX products_price_idx ON products (price, id);
+ALTER TABLE products ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+
+ALTER TABLE orders ADD COLUMN total_minor INTEGER NOT NULL DEFAULT 0;
+UPDATE orders SET total_minor = CAST(round(total * 100) AS INTEGER);
+ALTER TABLE orders DROP COLUMN total;
+ALTER TABLE orders RENAME COLUMN total_minor TO total;
+ALTER TABLE orders ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+
+ALTER TABLE order_items ADD COLUMN unit_price_minor INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN line_total_minor INTEGER NOT NULL DEFAULT 0;
+UPDATE order_items SET unit_price_minor = CAST(round(unit_price * 100) AS INTEGER), line_total_minor = CAST(round(line_total * 100) AS INTEGER);
+ALTER TABLE order_items DROP COLUMN unit_price;
+ALTER TABLE order_items DROP COLUMN line_total;
+ALTER TABLE order_items RENAME COLUMN unit_price_minor TO unit_price;
+ALTER TABLE order_items RENAME COLUMN line_total_minor TO line_total;
@@ -0,0 +1,6 @@
+-- Order lines record the currency their product was priced in and the exchange rate that converted the price
+-- to the currency of the order, in units of 10^-10. Lines written before were priced in the currency of their order.
+
+ALTER TABLE order_items ADD COLUMN price_currency TEXT NOT NULL DEFAULT 'USD';
+ALTER TABLE order_items ADD COLUMN exchange_rate INTEGER NOT NULL DEFAULT 10000000000;
+UPDATE order_items SET price_currency = (SELECT currency FROM orders WHERE orders.id = order_items.order_id);
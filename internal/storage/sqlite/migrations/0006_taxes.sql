@@ -0,0 +1,24 @@
+-- Products have a tax class, the products stored before get the standard one.
+-- Order lines keep the tax class they were taxed in, orders keep the region and the mode they were taxed with
+-- and a breakdown of the tax by class. The net is the total without the tax.
+-- Orders placed before weren't taxed, their net is their total.
+
+ALTER TABLE products ADD COLUMN tax_class TEXT NOT NULL DEFAULT 'standard';
+
+ALTER TABLE order_items ADD COLUMN tax_class TEXT NOT NULL DEFAULT 'standard';
+
+ALTER TABLE orders ADD COLUMN tax_region TEXT NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN tax_mode TEXT NOT NULL DEFAULT 'exclusive';
+ALTER TABLE orders ADD COLUMN net INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN tax INTEGER NOT NULL DEFAULT 0;
+UPDATE orders SET net = total;
+
+CREATE TABLE order_taxes (
+    order_id  TEXT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    position  INTEGER NOT NULL,
+    tax_class TEXT NOT NULL,
+    rate      INTEGER NOT NULL,
+    net       INTEGER NOT NULL,
+    tax       INTEGER NOT NULL,
+    PRIMARY KEY (order_id, position)
+);
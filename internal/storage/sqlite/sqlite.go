@@ -0,0 +1,115 @@
+// Package sqlite opens and migrates an embedded SQLite database file for the sqlstore repositories.
+//
+// It runs on a pure Go SQLite build, so the API still ships as a single binary without cgo.
+// The schema is created and upgraded by the migrations embedded in the binary, see Migrate.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/sqlstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	sqlite3 "modernc.org/sqlite" // Registers the "sqlite" database/sql driver
+	sqlitelib "modernc.org/sqlite/lib"
+)
+
+func init() {
+	// SQLite only declares the REGEXP operator, "x REGEXP y" calls regexp(y, x)
+	sqlite3.MustRegisterDeterministicScalarFunction("regexp", 2, func(_ *sqlite3.FunctionContext, args []driver.Value) (driver.Value, error) {
+		pattern, ok := args[0].(string)
+		if !ok {
+			return nil, errors.New("regexp: pattern is not a string")
+		}
+		value, ok := args[1].(string)
+		if !ok {
+			return false, nil
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(value), nil
+	})
+}
+
+// ConnectDB opens the database file in SQLITE_PATH, creating it when it doesn't exist yet, and applies the pending migrations
+func ConnectDB(cfg *config.Config) (*sql.DB, error) {
+	// Foreign keys are off by default in SQLite, the busy timeout covers other processes holding the file
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+cfg.SQLite.Path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer at a time, a single connection queues the writes instead of
+	// failing them with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Dialect is the SQL of SQLite. Timestamps are stored as milliseconds since the epoch, the way the models hold them.
+type Dialect struct{}
+
+var _ sqlstore.Dialect = Dialect{}
+
+func (Dialect) Timestamp(t time.Time) any {
+	return primitive.NewDateTimeFromTime(t)
+}
+
+func (Dialect) Sum(expr string) string {
+	return "SUM(" + expr + ")"
+}
+
+func (Dialect) Day(column string) string {
+	return "strftime('%Y-%m-%d', " + column + " / 1000, 'unixepoch')"
+}
+
+// MatchesPattern validates the pattern up front: the driver only reports the errors of the regexp function as text
+func (Dialect) MatchesPattern(column, pattern string, arg func(value any) string) (string, error) {
+	pattern = "(?i)" + pattern
+	if _, err := regexp.Compile(pattern); err != nil {
+		return "", errs.Validation("invalid search pattern")
+	}
+	return column + " REGEXP " + arg(pattern), nil
+}
+
+func (Dialect) IsInvalidPattern(error) bool {
+	return false
+}
+
+func (Dialect) IsUniqueViolation(err error) bool {
+	var sqliteErr *sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlitelib.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// IsRetryable is always false: the single connection and the immediate transactions serialize the writes
+func (Dialect) IsRetryable(error) bool {
+	return false
+}
+
+func (Dialect) CartItemOrder() string {
+	return "rowid"
+}
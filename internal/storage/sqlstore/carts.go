@@ -0,0 +1,190 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+)
+
+// CartStorage keeps carts in the carts and cart_items tables. There is no TTL monitor like in MongoDB,
+// expired carts are treated as missing and replaced by a fresh cart on the next change.
+type CartStorage struct {
+	db      *sql.DB
+	dialect Dialect
+	logger  *slog.Logger
+	cfg     *config.Config
+}
+
+func NewCartStorage(db *sql.DB, dialect Dialect, logger *slog.Logger, cfg *config.Config) repos.CartRepo {
+	return &CartStorage{
+		db:      db,
+		dialect: dialect,
+		logger:  logger,
+		cfg:     cfg,
+	}
+}
+
+// GetCartByUser fetches the cart of a user, carts past their expiry count as missing
+func (c *CartStorage) GetCartByUser(ctx context.Context, userID string) (*models.Cart, error) {
+	c.logger.Info("fetching cart of user", "userID", userID)
+
+	id, err := parseID(userID, "user")
+	if err != nil {
+		c.logger.Error("invalid user ID format", "error", err)
+		return nil, err
+	}
+
+	cart := models.Cart{UserID: id, Items: []models.CartItem{}}
+	err = conn(ctx, c.db).QueryRowContext(ctx,
+		`SELECT id, created_at, updated_at, expires_at FROM carts WHERE user_id = $1 AND expires_at > $2`, id, c.dialect.Timestamp(now()),
+	).Scan(&cart.ID, timeOf(&cart.CreatedAt), timeOf(&cart.UpdatedAt), timeOf(&cart.ExpiresAt))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.NotFound("cart not found")
+		}
+		c.logger.Error("failed to fetch cart from database", "error", err)
+		return nil, fmt.Errorf("failed to fetch cart: %w", err)
+	}
+
+	rows, err := conn(ctx, c.db).QueryContext(ctx, `SELECT product_id, quantity FROM cart_items WHERE user_id = $1 ORDER BY `+c.dialect.CartItemOrder(), id)
+	if err != nil {
+		c.logger.Error("failed to fetch cart items from database", "error", err)
+		return nil, fmt.Errorf("failed to fetch cart items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.CartItem
+		if err := rows.Scan(&item.ProductID, &item.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to decode cart item: %w", err)
+		}
+		cart.Items = append(cart.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch cart items: %w", err)
+	}
+
+	c.logger.Info("successfully fetched cart", "userID", userID, "items", len(cart.Items))
+	return &cart, nil
+}
+
+// SetCartItem sets the quantity of a product in the cart of a user, adding the line and creating the cart as needed
+func (c *CartStorage) SetCartItem(ctx context.Context, userID, productID string, quantity int) error {
+	c.logger.Info("setting cart item", "userID", userID, "productID", productID, "quantity", quantity)
+
+	id, err := parseID(userID, "user")
+	if err != nil {
+		c.logger.Error("invalid user ID format", "error", err)
+		return err
+	}
+	product, err := parseID(productID, "product")
+	if err != nil {
+		c.logger.Error("invalid product ID format", "error", err)
+		return err
+	}
+
+	updatedAt, expiresAt := c.dialect.Timestamp(now()), c.dialect.Timestamp(now().Add(c.cfg.Cart.TTL))
+	err = inTx(ctx, c.db, func(tx *sql.Tx) error {
+		// An expired cart would have been removed by the MongoDB TTL monitor, start over
+		if _, err := tx.ExecContext(ctx, `DELETE FROM carts WHERE user_id = $1 AND expires_at <= $2`, id, updatedAt); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO carts (user_id, id, created_at, updated_at, expires_at) VALUES ($1, $2, $3, $3, $4)
+			ON CONFLICT (user_id) DO UPDATE SET updated_at = EXCLUDED.updated_at, expires_at = EXCLUDED.expires_at`,
+			id, models.NewID(), updatedAt, expiresAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO cart_items (user_id, product_id, quantity) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = EXCLUDED.quantity`,
+			id, product, quantity,
+		)
+		return err
+	})
+	if err != nil {
+		c.logger.Error("failed to set cart item", "error", err)
+		return fmt.Errorf("failed to set cart item: %w", err)
+	}
+
+	c.logger.Info("cart item set successfully", "userID", userID, "productID", productID)
+	return nil
+}
+
+// RemoveCartItem removes a product from the cart of a user
+func (c *CartStorage) RemoveCartItem(ctx context.Context, userID, productID string) error {
+	c.logger.Info("removing cart item", "userID", userID, "productID", productID)
+
+	id, err := parseID(userID, "user")
+	if err != nil {
+		c.logger.Error("invalid user ID format", "error", err)
+		return err
+	}
+	product, err := parseID(productID, "product")
+	if err != nil {
+		c.logger.Error("invalid product ID format", "error", err)
+		return err
+	}
+
+	var found bool
+	updatedAt, expiresAt := c.dialect.Timestamp(now()), c.dialect.Timestamp(now().Add(c.cfg.Cart.TTL))
+	err = inTx(ctx, c.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM cart_items WHERE user_id = $1 AND product_id = $2
+			AND EXISTS (SELECT 1 FROM carts WHERE user_id = $1 AND expires_at > $3)`,
+			id, product, updatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return nil
+		}
+		found = true
+
+		_, err = tx.ExecContext(ctx, `UPDATE carts SET updated_at = $2, expires_at = $3 WHERE user_id = $1`, id, updatedAt, expiresAt)
+		return err
+	})
+	if err != nil {
+		c.logger.Error("failed to remove cart item", "error", err)
+		return fmt.Errorf("failed to remove cart item: %w", err)
+	}
+
+	if !found {
+		c.logger.Warn("no cart item found to remove", "userID", userID, "productID", productID)
+		return errs.NotFound("product is not in the cart")
+	}
+
+	c.logger.Info("cart item removed successfully", "userID", userID, "productID", productID)
+	return nil
+}
+
+// DeleteCart removes the cart of a user, deleting a missing cart is not an error
+func (c *CartStorage) DeleteCart(ctx context.Context, userID string) error {
+	c.logger.Info("deleting cart", "userID", userID)
+
+	id, err := parseID(userID, "user")
+	if err != nil {
+		c.logger.Error("invalid user ID format", "error", err)
+		return err
+	}
+
+	if _, err := conn(ctx, c.db).ExecContext(ctx, `DELETE FROM carts WHERE user_id = $1`, id); err != nil {
+		c.logger.Error("failed to delete cart", "error", err)
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+
+	c.logger.Info("cart deleted successfully", "userID", userID)
+	return nil
+}
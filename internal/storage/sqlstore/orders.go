@@ -0,0 +1,589 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const orderColumns = "id, user_id, status, subtotal, discount, tax_region, tax_mode, net, tax, total, currency, version, created_at, updated_at"
+
+type OrderStorage struct {
+	db      *sql.DB
+	dialect Dialect
+	logger  *slog.Logger
+	cfg     *config.Config
+}
+
+func NewOrderStorage(db *sql.DB, dialect Dialect, logger *slog.Logger, cfg *config.Config) repos.OrderRepo {
+	return &OrderStorage{
+		db:      db,
+		dialect: dialect,
+		logger:  logger,
+		cfg:     cfg,
+	}
+}
+
+// scanOrder reads the order row itself, its items, discounts, taxes and history are loaded by loadOrderDetails
+func scanOrder(row scanner) (models.Order, error) {
+	var order models.Order
+	err := row.Scan(&order.ID, &order.UserID, &order.Status, &order.Subtotal, &order.Discount, &order.TaxRegion, &order.TaxMode, &order.Net, &order.Tax, &order.Total, &order.Currency, &order.Version, timeOf(&order.CreatedAt), timeOf(&order.UpdatedAt))
+	order.Items, order.Discounts, order.Taxes, order.StatusHistory = []models.OrderItem{}, []models.AppliedDiscount{}, []models.TaxLine{}, []models.StatusChange{}
+	return order, err
+}
+
+// CreateOrder stores a priced order, every order starts its lifecycle as pending
+func (o *OrderStorage) CreateOrder(ctx context.Context, order *models.Order) (string, error) {
+	o.logger.Info("starting order creation", "userID", order.UserID, "items", len(order.Items))
+
+	id, created_at := models.NewID(), o.dialect.Timestamp(now())
+	err := inTx(ctx, o.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO orders (id, user_id, status, subtotal, discount, tax_region, tax_mode, net, tax, total, currency, version, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, 1, $12, $12)`,
+			id, order.UserID, models.OrderStatusPending, order.Subtotal, order.Discount, order.TaxRegion, order.TaxMode, order.Net, order.Tax, order.Total, order.Currency, created_at,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := insertOrderItems(ctx, tx, id, order.Items); err != nil {
+			return err
+		}
+		if err := insertOrderDiscounts(ctx, tx, id, order.Discounts); err != nil {
+			return err
+		}
+		if err := insertOrderTaxes(ctx, tx, id, order.Taxes); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO order_status_history (order_id, position, from_status, to_status, changed_at) VALUES ($1, 0, '', $2, $3)`,
+			id, models.OrderStatusPending, created_at,
+		)
+		return err
+	})
+	if err != nil {
+		o.logger.Error("failed to insert order", "error", err)
+		return "", fmt.Errorf("failed to insert order: %w", err)
+	}
+
+	o.logger.Info("order creation successful", "orderID", id.Hex())
+	return id.Hex(), nil
+}
+
+// GetOrderByID fetches an order by its ID
+func (o *OrderStorage) GetOrderByID(ctx context.Context, orderID string) (*models.Order, error) {
+	o.logger.Info("fetching order by ID", "orderID", orderID)
+
+	id, err := parseID(orderID, "order")
+	if err != nil {
+		o.logger.Error("invalid order ID format", "error", err)
+		return nil, err
+	}
+
+	order, err := getOrder(ctx, conn(ctx, o.db), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			o.logger.Warn("order not found", "orderID", orderID)
+			return nil, errs.NotFound("order not found")
+		}
+		o.logger.Error("failed to fetch order from database", "error", err)
+		return nil, fmt.Errorf("failed to fetch order: %w", err)
+	}
+
+	o.logger.Info("successfully fetched order", "orderID", orderID)
+	return order, nil
+}
+
+// UpdateOrder replaces the items, the discounts, the taxes and the totals of an order, as long as it is still at the expected version
+func (o *OrderStorage) UpdateOrder(ctx context.Context, orderID string, version int64, updates *models.UpdatedOrder) error {
+	o.logger.Info("updating order", "orderID", orderID, "version", version)
+
+	id, err := parseID(orderID, "order")
+	if err != nil {
+		o.logger.Error("invalid order ID format", "error", err)
+		return err
+	}
+
+	err = inTx(ctx, o.db, func(tx *sql.Tx) error {
+		// The expected version only has to match when it isn't models.AnyVersion
+		result, err := tx.ExecContext(ctx,
+			`UPDATE orders SET subtotal = $2, discount = $3, net = $4, tax = $5, total = $6, version = version + 1, updated_at = $7 WHERE id = $1 AND (version = $8 OR $8 = 0)`,
+			id, updates.Subtotal, updates.Discount, updates.Net, updates.Tax, updates.Total, o.dialect.Timestamp(now()), version,
+		)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return missingOrStale(ctx, tx, "orders", "order", id)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM order_items WHERE order_id = $1`, id); err != nil {
+			return err
+		}
+		if err := insertOrderItems(ctx, tx, id, updates.Items); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM order_discounts WHERE order_id = $1`, id); err != nil {
+			return err
+		}
+		if err := insertOrderDiscounts(ctx, tx, id, updates.Discounts); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM order_taxes WHERE order_id = $1`, id); err != nil {
+			return err
+		}
+		return insertOrderTaxes(ctx, tx, id, updates.Taxes)
+	})
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrPreconditionFailed) {
+			o.logger.Warn("order not updated", "orderID", orderID, "expected", version, "reason", err)
+			return err
+		}
+		o.logger.Error("failed to update order", "error", err)
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+
+	o.logger.Info("order updated successfully", "orderID", orderID, "items", len(updates.Items))
+	return nil
+}
+
+// DeleteOrder deletes an order by its ID as long as it is still at the expected version, its items and history go with it
+func (o *OrderStorage) DeleteOrder(ctx context.Context, orderID string, version int64) error {
+	o.logger.Info("deleting order", "orderID", orderID, "version", version)
+
+	id, err := parseID(orderID, "order")
+	if err != nil {
+		o.logger.Error("invalid order ID format", "error", err)
+		return err
+	}
+
+	result, err := conn(ctx, o.db).ExecContext(ctx, `DELETE FROM orders WHERE id = $1 AND (version = $2 OR $2 = 0)`, id, version)
+	if err != nil {
+		o.logger.Error("failed to delete order", "error", err)
+		return fmt.Errorf("failed to delete order: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		err := missingOrStale(ctx, conn(ctx, o.db), "orders", "order", id)
+		if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrPreconditionFailed) {
+			o.logger.Warn("order not deleted", "orderID", orderID, "expected", version, "reason", err)
+		} else {
+			o.logger.Error("failed to check order existence", "error", err)
+		}
+		return err
+	}
+
+	o.logger.Info("order deleted successfully", "orderID", orderID)
+	return nil
+}
+
+// TransitionOrderStatus moves an order from one status to another and records the change in its history.
+// The update only applies while the order is still in the from status, so concurrent transitions can't both win.
+func (o *OrderStorage) TransitionOrderStatus(ctx context.Context, orderID, from, to string) (*models.Order, error) {
+	o.logger.Info("transitioning order status", "orderID", orderID, "from", from, "to", to)
+
+	id, err := parseID(orderID, "order")
+	if err != nil {
+		o.logger.Error("invalid order ID format", "error", err)
+		return nil, err
+	}
+
+	var order *models.Order
+	err = inTx(ctx, o.db, func(tx *sql.Tx) error {
+		changedAt := o.dialect.Timestamp(now())
+		result, err := tx.ExecContext(ctx, `UPDATE orders SET status = $3, version = version + 1, updated_at = $4 WHERE id = $1 AND status = $2`, id, from, to, changedAt)
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			// Tell a missing order apart from one whose status moved on in the meantime
+			var exists bool
+			if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)`, id).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return errs.NotFound("order not found")
+			}
+			return errs.Conflict("order status was changed concurrently, try again")
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO order_status_history (order_id, position, from_status, to_status, changed_at)
+			SELECT $1, COALESCE(MAX(position) + 1, 0), $2, $3, $4 FROM order_status_history WHERE order_id = $1`,
+			id, from, to, changedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		order, err = getOrder(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrConflict) {
+			o.logger.Warn("order status not transitioned", "orderID", orderID, "expected", from, "reason", err)
+			return nil, err
+		}
+		o.logger.Error("failed to transition order status", "error", err)
+		return nil, fmt.Errorf("failed to transition order status: %w", err)
+	}
+
+	o.logger.Info("order status transitioned successfully", "orderID", orderID, "status", to)
+	return order, nil
+}
+
+// ListOrders fetches all orders from the database with pagination, newest first
+func (o *OrderStorage) ListOrders(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Order], error) {
+	o.logger.Info("fetching all orders with pagination")
+
+	page, err := o.find(ctx, listing{}, cursor.NewestFirst, pagination)
+	if err != nil {
+		o.logger.Error("failed to fetch orders from database", "error", err)
+		return nil, err
+	}
+
+	o.logger.Info("successfully fetched orders with pagination", "orderCount", len(page.Items))
+	return page, nil
+}
+
+// ListOrdersByUser fetches the orders placed by a single user with pagination, newest first
+func (o *OrderStorage) ListOrdersByUser(ctx context.Context, userID string, pagination *models.Pagination) (*models.Page[models.Order], error) {
+	o.logger.Info("fetching orders of user", "userID", userID)
+
+	id, err := parseID(userID, "user")
+	if err != nil {
+		o.logger.Error("invalid user ID format", "error", err)
+		return nil, err
+	}
+
+	var search listing
+	search.where = "user_id = " + search.args.add(id)
+
+	page, err := o.find(ctx, search, cursor.NewestFirst, pagination)
+	if err != nil {
+		o.logger.Error("failed to fetch orders from database", "error", err)
+		return nil, err
+	}
+
+	o.logger.Info("successfully fetched orders of user", "userID", userID, "orderCount", len(page.Items))
+	return page, nil
+}
+
+// ListOrdersWithUsers fetches orders together with the name of the user who placed them, newest first
+func (o *OrderStorage) ListOrdersWithUsers(ctx context.Context, pagination *models.Pagination) (*models.Page[models.OrderWithUser], error) {
+	o.logger.Info("fetching orders with users", "page", pagination.Page, "pageSize", pagination.PageSize)
+
+	page := &models.Page[models.OrderWithUser]{
+		Items:    []models.OrderWithUser{},
+		Page:     pagination.Page,
+		PageSize: pagination.PageSize,
+	}
+	if err := conn(ctx, o.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM orders`).Scan(&page.Total); err != nil {
+		o.logger.Error("failed to count orders", "error", err)
+		return nil, fmt.Errorf("failed to fetch orders with users: %w", err)
+	}
+
+	// Orders of deleted users keep an empty name
+	var queryArgs args
+	rows, err := conn(ctx, o.db).QueryContext(ctx, `
+		SELECT o.id, o.user_id, COALESCE(u.name, ''), o.status, o.total, o.currency, o.created_at
+		FROM orders o LEFT JOIN users u ON u.id = o.user_id
+		ORDER BY o.created_at DESC, o.id DESC `+offset(&queryArgs, pagination),
+		queryArgs...,
+	)
+	if err != nil {
+		o.logger.Error("failed to query orders with users", "error", err)
+		return nil, fmt.Errorf("failed to fetch orders with users: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []models.ID
+	for rows.Next() {
+		var (
+			item      models.OrderWithUser
+			id        models.ID
+			userID    models.ID
+			createdAt primitive.DateTime
+		)
+		if err := rows.Scan(&id, &userID, &item.UserName, &item.Status, &item.Total, &item.Currency, timeOf(&createdAt)); err != nil {
+			o.logger.Error("failed to decode orders with users", "error", err)
+			return nil, fmt.Errorf("failed to decode orders with users: %w", err)
+		}
+		item.OrderID, item.UserID, item.CreatedAt = id.Hex(), userID.Hex(), createdAt.Time().UTC()
+		page.Items = append(page.Items, item)
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch orders with users: %w", err)
+	}
+
+	items, err := loadOrderItems(ctx, conn(ctx, o.db), ids)
+	if err != nil {
+		o.logger.Error("failed to fetch order items", "error", err)
+		return nil, fmt.Errorf("failed to fetch orders with users: %w", err)
+	}
+	for i, id := range ids {
+		page.Items[i].Items = items[id]
+	}
+	page.HasNext = int64(max(pagination.Page-1, 0)*pagination.PageSize+len(page.Items)) < page.Total
+
+	o.logger.Info("successfully fetched orders with users", "orderCount", len(page.Items), "total", page.Total)
+	return page, nil
+}
+
+// ListOrdersByDateRange fetches the orders created between startDate and endDate inclusive, sorted by creation time
+func (o *OrderStorage) ListOrdersByDateRange(ctx context.Context, order int8, pagination *models.Pagination, startDate, endDate time.Time) (*models.Page[models.Order], error) {
+	var search listing
+	search.where = "created_at BETWEEN " + search.args.add(o.dialect.Timestamp(startDate)) + " AND " + search.args.add(o.dialect.Timestamp(endDate))
+
+	page, err := o.find(ctx, search, cursor.OldestFirst.WithDirection(order), pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) == 0 {
+		return nil, errs.NotFound("no orders found in the given date range")
+	}
+
+	return page, nil
+}
+
+// find pages through the orders matching the condition of search
+func (o *OrderStorage) find(ctx context.Context, search listing, order cursor.Order, pagination *models.Pagination) (*models.Page[models.Order], error) {
+	search.columns, search.from, search.keys = orderColumns, "orders", orderKeys
+
+	page, err := findPage(ctx, conn(ctx, o.db), o.dialect, search, order, pagination, scanOrder, orderKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadOrderDetails(ctx, conn(ctx, o.db), page.Items); err != nil {
+		return nil, fmt.Errorf("failed to fetch order details: %w", err)
+	}
+	return page, nil
+}
+
+// getOrder reads a single order with its items, discounts and history, sql.ErrNoRows means there is no such order
+func getOrder(ctx context.Context, q querier, id models.ID) (*models.Order, error) {
+	order, err := scanOrder(q.QueryRowContext(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	orders := []models.Order{order}
+	if err := loadOrderDetails(ctx, q, orders); err != nil {
+		return nil, err
+	}
+	return &orders[0], nil
+}
+
+func insertOrderItems(ctx context.Context, tx *sql.Tx, orderID models.ID, items []models.OrderItem) error {
+	for position, item := range items {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO order_items (order_id, position, product_id, quantity, unit_price, line_total, price_currency, exchange_rate, tax_class) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			orderID, position, item.ProductID, item.Quantity, item.UnitPrice, item.LineTotal, item.PriceCurrency, item.ExchangeRate, item.TaxClass,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertOrderDiscounts(ctx context.Context, tx *sql.Tx, orderID models.ID, discounts []models.AppliedDiscount) error {
+	for position, discount := range discounts {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO order_discounts (order_id, position, promotion_id, code, type, amount) VALUES ($1, $2, $3, $4, $5, $6)`,
+			orderID, position, discount.PromotionID, discount.Code, discount.Type, discount.Amount,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertOrderTaxes(ctx context.Context, tx *sql.Tx, orderID models.ID, taxes []models.TaxLine) error {
+	for position, line := range taxes {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO order_taxes (order_id, position, tax_class, rate, net, tax) VALUES ($1, $2, $3, $4, $5, $6)`,
+			orderID, position, line.TaxClass, line.Rate, line.Net, line.Tax,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadOrderDetails fills in the items, the discounts, the taxes and the status history of orders
+func loadOrderDetails(ctx context.Context, q querier, orders []models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]models.ID, len(orders))
+	for i := range orders {
+		ids[i] = orders[i].ID
+	}
+
+	items, err := loadOrderItems(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	discounts, err := loadOrderDiscounts(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	taxes, err := loadOrderTaxes(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	var queryArgs args
+	rows, err := q.QueryContext(ctx,
+		`SELECT order_id, from_status, to_status, changed_at FROM order_status_history WHERE order_id IN `+queryArgs.in(anySlice(ids))+` ORDER BY order_id, position`,
+		queryArgs...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	history := make(map[models.ID][]models.StatusChange)
+	for rows.Next() {
+		var (
+			orderID models.ID
+			change  models.StatusChange
+		)
+		if err := rows.Scan(&orderID, &change.From, &change.To, timeOf(&change.At)); err != nil {
+			return err
+		}
+		history[orderID] = append(history[orderID], change)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range orders {
+		if lines, ok := items[orders[i].ID]; ok {
+			orders[i].Items = lines
+		}
+		if applied, ok := discounts[orders[i].ID]; ok {
+			orders[i].Discounts = applied
+		}
+		if lines, ok := taxes[orders[i].ID]; ok {
+			orders[i].Taxes = lines
+		}
+		if changes, ok := history[orders[i].ID]; ok {
+			orders[i].StatusHistory = changes
+		}
+	}
+	return nil
+}
+
+// loadOrderItems reads the items of the given orders, in the order they were placed
+func loadOrderItems(ctx context.Context, q querier, ids []models.ID) (map[models.ID][]models.OrderItem, error) {
+	items := make(map[models.ID][]models.OrderItem, len(ids))
+	if len(ids) == 0 {
+		return items, nil
+	}
+
+	var queryArgs args
+	rows, err := q.QueryContext(ctx,
+		`SELECT order_id, product_id, quantity, unit_price, line_total, price_currency, exchange_rate, tax_class FROM order_items WHERE order_id IN `+queryArgs.in(anySlice(ids))+` ORDER BY order_id, position`,
+		queryArgs...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			orderID models.ID
+			item    models.OrderItem
+		)
+		if err := rows.Scan(&orderID, &item.ProductID, &item.Quantity, &item.UnitPrice, &item.LineTotal, &item.PriceCurrency, &item.ExchangeRate, &item.TaxClass); err != nil {
+			return nil, err
+		}
+		items[orderID] = append(items[orderID], item)
+	}
+	return items, rows.Err()
+}
+
+// loadOrderDiscounts reads the discounts applied to the given orders, in the order they were applied
+func loadOrderDiscounts(ctx context.Context, q querier, ids []models.ID) (map[models.ID][]models.AppliedDiscount, error) {
+	var queryArgs args
+	rows, err := q.QueryContext(ctx,
+		`SELECT order_id, promotion_id, code, type, amount FROM order_discounts WHERE order_id IN `+queryArgs.in(anySlice(ids))+` ORDER BY order_id, position`,
+		queryArgs...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	discounts := make(map[models.ID][]models.AppliedDiscount, len(ids))
+	for rows.Next() {
+		var (
+			orderID  models.ID
+			discount models.AppliedDiscount
+		)
+		if err := rows.Scan(&orderID, &discount.PromotionID, &discount.Code, &discount.Type, &discount.Amount); err != nil {
+			return nil, err
+		}
+		discounts[orderID] = append(discounts[orderID], discount)
+	}
+	return discounts, rows.Err()
+}
+
+// loadOrderTaxes reads the tax breakdown of the given orders, class by class
+func loadOrderTaxes(ctx context.Context, q querier, ids []models.ID) (map[models.ID][]models.TaxLine, error) {
+	var queryArgs args
+	rows, err := q.QueryContext(ctx,
+		`SELECT order_id, tax_class, rate, net, tax FROM order_taxes WHERE order_id IN `+queryArgs.in(anySlice(ids))+` ORDER BY order_id, position`,
+		queryArgs...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taxes := make(map[models.ID][]models.TaxLine, len(ids))
+	for rows.Next() {
+		var (
+			orderID models.ID
+			line    models.TaxLine
+		)
+		if err := rows.Scan(&orderID, &line.TaxClass, &line.Rate, &line.Net, &line.Tax); err != nil {
+			return nil, err
+		}
+		taxes[orderID] = append(taxes[orderID], line)
+	}
+	return taxes, rows.Err()
+}
+
+func anySlice[T any](values []T) []any {
+	result := make([]any, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+)
+
+// listing describes the rows a paginated query selects from
+type listing struct {
+	columns string            // Selected columns
+	from    string            // Table with an id column
+	where   string            // Condition using the placeholders of args, empty for all rows
+	args    args              // Arguments of the condition
+	keys    map[string]string // Column of every sort key
+}
+
+// findPage reads one page of a listing in the given order. With a cursor the page starts right
+// after (or before) the row the cursor was issued for, otherwise page and page size select it by offset.
+func findPage[T any](ctx context.Context, db querier, dialect Dialect, l listing, order cursor.Order, pagination *models.Pagination, scan func(scanner) (T, error), keyOf func(*T) cursor.Cursor) (*models.Page[T], error) {
+	from, direction, err := order.Resume(pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	where := l.where
+	if where == "" {
+		where = "1 = 1"
+	}
+
+	var total int64
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+l.from+" WHERE "+where, l.args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+
+	key := l.keys[order.Key]
+	dir, op := "ASC", ">"
+	if direction < 0 {
+		dir, op = "DESC", "<"
+	}
+
+	queryArgs := append(args{}, l.args...)
+	query := "SELECT " + l.columns + " FROM " + l.from + " WHERE " + where
+	if from != nil {
+		if _, err := models.ParseID(from.ID); err != nil {
+			return nil, cursor.ErrInvalid
+		}
+
+		var value any = dialect.Timestamp(from.CreatedAt)
+		if order.Key == cursor.KeyPrice {
+			value = from.Price
+		}
+		query += fmt.Sprintf(" AND (%s, id) %s (%s, %s)", key, op, queryArgs.add(value), queryArgs.add(from.ID))
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", key, dir, dir, queryArgs.add(pagination.PageSize+1))
+	if from == nil {
+		query += " OFFSET " + queryArgs.add(max(pagination.Page-1, 0)*pagination.PageSize)
+	}
+
+	rows, err := db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode row: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cursor.NewPage(items, total, from, order, pagination, keyOf), nil
+}
+
+// offset returns the LIMIT and OFFSET clause of a page selected by number
+func offset(a *args, pagination *models.Pagination) string {
+	return "LIMIT " + a.add(pagination.PageSize) + " OFFSET " + a.add(max(pagination.Page-1, 0)*pagination.PageSize)
+}
+
+// Sort key columns of the listings
+var (
+	productKeys   = map[string]string{cursor.KeyCreatedAt: "created_at", cursor.KeyPrice: "price"}
+	orderKeys     = map[string]string{cursor.KeyCreatedAt: "created_at", cursor.KeyPrice: "total"}
+	userKeys      = map[string]string{cursor.KeyCreatedAt: "created_at"}
+	promotionKeys = map[string]string{cursor.KeyCreatedAt: "created_at"}
+)
+
+// productKey, orderKey, userKey and promotionKey position a product, an order, a user or a promotion within a listing
+func productKey(p *models.Product) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: p.CreatedAt.Time(), Price: p.Price, ID: p.ID.Hex()}
+}
+
+func orderKey(o *models.Order) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: o.CreatedAt.Time(), Price: o.Total, ID: o.ID.Hex()}
+}
+
+func userKey(u *models.User) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: u.CreatedAt.Time(), ID: u.ID.Hex()}
+}
+
+func promotionKey(p *models.Promotion) cursor.Cursor {
+	return cursor.Cursor{CreatedAt: p.CreatedAt.Time(), ID: p.ID.Hex()}
+}
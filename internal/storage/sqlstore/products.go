@@ -0,0 +1,322 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+)
+
+const productColumns = "id, name, description, price, currency, tax_class, stock, version, created_at, updated_at"
+
+type ProductStorage struct {
+	db      *sql.DB
+	dialect Dialect
+	logger  *slog.Logger
+	cfg     *config.Config
+}
+
+func NewProductStorage(db *sql.DB, dialect Dialect, logger *slog.Logger, cfg *config.Config) repos.ProductRepo {
+	return &ProductStorage{
+		db:      db,
+		dialect: dialect,
+		logger:  logger,
+		cfg:     cfg,
+	}
+}
+
+func scanProduct(row scanner) (models.Product, error) {
+	var product models.Product
+	err := row.Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.Currency, &product.TaxClass, &product.Stock, &product.Version, timeOf(&product.CreatedAt), timeOf(&product.UpdatedAt))
+	return product, err
+}
+
+// CreateProduct creates a new product in the database
+func (p *ProductStorage) CreateProduct(ctx context.Context, product *models.ProductCreate) (string, error) {
+	p.logger.Info("starting product creation", "name", product.Name)
+
+	id, created_at := models.NewID(), p.dialect.Timestamp(now())
+	_, err := conn(ctx, p.db).ExecContext(ctx,
+		`INSERT INTO products (id, name, description, price, currency, tax_class, stock, version, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, 1, $8, $8)`,
+		id, product.Name, product.Description, product.Price, product.Currency, product.TaxClass, product.Stock, created_at,
+	)
+	if err != nil {
+		p.logger.Error("failed to insert product", "error", err)
+		return "", fmt.Errorf("failed to insert product: %w", err)
+	}
+
+	p.logger.Info("product creation successful", "productID", id.Hex())
+	return id.Hex(), nil
+}
+
+// GetProductByID fetches a product by its ID
+func (p *ProductStorage) GetProductByID(ctx context.Context, productID string) (*models.Product, error) {
+	p.logger.Info("fetching product by ID", "productID", productID)
+
+	id, err := parseID(productID, "product")
+	if err != nil {
+		p.logger.Error("invalid product ID format", "error", err)
+		return nil, err
+	}
+
+	product, err := scanProduct(conn(ctx, p.db).QueryRowContext(ctx, `SELECT `+productColumns+` FROM products WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			p.logger.Warn("product not found", "productID", productID)
+			return nil, errs.NotFound("product not found")
+		}
+		p.logger.Error("failed to fetch product from database", "error", err)
+		return nil, fmt.Errorf("failed to fetch product: %w", err)
+	}
+
+	p.logger.Info("successfully fetched product", "productID", productID)
+	return &product, nil
+}
+
+// UpdateProduct updates a product in the database, as long as it is still at the expected version
+func (p *ProductStorage) UpdateProduct(ctx context.Context, productID string, version int64, updates *models.ProductUpdate) error {
+	p.logger.Info("updating product", "productID", productID, "version", version)
+
+	id, err := parseID(productID, "product")
+	if err != nil {
+		p.logger.Error("invalid product ID format", "error", err)
+		return err
+	}
+
+	// The expected version only has to match when it isn't models.AnyVersion
+	result, err := conn(ctx, p.db).ExecContext(ctx, `
+		UPDATE products SET name = $2, description = $3, price = $4, currency = $5, tax_class = $6, stock = $7, version = version + 1, updated_at = $8
+		WHERE id = $1 AND (version = $9 OR $9 = 0)`,
+		id, updates.Name, updates.Description, updates.Price, updates.Currency, updates.TaxClass, updates.Stock, p.dialect.Timestamp(now()), version,
+	)
+	if err != nil {
+		p.logger.Error("failed to update product", "error", err)
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return p.missingOrStale(ctx, id, productID, version)
+	}
+
+	p.logger.Info("product updated successfully", "productID", productID, "updatedFields", updates)
+	return nil
+}
+
+// PatchProduct sets the fields present in the patch, as long as the product is still at the expected version
+func (p *ProductStorage) PatchProduct(ctx context.Context, productID string, version int64, patch *models.ProductPatch) error {
+	p.logger.Info("patching product", "productID", productID, "version", version)
+
+	id, err := parseID(productID, "product")
+	if err != nil {
+		p.logger.Error("invalid product ID format", "error", err)
+		return err
+	}
+
+	queryArgs := args{id}
+	set := []string{"version = version + 1", "updated_at = " + queryArgs.add(p.dialect.Timestamp(now()))}
+	if patch.Name != nil {
+		set = append(set, "name = "+queryArgs.add(*patch.Name))
+	}
+	if patch.Description != nil {
+		set = append(set, "description = "+queryArgs.add(*patch.Description))
+	}
+	if patch.Price != nil {
+		set = append(set, "price = "+queryArgs.add(*patch.Price))
+	}
+	if patch.Currency != nil {
+		set = append(set, "currency = "+queryArgs.add(*patch.Currency))
+	}
+	if patch.TaxClass != nil {
+		set = append(set, "tax_class = "+queryArgs.add(*patch.TaxClass))
+	}
+	if patch.Stock != nil {
+		set = append(set, "stock = "+queryArgs.add(*patch.Stock))
+	}
+	expected := queryArgs.add(version)
+
+	result, err := conn(ctx, p.db).ExecContext(ctx,
+		`UPDATE products SET `+strings.Join(set, ", ")+` WHERE id = $1 AND (version = `+expected+` OR `+expected+` = 0)`,
+		queryArgs...,
+	)
+	if err != nil {
+		p.logger.Error("failed to patch product", "error", err)
+		return fmt.Errorf("failed to patch product: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return p.missingOrStale(ctx, id, productID, version)
+	}
+
+	p.logger.Info("product patched successfully", "productID", productID, "fields", len(set)-2)
+	return nil
+}
+
+// DeleteProduct deletes a product by its ID, as long as it is still at the expected version
+func (p *ProductStorage) DeleteProduct(ctx context.Context, productID string, version int64) error {
+	p.logger.Info("deleting product", "productID", productID, "version", version)
+
+	id, err := parseID(productID, "product")
+	if err != nil {
+		p.logger.Error("invalid product ID format", "error", err)
+		return err
+	}
+
+	result, err := conn(ctx, p.db).ExecContext(ctx, `DELETE FROM products WHERE id = $1 AND (version = $2 OR $2 = 0)`, id, version)
+	if err != nil {
+		p.logger.Error("failed to delete product", "error", err)
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return p.missingOrStale(ctx, id, productID, version)
+	}
+
+	p.logger.Info("product deleted successfully", "productID", productID)
+	return nil
+}
+
+// missingOrStale tells why a conditional write matched no product
+func (p *ProductStorage) missingOrStale(ctx context.Context, id models.ID, productID string, version int64) error {
+	err := missingOrStale(ctx, conn(ctx, p.db), "products", "product", id)
+	switch {
+	case errors.Is(err, errs.ErrNotFound):
+		p.logger.Warn("product not found", "productID", productID)
+	case errors.Is(err, errs.ErrPreconditionFailed):
+		p.logger.Warn("product version mismatch", "productID", productID, "expected", version)
+	default:
+		p.logger.Error("failed to check product existence", "error", err)
+	}
+	return err
+}
+
+// ReserveStock atomically takes quantity units out of the product stock.
+// The update only matches while enough units are left, so concurrent orders can't oversell.
+// Stock movements leave the version alone, it only guards the fields admins edit.
+func (p *ProductStorage) ReserveStock(ctx context.Context, productID string, quantity int) error {
+	p.logger.Info("reserving stock", "productID", productID, "quantity", quantity)
+
+	id, err := parseID(productID, "product")
+	if err != nil {
+		p.logger.Error("invalid product ID format", "error", err)
+		return err
+	}
+
+	result, err := conn(ctx, p.db).ExecContext(ctx,
+		`UPDATE products SET stock = stock - $2, updated_at = $3 WHERE id = $1 AND stock >= $2`,
+		id, quantity, p.dialect.Timestamp(now()),
+	)
+	if err != nil {
+		p.logger.Error("failed to reserve stock", "error", err)
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		// Tell a missing product apart from one that ran out of stock
+		var exists bool
+		if err := conn(ctx, p.db).QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, id).Scan(&exists); err != nil {
+			p.logger.Error("failed to check product existence", "error", err)
+			return fmt.Errorf("failed to check product existence: %w", err)
+		}
+		if !exists {
+			p.logger.Warn("product not found", "productID", productID)
+			return errs.NotFound("product not found")
+		}
+
+		p.logger.Warn("insufficient stock", "productID", productID, "quantity", quantity)
+		return &errs.InsufficientStockError{ProductID: productID, Requested: quantity}
+	}
+
+	p.logger.Info("stock reserved successfully", "productID", productID, "quantity", quantity)
+	return nil
+}
+
+// ReleaseStock puts quantity previously reserved units back into the product stock
+func (p *ProductStorage) ReleaseStock(ctx context.Context, productID string, quantity int) error {
+	p.logger.Info("releasing stock", "productID", productID, "quantity", quantity)
+
+	id, err := parseID(productID, "product")
+	if err != nil {
+		p.logger.Error("invalid product ID format", "error", err)
+		return err
+	}
+
+	result, err := conn(ctx, p.db).ExecContext(ctx, `UPDATE products SET stock = stock + $2, updated_at = $3 WHERE id = $1`, id, quantity, p.dialect.Timestamp(now()))
+	if err != nil {
+		p.logger.Error("failed to release stock", "error", err)
+		return fmt.Errorf("failed to release stock: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		p.logger.Warn("product not found", "productID", productID)
+		return errs.NotFound("product not found")
+	}
+
+	p.logger.Info("stock released successfully", "productID", productID, "quantity", quantity)
+	return nil
+}
+
+// ListProducts fetches a paginated list of products from the database, oldest first
+func (p *ProductStorage) ListProducts(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	p.logger.Info("fetching list of products", "page", pagination.Page, "pageSize", pagination.PageSize, "cursor", pagination.Cursor)
+
+	page, err := p.find(ctx, listing{}, cursor.OldestFirst, pagination)
+	if err != nil {
+		p.logger.Error("failed to fetch products from database", "error", err)
+		return nil, err
+	}
+
+	p.logger.Info("successfully fetched products", "productCount", len(page.Items))
+	return page, nil
+}
+
+// SearchProductsByName searches for products whose name matches a case-insensitive regular expression
+func (p *ProductStorage) SearchProductsByName(ctx context.Context, name string, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	var search listing
+	where, err := p.dialect.MatchesPattern("name", name, search.args.add)
+	if err != nil {
+		return nil, err
+	}
+	search.where = where
+
+	page, err := p.find(ctx, search, cursor.OldestFirst, pagination)
+	if err != nil {
+		if p.dialect.IsInvalidPattern(err) {
+			return nil, errs.Validation("invalid search pattern")
+		}
+		p.logger.Error("failed to search products from database", "error", err)
+		return nil, err
+	}
+
+	p.logger.Info("successfully fetched search results", "productCount", len(page.Items))
+	return page, nil
+}
+
+// ExactSearchProductsByPrice searches for products priced in currency by price with pagination, newest first
+func (p *ProductStorage) ExactSearchProductsByPrice(ctx context.Context, price models.Money, currency string, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	var search listing
+	search.where = "currency = " + search.args.add(currency) + " AND price = " + search.args.add(price)
+
+	return p.find(ctx, search, cursor.NewestFirst, pagination)
+}
+
+// SearchProductsByPriceRange searches for products priced in currency between minPrice and maxPrice inclusive, sorted by price
+func (p *ProductStorage) SearchProductsByPriceRange(ctx context.Context, order int8, minPrice, maxPrice models.Money, currency string, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	var search listing
+	search.where = "currency = " + search.args.add(currency) + " AND price BETWEEN " + search.args.add(minPrice) + " AND " + search.args.add(maxPrice)
+
+	return p.find(ctx, search, cursor.CheapestFirst.WithDirection(order), pagination)
+}
+
+// find pages through the products matching the condition of search
+func (p *ProductStorage) find(ctx context.Context, search listing, order cursor.Order, pagination *models.Pagination) (*models.Page[models.Product], error) {
+	search.columns, search.from, search.keys = productColumns, "products", productKeys
+	return findPage(ctx, conn(ctx, p.db), p.dialect, search, order, pagination, scanProduct, productKey)
+}
@@ -0,0 +1,263 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const promotionColumns = `id, code, description, type, percent, amount, product_id, buy_quantity, free_quantity, min_order_value, currency,
+	starts_at, ends_at, usage_limit, per_user_limit, usage_count, created_at, updated_at`
+
+type PromotionStorage struct {
+	db      *sql.DB
+	dialect Dialect
+	logger  *slog.Logger
+	cfg     *config.Config
+}
+
+func NewPromotionStorage(db *sql.DB, dialect Dialect, logger *slog.Logger, cfg *config.Config) repos.PromotionRepo {
+	return &PromotionStorage{
+		db:      db,
+		dialect: dialect,
+		logger:  logger,
+		cfg:     cfg,
+	}
+}
+
+// scanPromotion reads a promotion, one without a start or an end gets a zero time like the documents without the field
+func scanPromotion(row scanner) (models.Promotion, error) {
+	var promotion models.Promotion
+	err := row.Scan(
+		&promotion.ID, &promotion.Code, &promotion.Description, &promotion.Type, &promotion.Percent, &promotion.Amount,
+		&promotion.ProductID, &promotion.BuyQuantity, &promotion.FreeQuantity, &promotion.MinOrderValue, &promotion.Currency,
+		timeOf(&promotion.StartsAt), timeOf(&promotion.EndsAt), &promotion.UsageLimit, &promotion.PerUserLimit, &promotion.UsageCount,
+		timeOf(&promotion.CreatedAt), timeOf(&promotion.UpdatedAt),
+	)
+	return promotion, err
+}
+
+// CreatePromotion creates a new promotion, the code has to be unique
+func (p *PromotionStorage) CreatePromotion(ctx context.Context, promotion *models.PromotionCreate) (string, error) {
+	p.logger.Info("starting promotion creation", "code", promotion.Code)
+
+	id, created_at := models.NewID(), p.dialect.Timestamp(now())
+	_, err := conn(ctx, p.db).ExecContext(ctx, `
+		INSERT INTO promotions (id, code, description, type, percent, amount, product_id, buy_quantity, free_quantity, min_order_value, currency,
+			starts_at, ends_at, usage_limit, per_user_limit, usage_count, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, 0, $16, $16)`,
+		id, promotion.Code, promotion.Description, promotion.Type, promotion.Percent, promotion.Amount, promotion.ProductID,
+		promotion.BuyQuantity, promotion.FreeQuantity, promotion.MinOrderValue, promotion.Currency,
+		p.optionalTime(promotion.StartsAt), p.optionalTime(promotion.EndsAt), promotion.UsageLimit, promotion.PerUserLimit, created_at,
+	)
+	if err != nil {
+		if p.dialect.IsUniqueViolation(err) {
+			p.logger.Warn("promotion code already exists", "code", promotion.Code)
+			return "", errs.Conflict("promotion code already exists")
+		}
+		p.logger.Error("failed to insert promotion", "error", err)
+		return "", fmt.Errorf("failed to insert promotion: %w", err)
+	}
+
+	p.logger.Info("promotion creation successful", "promotionID", id.Hex())
+	return id.Hex(), nil
+}
+
+// GetPromotionByID fetches a promotion by its ID
+func (p *PromotionStorage) GetPromotionByID(ctx context.Context, promotionID string) (*models.Promotion, error) {
+	p.logger.Info("fetching promotion by ID", "promotionID", promotionID)
+
+	id, err := parseID(promotionID, "promotion")
+	if err != nil {
+		p.logger.Error("invalid promotion ID format", "error", err)
+		return nil, err
+	}
+
+	return p.get(ctx, "id", id)
+}
+
+// GetPromotionByCode fetches a promotion by its code
+func (p *PromotionStorage) GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	p.logger.Info("fetching promotion by code", "code", code)
+
+	return p.get(ctx, "code", code)
+}
+
+// DeletePromotion deletes a promotion by its ID, the orders it was applied to keep their discounts
+func (p *PromotionStorage) DeletePromotion(ctx context.Context, promotionID string) error {
+	p.logger.Info("deleting promotion", "promotionID", promotionID)
+
+	id, err := parseID(promotionID, "promotion")
+	if err != nil {
+		p.logger.Error("invalid promotion ID format", "error", err)
+		return err
+	}
+
+	result, err := conn(ctx, p.db).ExecContext(ctx, `DELETE FROM promotions WHERE id = $1`, id)
+	if err != nil {
+		p.logger.Error("failed to delete promotion", "error", err)
+		return fmt.Errorf("failed to delete promotion: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		p.logger.Warn("no promotion found to delete", "promotionID", promotionID)
+		return errs.NotFound("promotion not found")
+	}
+
+	p.logger.Info("promotion deleted successfully", "promotionID", promotionID)
+	return nil
+}
+
+// ListPromotions fetches a paginated list of promotions, oldest first
+func (p *PromotionStorage) ListPromotions(ctx context.Context, pagination *models.Pagination) (*models.Page[models.Promotion], error) {
+	p.logger.Info("fetching list of promotions", "page", pagination.Page, "pageSize", pagination.PageSize)
+
+	page, err := findPage(ctx, conn(ctx, p.db), p.dialect, listing{columns: promotionColumns, from: "promotions", keys: promotionKeys}, cursor.OldestFirst, pagination, scanPromotion, promotionKey)
+	if err != nil {
+		p.logger.Error("failed to fetch promotions from database", "error", err)
+		return nil, err
+	}
+
+	p.logger.Info("successfully fetched promotions", "promotionCount", len(page.Items))
+	return page, nil
+}
+
+// RedeemPromotion counts a use of the promotion by the user. The usage count only goes up while it is below
+// the usage limit and the count of the user only while it is below the per user limit, both in one transaction,
+// so concurrent orders can't use a promotion more often than allowed.
+func (p *PromotionStorage) RedeemPromotion(ctx context.Context, promotionID, userID string) error {
+	p.logger.Info("redeeming promotion", "promotionID", promotionID, "userID", userID)
+
+	id, err := parseID(promotionID, "promotion")
+	if err != nil {
+		p.logger.Error("invalid promotion ID format", "error", err)
+		return err
+	}
+	user, err := parseID(userID, "user")
+	if err != nil {
+		p.logger.Error("invalid user ID format", "error", err)
+		return err
+	}
+
+	err = inTx(ctx, p.db, func(tx *sql.Tx) error {
+		var perUserLimit int
+		err := tx.QueryRowContext(ctx,
+			`UPDATE promotions SET usage_count = usage_count + 1 WHERE id = $1 AND (usage_limit = 0 OR usage_count < usage_limit) RETURNING per_user_limit`,
+			id,
+		).Scan(&perUserLimit)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Tell a missing promotion apart from one that was used up
+			var exists bool
+			if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM promotions WHERE id = $1)`, id).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return errs.NotFound("promotion not found")
+			}
+			return errs.Conflict("promotion has reached its usage limit")
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO promotion_redemptions (promotion_id, user_id, count) VALUES ($1, $2, 1)
+			ON CONFLICT (promotion_id, user_id) DO UPDATE SET count = promotion_redemptions.count + 1
+			WHERE $3 = 0 OR promotion_redemptions.count < $3`,
+			id, user, perUserLimit,
+		)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return errs.Conflict("promotion has reached its usage limit for this user")
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrConflict) {
+			p.logger.Warn("promotion not redeemed", "promotionID", promotionID, "userID", userID, "reason", err)
+			return err
+		}
+		p.logger.Error("failed to redeem promotion", "error", err)
+		return fmt.Errorf("failed to redeem promotion: %w", err)
+	}
+
+	p.logger.Info("promotion redeemed successfully", "promotionID", promotionID, "userID", userID)
+	return nil
+}
+
+// ReleasePromotion takes back a use of the promotion by the user, the counts never go below zero
+func (p *PromotionStorage) ReleasePromotion(ctx context.Context, promotionID, userID string) error {
+	p.logger.Info("releasing promotion", "promotionID", promotionID, "userID", userID)
+
+	id, err := parseID(promotionID, "promotion")
+	if err != nil {
+		p.logger.Error("invalid promotion ID format", "error", err)
+		return err
+	}
+	user, err := parseID(userID, "user")
+	if err != nil {
+		p.logger.Error("invalid user ID format", "error", err)
+		return err
+	}
+
+	err = inTx(ctx, p.db, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM promotions WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return errs.NotFound("promotion not found")
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE promotions SET usage_count = usage_count - 1 WHERE id = $1 AND usage_count > 0`, id); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE promotion_redemptions SET count = count - 1 WHERE promotion_id = $1 AND user_id = $2 AND count > 0`, id, user)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			p.logger.Warn("promotion not found", "promotionID", promotionID)
+			return err
+		}
+		p.logger.Error("failed to release promotion", "error", err)
+		return fmt.Errorf("failed to release promotion: %w", err)
+	}
+
+	p.logger.Info("promotion released successfully", "promotionID", promotionID, "userID", userID)
+	return nil
+}
+
+// get fetches the promotion whose column holds value
+func (p *PromotionStorage) get(ctx context.Context, column string, value any) (*models.Promotion, error) {
+	promotion, err := scanPromotion(conn(ctx, p.db).QueryRowContext(ctx, `SELECT `+promotionColumns+` FROM promotions WHERE `+column+` = $1`, value))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			p.logger.Warn("promotion not found", column, value)
+			return nil, errs.NotFound("promotion not found")
+		}
+		p.logger.Error("failed to fetch promotion from database", "error", err)
+		return nil, fmt.Errorf("failed to fetch promotion: %w", err)
+	}
+
+	return &promotion, nil
+}
+
+// optionalTime stores a zero time as NULL
+func (p *PromotionStorage) optionalTime(t primitive.DateTime) any {
+	if t == 0 {
+		return nil
+	}
+	return p.dialect.Timestamp(t.Time())
+}
@@ -0,0 +1,151 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+)
+
+// salesCondition skips orders that never turned into revenue
+const salesCondition = "status NOT IN ('" + models.OrderStatusCancelled + "', '" + models.OrderStatusRefunded + "')"
+
+type ReportStorage struct {
+	db      *sql.DB
+	dialect Dialect
+	logger  *slog.Logger
+	cfg     *config.Config
+}
+
+func NewReportStorage(db *sql.DB, dialect Dialect, logger *slog.Logger, cfg *config.Config) repos.ReportRepo {
+	return &ReportStorage{
+		db:      db,
+		dialect: dialect,
+		logger:  logger,
+		cfg:     cfg,
+	}
+}
+
+// GetReport calculates the overall number of products, orders and the revenue with and without the tax per currency.
+// Cancelled and refunded orders don't count towards the sales figures.
+func (r *ReportStorage) GetReport(ctx context.Context) (*models.Report, error) {
+	r.logger.Info("building sales report")
+
+	q := conn(ctx, r.db)
+	report := &models.Report{Revenue: []models.Revenue{}}
+	if err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM products`).Scan(&report.TotalProducts); err != nil {
+		r.logger.Error("failed to count products", "error", err)
+		return nil, fmt.Errorf("failed to count products: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT currency, COUNT(*), `+r.dialect.Sum("total")+`, `+r.dialect.Sum("net")+`, `+r.dialect.Sum("tax")+`
+		FROM orders
+		WHERE `+salesCondition+`
+		GROUP BY currency
+		ORDER BY currency`,
+	)
+	if err != nil {
+		r.logger.Error("failed to aggregate orders", "error", err)
+		return nil, fmt.Errorf("failed to aggregate orders: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var revenue models.Revenue
+		if err := rows.Scan(&revenue.Currency, &revenue.TotalOrders, &revenue.TotalRevenue, &revenue.NetRevenue, &revenue.TotalTax); err != nil {
+			r.logger.Error("failed to decode order totals", "error", err)
+			return nil, fmt.Errorf("failed to decode order totals: %w", err)
+		}
+		report.TotalOrders += revenue.TotalOrders
+		report.Revenue = append(report.Revenue, revenue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to aggregate orders: %w", err)
+	}
+
+	r.logger.Info("successfully built sales report", "totalOrders", report.TotalOrders)
+	return report, nil
+}
+
+// GetTopProducts returns the best selling products per currency, ranked by units sold and then by revenue
+func (r *ReportStorage) GetTopProducts(ctx context.Context, limit int) ([]models.TopProduct, error) {
+	r.logger.Info("fetching top products", "limit", limit)
+
+	// Products that were deleted keep an empty name
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT i.product_id, COALESCE(MAX(p.name), ''), o.currency, SUM(i.quantity), `+r.dialect.Sum("i.line_total")+`
+		FROM order_items i
+		JOIN orders o ON o.id = i.order_id AND o.`+salesCondition+`
+		LEFT JOIN products p ON p.id = i.product_id
+		GROUP BY i.product_id, o.currency
+		ORDER BY 4 DESC, 5 DESC, 1, 3
+		LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		r.logger.Error("failed to aggregate top products", "error", err)
+		return nil, fmt.Errorf("failed to aggregate top products: %w", err)
+	}
+	defer rows.Close()
+
+	var products []models.TopProduct
+	for rows.Next() {
+		var (
+			product models.TopProduct
+			id      models.ID
+		)
+		if err := rows.Scan(&id, &product.Name, &product.Currency, &product.TotalSold, &product.TotalRevenue); err != nil {
+			r.logger.Error("failed to decode top products", "error", err)
+			return nil, fmt.Errorf("failed to decode top products: %w", err)
+		}
+		product.ProductID = id.Hex()
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to aggregate top products: %w", err)
+	}
+
+	r.logger.Info("successfully fetched top products", "productCount", len(products))
+	return products, nil
+}
+
+// GetDailyOrderAggregates groups the orders created between startDate and endDate by UTC day and currency
+func (r *ReportStorage) GetDailyOrderAggregates(ctx context.Context, startDate, endDate time.Time) ([]models.OrderAggregate, error) {
+	r.logger.Info("fetching daily order aggregates", "startDate", startDate, "endDate", endDate)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+r.dialect.Day("created_at")+` AS day, currency, COUNT(*), `+r.dialect.Sum("total")+`, `+r.dialect.Sum("net")+`, `+r.dialect.Sum("tax")+`
+		FROM orders
+		WHERE `+salesCondition+` AND created_at BETWEEN $1 AND $2
+		GROUP BY day, currency
+		ORDER BY day, currency`,
+		r.dialect.Timestamp(startDate), r.dialect.Timestamp(endDate),
+	)
+	if err != nil {
+		r.logger.Error("failed to aggregate daily orders", "error", err)
+		return nil, fmt.Errorf("failed to aggregate daily orders: %w", err)
+	}
+	defer rows.Close()
+
+	var aggregates []models.OrderAggregate
+	for rows.Next() {
+		var aggregate models.OrderAggregate
+		if err := rows.Scan(&aggregate.Date, &aggregate.Currency, &aggregate.TotalOrders, &aggregate.TotalRevenue, &aggregate.NetRevenue, &aggregate.TotalTax); err != nil {
+			r.logger.Error("failed to decode daily aggregates", "error", err)
+			return nil, fmt.Errorf("failed to decode daily aggregates: %w", err)
+		}
+		aggregates = append(aggregates, aggregate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to aggregate daily orders: %w", err)
+	}
+
+	r.logger.Info("successfully fetched daily order aggregates", "days", len(aggregates))
+	return aggregates, nil
+}
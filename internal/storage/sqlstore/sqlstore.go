@@ -0,0 +1,154 @@
+// Package sqlstore implements the repositories on top of a SQL database through database/sql.
+//
+// The PostgreSQL and SQLite backends share these repositories, each of them opens and migrates its database
+// and hands in a Dialect for the little SQL the two don't have in common. Queries are written with the $1, $2, ...
+// placeholders, the ON CONFLICT upserts and the RETURNING clauses both databases understand.
+// The repositories mirror the semantics of the MongoDB ones: the same errors, the same sort orders
+// and the same cursors.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Dialect is what sets the SQL of a database apart
+type Dialect interface {
+	// Timestamp converts a time into the value of a timestamp column
+	Timestamp(t time.Time) any
+	// Sum sums an integer expression into a value that scans into an int64
+	Sum(expr string) string
+	// Day formats a timestamp column as the UTC day it falls on, such as 2026-03-01
+	Day(column string) string
+	// MatchesPattern returns the condition of column matching the case-insensitive regular expression pattern,
+	// arg adds an argument to the query and returns its placeholder. Patterns the database would reject are
+	// reported here when it can tell up front, and by IsInvalidPattern when the query fails on them.
+	MatchesPattern(column, pattern string, arg func(value any) string) (string, error)
+	// IsInvalidPattern reports whether a query failed on a regular expression the database can't compile
+	IsInvalidPattern(err error) bool
+	// IsUniqueViolation reports whether a write failed on a unique constraint
+	IsUniqueViolation(err error) bool
+	// IsRetryable reports whether a transaction lost against a concurrent one and can simply run again
+	IsRetryable(err error) bool
+	// CartItemOrder is the column that orders the lines of a cart as they were added
+	CartItemOrder() string
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// txKey carries the transaction of a unit of work in its context, see Transactor
+type txKey struct{}
+
+// conn returns the transaction of the unit of work ctx belongs to, or db outside of one
+func conn(ctx context.Context, db *sql.DB) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// inTx runs fn in a transaction, committing it when fn succeeds and rolling it back otherwise.
+// Inside a unit of work fn runs in its transaction, which commits or rolls back as a whole.
+// fn must only use tx: with SQLite the transaction holds the only connection of the pool.
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op once committed
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// missingOrStale tells why a write conditional on the version of a row matched nothing: the row is gone
+// or its version moved on. table holds the row, what names the kind of row in the errors.
+func missingOrStale(ctx context.Context, q querier, table, what string, id models.ID) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check %s existence: %w", what, err)
+	}
+	if !exists {
+		return errs.NotFound(what + " not found")
+	}
+	return errs.PreconditionFailed(what + " was changed since it was read")
+}
+
+// parseID converts a hex ID like the MongoDB repositories do, what names the kind of ID in the error
+func parseID(id, what string) (models.ID, error) {
+	parsed, err := models.ParseID(id)
+	if err != nil {
+		return models.NilID, errs.InvalidID(fmt.Sprintf("invalid %s ID format", what), err)
+	}
+	return parsed, nil
+}
+
+// now returns the current time with the millisecond precision of the models
+func now() time.Time {
+	return time.Now().Truncate(time.Millisecond)
+}
+
+// timestamp scans a timestamp column into a primitive.DateTime. SQLite reads it as milliseconds since the epoch
+// and PostgreSQL as a time, NULL reads as the zero time like a document without the field.
+type timestamp struct {
+	dst *primitive.DateTime
+}
+
+func timeOf(dst *primitive.DateTime) timestamp {
+	return timestamp{dst}
+}
+
+func (t timestamp) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t.dst = 0
+	case int64:
+		*t.dst = primitive.DateTime(v)
+	case time.Time:
+		*t.dst = primitive.NewDateTimeFromTime(v)
+	default:
+		return fmt.Errorf("can't scan %T into a timestamp", src)
+	}
+	return nil
+}
+
+// args collects the arguments of a query and hands out their placeholders
+type args []any
+
+func (a *args) add(value any) string {
+	*a = append(*a, value)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// in returns the placeholders of an IN list holding values
+func (a *args) in(values []any) string {
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = a.add(value)
+	}
+	return "(" + strings.Join(placeholders, ", ") + ")"
+}
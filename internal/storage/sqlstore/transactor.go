@@ -0,0 +1,46 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+)
+
+// maxAttempts bounds how often a unit of work runs before its error is returned
+const maxAttempts = 3
+
+// Transactor runs units of work in database transactions. The transaction travels in the context,
+// the repositories pick it up through conn and inTx.
+type Transactor struct {
+	db      *sql.DB
+	dialect Dialect
+	logger  *slog.Logger
+}
+
+func NewTransactor(db *sql.DB, dialect Dialect, logger *slog.Logger) repos.Transactor {
+	return &Transactor{
+		db:      db,
+		dialect: dialect,
+		logger:  logger,
+	}
+}
+
+// WithTransaction runs fn in a transaction, running it again when it loses against a concurrent
+// transaction, a deadlock or a serialization failure in PostgreSQL
+func (t *Transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := inTx(ctx, t.db, func(tx *sql.Tx) error {
+			return fn(context.WithValue(ctx, txKey{}, tx))
+		})
+		if err == nil || attempt == maxAttempts || !t.dialect.IsRetryable(err) {
+			return err
+		}
+		t.logger.Warn("retrying transaction", "attempt", attempt+1, "error", err)
+	}
+}
@@ -0,0 +1,167 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/cursor"
+)
+
+const userColumns = "id, name, email, password_hash, role, created_at, updated_at"
+
+type UserStorage struct {
+	db      *sql.DB
+	dialect Dialect
+	logger  *slog.Logger
+	cfg     *config.Config
+}
+
+func NewUserStorage(db *sql.DB, dialect Dialect, logger *slog.Logger, cfg *config.Config) repos.UserRepo {
+	return &UserStorage{
+		db:      db,
+		dialect: dialect,
+		logger:  logger,
+		cfg:     cfg,
+	}
+}
+
+func scanUser(row scanner) (models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.Role, timeOf(&user.CreatedAt), timeOf(&user.UpdatedAt))
+	return user, err
+}
+
+// CreateUser creates a new user, the email address has to be unique
+func (u *UserStorage) CreateUser(ctx context.Context, passwordHash string, user *models.UserCreate) (string, error) {
+	u.logger.Info("starting user creation", "email", user.Email)
+
+	id, created_at := models.NewID(), u.dialect.Timestamp(now())
+	_, err := conn(ctx, u.db).ExecContext(ctx,
+		`INSERT INTO users (id, name, email, password_hash, role, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $6)`,
+		id, user.Name, user.Email, passwordHash, user.Role, created_at,
+	)
+	if err != nil {
+		if u.dialect.IsUniqueViolation(err) {
+			u.logger.Warn("email already registered", "email", user.Email)
+			return "", errs.Conflict("email is already registered")
+		}
+		u.logger.Error("failed to insert user", "error", err)
+		return "", fmt.Errorf("failed to insert user: %w", err)
+	}
+
+	u.logger.Info("user creation successful", "userID", id.Hex())
+	return id.Hex(), nil
+}
+
+// GetUserByID fetches a user by its ID
+func (u *UserStorage) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	u.logger.Info("fetching user by ID", "userID", userID)
+
+	id, err := parseID(userID, "user")
+	if err != nil {
+		u.logger.Error("invalid user ID format", "error", err)
+		return nil, err
+	}
+
+	return u.get(ctx, "id", id)
+}
+
+// GetUserByEmail fetches a user by its email address
+func (u *UserStorage) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	u.logger.Info("fetching user by email", "email", email)
+
+	return u.get(ctx, "email", email)
+}
+
+// UpdateUser changes the name and email of a user, and the role when one is given
+func (u *UserStorage) UpdateUser(ctx context.Context, userID string, updates *models.UserUpdate) error {
+	u.logger.Info("updating user", "userID", userID)
+
+	id, err := parseID(userID, "user")
+	if err != nil {
+		u.logger.Error("invalid user ID format", "error", err)
+		return err
+	}
+
+	result, err := conn(ctx, u.db).ExecContext(ctx,
+		`UPDATE users SET name = $2, email = $3, role = COALESCE(NULLIF($4, ''), role), updated_at = $5 WHERE id = $1`,
+		id, updates.Name, updates.Email, updates.Role, u.dialect.Timestamp(now()),
+	)
+	if err != nil {
+		if u.dialect.IsUniqueViolation(err) {
+			u.logger.Warn("email already registered", "email", updates.Email)
+			return errs.Conflict("email is already registered")
+		}
+		u.logger.Error("failed to update user", "error", err)
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		u.logger.Warn("no user found to update", "userID", userID)
+		return errs.NotFound("user not found")
+	}
+
+	u.logger.Info("user updated successfully", "userID", userID)
+	return nil
+}
+
+// DeleteUser deletes a user by its ID
+func (u *UserStorage) DeleteUser(ctx context.Context, userID string) error {
+	u.logger.Info("deleting user", "userID", userID)
+
+	id, err := parseID(userID, "user")
+	if err != nil {
+		u.logger.Error("invalid user ID format", "error", err)
+		return err
+	}
+
+	result, err := conn(ctx, u.db).ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		u.logger.Error("failed to delete user", "error", err)
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		u.logger.Warn("no user found to delete", "userID", userID)
+		return errs.NotFound("user not found")
+	}
+
+	u.logger.Info("user deleted successfully", "userID", userID)
+	return nil
+}
+
+// ListUsers fetches a paginated list of users, oldest first
+func (u *UserStorage) ListUsers(ctx context.Context, pagination *models.Pagination) (*models.Page[models.User], error) {
+	u.logger.Info("fetching list of users", "page", pagination.Page, "pageSize", pagination.PageSize)
+
+	page, err := findPage(ctx, conn(ctx, u.db), u.dialect, listing{columns: userColumns, from: "users", keys: userKeys}, cursor.OldestFirst, pagination, scanUser, userKey)
+	if err != nil {
+		u.logger.Error("failed to fetch users from database", "error", err)
+		return nil, err
+	}
+
+	u.logger.Info("successfully fetched users", "userCount", len(page.Items))
+	return page, nil
+}
+
+// get fetches the user whose column holds value
+func (u *UserStorage) get(ctx context.Context, column string, value any) (*models.User, error) {
+	user, err := scanUser(conn(ctx, u.db).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE `+column+` = $1`, value))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			u.logger.Warn("user not found", column, value)
+			return nil, errs.NotFound("user not found")
+		}
+		u.logger.Error("failed to fetch user from database", "error", err)
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	return &user, nil
+}
@@ -1,28 +1,85 @@
 package storage
 
 import (
+	"database/sql"
 	"log/slog"
 
 	"github.com/abdulazizax/udevslab-lesson3/internal/config"
 	"github.com/abdulazizax/udevslab-lesson3/internal/repos"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/memory"
 	"github.com/abdulazizax/udevslab-lesson3/internal/storage/mongodb"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/postgres"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/sqlite"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/sqlstore"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type StorageI interface {
 	ProductRepo() repos.ProductRepo
 	OrderRepo() repos.OrderRepo
+	ReportRepo() repos.ReportRepo
+	UserRepo() repos.UserRepo
+	CartRepo() repos.CartRepo
+	PromotionRepo() repos.PromotionRepo
+	Transactor() repos.Transactor
 }
 
 type Storage struct {
-	productRepo repos.ProductRepo
-	orderRepo   repos.OrderRepo
+	productRepo   repos.ProductRepo
+	orderRepo     repos.OrderRepo
+	reportRepo    repos.ReportRepo
+	userRepo      repos.UserRepo
+	cartRepo      repos.CartRepo
+	promotionRepo repos.PromotionRepo
+	transactor    repos.Transactor
 }
 
 func New(db *mongo.Database, cfg *config.Config, logger *slog.Logger) StorageI {
 	return &Storage{
-		productRepo: mongodb.NewProductStorage(db, logger, cfg),
-		orderRepo:   mongodb.NewOrderStorage(db, logger, cfg),
+		productRepo:   mongodb.NewProductStorage(db, logger, cfg),
+		orderRepo:     mongodb.NewOrderStorage(db, logger, cfg),
+		reportRepo:    mongodb.NewReportStorage(db, logger, cfg),
+		userRepo:      mongodb.NewUserStorage(db, logger, cfg),
+		cartRepo:      mongodb.NewCartStorage(db, logger, cfg),
+		promotionRepo: mongodb.NewPromotionStorage(db, logger, cfg),
+		transactor:    mongodb.NewTransactor(db, logger),
+	}
+}
+
+// NewPostgres creates a storage on top of a PostgreSQL database migrated with postgres.Migrate
+func NewPostgres(db *sql.DB, cfg *config.Config, logger *slog.Logger) StorageI {
+	return newSQL(db, postgres.Dialect{}, cfg, logger)
+}
+
+// NewSQLite creates a storage on top of a SQLite database opened with sqlite.ConnectDB
+func NewSQLite(db *sql.DB, cfg *config.Config, logger *slog.Logger) StorageI {
+	return newSQL(db, sqlite.Dialect{}, cfg, logger)
+}
+
+// newSQL creates a storage on top of the sqlstore repositories speaking dialect
+func newSQL(db *sql.DB, dialect sqlstore.Dialect, cfg *config.Config, logger *slog.Logger) StorageI {
+	return &Storage{
+		productRepo:   sqlstore.NewProductStorage(db, dialect, logger, cfg),
+		orderRepo:     sqlstore.NewOrderStorage(db, dialect, logger, cfg),
+		reportRepo:    sqlstore.NewReportStorage(db, dialect, logger, cfg),
+		userRepo:      sqlstore.NewUserStorage(db, dialect, logger, cfg),
+		cartRepo:      sqlstore.NewCartStorage(db, dialect, logger, cfg),
+		promotionRepo: sqlstore.NewPromotionStorage(db, dialect, logger, cfg),
+		transactor:    sqlstore.NewTransactor(db, dialect, logger),
+	}
+}
+
+// NewMemory creates a storage that keeps everything in memory, its data is lost when the process exits
+func NewMemory(cfg *config.Config, logger *slog.Logger) StorageI {
+	store := memory.NewStore()
+	return &Storage{
+		productRepo:   memory.NewProductStorage(store, logger, cfg),
+		orderRepo:     memory.NewOrderStorage(store, logger, cfg),
+		reportRepo:    memory.NewReportStorage(store, logger, cfg),
+		userRepo:      memory.NewUserStorage(store, logger, cfg),
+		cartRepo:      memory.NewCartStorage(store, logger, cfg),
+		promotionRepo: memory.NewPromotionStorage(store, logger, cfg),
+		transactor:    memory.NewTransactor(store, logger),
 	}
 }
 
@@ -33,3 +90,23 @@ func (s *Storage) ProductRepo() repos.ProductRepo {
 func (s *Storage) OrderRepo() repos.OrderRepo {
 	return s.orderRepo
 }
+
+func (s *Storage) ReportRepo() repos.ReportRepo {
+	return s.reportRepo
+}
+
+func (s *Storage) UserRepo() repos.UserRepo {
+	return s.userRepo
+}
+
+func (s *Storage) CartRepo() repos.CartRepo {
+	return s.cartRepo
+}
+
+func (s *Storage) PromotionRepo() repos.PromotionRepo {
+	return s.promotionRepo
+}
+
+func (s *Storage) Transactor() repos.Transactor {
+	return s.transactor
+}
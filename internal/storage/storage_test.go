@@ -0,0 +1,121 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/config"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/mongodb"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/postgres"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/sqlite"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage/storagetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	testConfig = &config.Config{Cart: config.CartConfig{TTL: time.Hour}}
+	testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+)
+
+func TestMemoryStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.StorageI {
+		return storage.NewMemory(testConfig, testLogger)
+	})
+}
+
+// TestSQLiteStorage runs against a fresh database file for every test
+func TestSQLiteStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.StorageI {
+		cfg := *testConfig
+		cfg.SQLite.Path = filepath.Join(t.TempDir(), "shop.db")
+
+		db, err := sqlite.ConnectDB(&cfg)
+		if err != nil {
+			t.Fatalf("failed to open SQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return storage.NewSQLite(db, &cfg, testLogger)
+	})
+}
+
+// TestMongoDBStorage runs against the server in TEST_MONGODB_URI, every test gets a database of its own
+func TestMongoDBStorage(t *testing.T) {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatalf("failed to ping MongoDB: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	var databases int
+	storagetest.Run(t, func(t *testing.T) storage.StorageI {
+		databases++
+		db := client.Database(fmt.Sprintf("storagetest_%d_%d", time.Now().UnixNano(), databases))
+		t.Cleanup(func() { db.Drop(context.Background()) })
+		if err := mongodb.EnsureSchema(context.Background(), db, testLogger); err != nil {
+			t.Fatalf("failed to prepare the collections: %v", err)
+		}
+
+		return storage.New(db, testConfig, testLogger)
+	})
+}
+
+// TestPostgresStorage runs against the database in TEST_POSTGRES_DSN, every test gets a schema of its own
+func TestPostgresStorage(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("failed to open PostgreSQL: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	var schemas int
+	storagetest.Run(t, func(t *testing.T) storage.StorageI {
+		schemas++
+		schema := fmt.Sprintf("storagetest_%d_%d", time.Now().UnixNano(), schemas)
+		if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+			t.Fatalf("failed to create schema: %v", err)
+		}
+		t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+		connConfig, err := pgx.ParseConfig(dsn)
+		if err != nil {
+			t.Fatalf("invalid TEST_POSTGRES_DSN: %v", err)
+		}
+		connConfig.RuntimeParams["search_path"] = schema
+
+		db := stdlib.OpenDB(*connConfig)
+		t.Cleanup(func() { db.Close() })
+		if err := postgres.Migrate(context.Background(), db); err != nil {
+			t.Fatalf("failed to migrate: %v", err)
+		}
+
+		return storage.NewPostgres(db, testConfig, testLogger)
+	})
+}
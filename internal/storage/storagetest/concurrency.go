@@ -0,0 +1,123 @@
+package storagetest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+)
+
+var concurrencyTests = []contractTest{
+	{"ReserveStock", testConcurrentReserveStock},
+	{"CreateOrders", testConcurrentCreateOrders},
+	{"TransitionOrderStatus", testConcurrentTransitions},
+}
+
+// parallel runs fn n times at once and returns the error of every call
+func parallel(n int, fn func(i int) error) []error {
+	results := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = fn(i)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func testConcurrentReserveStock(t *testing.T, s storage.StorageI) {
+	id := createProduct(t, s, "Laptop", 999, 10)
+
+	// More buyers than units, exactly as many of them as there are units may win
+	var reserved int
+	for _, err := range parallel(25, func(int) error { return s.ProductRepo().ReserveStock(ctx, id, 1) }) {
+		var insufficient *errs.InsufficientStockError
+		switch {
+		case err == nil:
+			reserved++
+		case !errors.As(err, &insufficient):
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if reserved != 10 {
+		t.Fatalf("reserved %d units, want 10", reserved)
+	}
+
+	product, err := s.ProductRepo().GetProductByID(ctx, id)
+	requireNoError(t, err)
+	if product.Stock != 0 {
+		t.Fatalf("got stock %d, want 0", product.Stock)
+	}
+}
+
+func testConcurrentCreateOrders(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	productID := createProduct(t, s, "Laptop", 999, 5)
+
+	order := models.Order{
+		UserID: mustParseID(t, userID),
+		Items:  []models.OrderItem{orderItem(t, productID, 1, 999)},
+		Total:  999,
+	}
+
+	ids := make([]string, 20)
+	for _, err := range parallel(len(ids), func(i int) error {
+		var err error
+		ids[i], err = s.OrderRepo().CreateOrder(ctx, &order)
+		return err
+	}) {
+		requireNoError(t, err)
+	}
+
+	got := walk(t, 7, orderIDs, func(p *models.Pagination) (*models.Page[models.Order], error) {
+		return s.OrderRepo().ListOrders(ctx, p)
+	})
+	seen := make(map[string]bool)
+	for _, id := range got {
+		seen[id] = true
+	}
+	for _, id := range ids {
+		if !seen[id] {
+			t.Fatalf("order %s is missing from the listing", id)
+		}
+	}
+	if len(got) != len(ids) {
+		t.Fatalf("listed %d orders, want %d", len(got), len(ids))
+	}
+}
+
+func testConcurrentTransitions(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	id := createOrder(t, s, userID, createProduct(t, s, "Laptop", 999, 5), 1, 999)
+
+	// Every request saw the order as pending, only one of them may move it on
+	var won int
+	for _, err := range parallel(10, func(int) error {
+		_, err := s.OrderRepo().TransitionOrderStatus(ctx, id, models.OrderStatusPending, models.OrderStatusPaid)
+		return err
+	}) {
+		switch {
+		case err == nil:
+			won++
+		case !errors.Is(err, errs.ErrConflict):
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if won != 1 {
+		t.Fatalf("%d transitions won, want 1", won)
+	}
+
+	order, err := s.OrderRepo().GetOrderByID(ctx, id)
+	requireNoError(t, err)
+	if len(order.StatusHistory) != 2 {
+		t.Fatalf("got history %+v, want a single transition", order.StatusHistory)
+	}
+}
@@ -0,0 +1,322 @@
+package storagetest
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+)
+
+var orderTests = []contractTest{
+	{"CreateAndGet", testCreateAndGetOrder},
+	{"CreateWithDiscounts", testCreateOrderWithDiscounts},
+	{"CreateWithTaxes", testCreateOrderWithTaxes},
+	{"Update", testUpdateOrder},
+	{"Delete", testDeleteOrder},
+	{"TransitionStatus", testTransitionOrderStatus},
+	{"InvalidIDs", testOrderInvalidIDs},
+	{"NotFound", testOrderNotFound},
+	{"ListNewestFirst", testListOrders},
+	{"ListByUser", testListOrdersByUser},
+	{"ListByDateRange", testListOrdersByDateRange},
+	{"ListWithUsers", testListOrdersWithUsers},
+}
+
+func testCreateAndGetOrder(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	productID := createProduct(t, s, "Laptop", 999, 5)
+
+	id := createOrder(t, s, userID, productID, 2, 999)
+
+	order, err := s.OrderRepo().GetOrderByID(ctx, id)
+	requireNoError(t, err)
+
+	if order.ID.Hex() != id || order.UserID.Hex() != userID || order.Total != 1998 || order.Currency != models.DefaultCurrency {
+		t.Fatalf("got order %+v", order)
+	}
+	if order.Subtotal != 1998 || order.Discount != 0 || order.Discounts == nil || len(order.Discounts) != 0 {
+		t.Fatalf("got subtotal %v with discounts %+v of %v", order.Subtotal, order.Discounts, order.Discount)
+	}
+	if !slices.Equal(order.Items, []models.OrderItem{orderItem(t, productID, 2, 999)}) {
+		t.Fatalf("got items %+v", order.Items)
+	}
+	// Every order starts as pending with that first step in its history
+	if order.Status != models.OrderStatusPending || len(order.StatusHistory) != 1 || order.StatusHistory[0].To != models.OrderStatusPending {
+		t.Fatalf("got status %q with history %+v", order.Status, order.StatusHistory)
+	}
+}
+
+func testCreateOrderWithDiscounts(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	productID := createProduct(t, s, "Laptop", 999, 5)
+
+	// Discounts outlive their promotions, so they are stored without one existing
+	discounts := []models.AppliedDiscount{
+		{PromotionID: models.NewID(), Code: "SPRING10", Type: models.PromotionPercentage, Amount: 200},
+		{PromotionID: models.NewID(), Code: "WELCOME", Type: models.PromotionFixedAmount, Amount: 500},
+	}
+	id, err := s.OrderRepo().CreateOrder(ctx, &models.Order{
+		UserID:    mustParseID(t, userID),
+		Items:     []models.OrderItem{orderItem(t, productID, 2, 999)},
+		Subtotal:  1998,
+		Discounts: discounts,
+		Discount:  700,
+		Total:     1298,
+		Currency:  models.DefaultCurrency,
+	})
+	requireNoError(t, err)
+
+	order, err := s.OrderRepo().GetOrderByID(ctx, id)
+	requireNoError(t, err)
+	if !slices.Equal(order.Discounts, discounts) || order.Subtotal != 1998 || order.Discount != 700 || order.Total != 1298 {
+		t.Fatalf("got subtotal %v, discounts %+v of %v and total %v", order.Subtotal, order.Discounts, order.Discount, order.Total)
+	}
+
+	// Listings load the discounts too
+	page, err := s.OrderRepo().ListOrdersByUser(ctx, userID, &models.Pagination{Page: 1, PageSize: 10})
+	requireNoError(t, err)
+	if len(page.Items) != 1 || !slices.Equal(page.Items[0].Discounts, discounts) {
+		t.Fatalf("got listed orders %+v", page.Items)
+	}
+}
+
+func testCreateOrderWithTaxes(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	laptop := createProduct(t, s, "Laptop", 11200, 5)
+	book := createProduct(t, s, "Book", 5000, 5)
+
+	// Prices that include the tax keep the tax out of the net
+	books := orderItem(t, book, 1, 5000)
+	books.TaxClass = "reduced"
+	taxes := []models.TaxLine{
+		{TaxClass: models.DefaultTaxClass, Rate: 120000, Net: 10000, Tax: 1200},
+		{TaxClass: "reduced", Rate: 0, Net: 5000, Tax: 0},
+	}
+	items := []models.OrderItem{orderItem(t, laptop, 1, 11200), books}
+	id, err := s.OrderRepo().CreateOrder(ctx, &models.Order{
+		UserID:    mustParseID(t, userID),
+		Items:     items,
+		Subtotal:  16200,
+		Discounts: []models.AppliedDiscount{},
+		TaxRegion: "UZ",
+		TaxMode:   models.TaxInclusive,
+		Taxes:     taxes,
+		Net:       15000,
+		Tax:       1200,
+		Total:     16200,
+		Currency:  models.DefaultCurrency,
+	})
+	requireNoError(t, err)
+
+	order, err := s.OrderRepo().GetOrderByID(ctx, id)
+	requireNoError(t, err)
+	if order.TaxRegion != "UZ" || order.TaxMode != models.TaxInclusive || order.Net != 15000 || order.Tax != 1200 || order.Total != 16200 {
+		t.Fatalf("got region %q, mode %q, net %v, tax %v and total %v", order.TaxRegion, order.TaxMode, order.Net, order.Tax, order.Total)
+	}
+	if !slices.Equal(order.Taxes, taxes) || !slices.Equal(order.Items, items) {
+		t.Fatalf("got taxes %+v on items %+v", order.Taxes, order.Items)
+	}
+
+	// Listings load the taxes too
+	page, err := s.OrderRepo().ListOrdersByUser(ctx, userID, &models.Pagination{Page: 1, PageSize: 10})
+	requireNoError(t, err)
+	if len(page.Items) != 1 || !slices.Equal(page.Items[0].Taxes, taxes) {
+		t.Fatalf("got listed orders %+v", page.Items)
+	}
+}
+
+func testUpdateOrder(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	laptop := createProduct(t, s, "Laptop", 999, 5)
+	mouse := createProduct(t, s, "Mouse", 20, 5)
+	id := createOrder(t, s, userID, laptop, 1, 999)
+
+	// Lines priced in another currency keep the rate they were converted at
+	converted := orderItem(t, mouse, 2, 20)
+	converted.PriceCurrency, converted.ExchangeRate = "UZS", 790482
+
+	items := []models.OrderItem{orderItem(t, laptop, 1, 999), converted}
+	discounts := []models.AppliedDiscount{{PromotionID: models.NewID(), Code: "SPRING10", Type: models.PromotionPercentage, Amount: 104}}
+	taxes := []models.TaxLine{{TaxClass: models.DefaultTaxClass, Rate: 88750, Net: 935, Tax: 83}}
+	requireNoError(t, s.OrderRepo().UpdateOrder(ctx, id, models.AnyVersion, &models.UpdatedOrder{Items: items, Subtotal: 1039, Discounts: discounts, Discount: 104, Taxes: taxes, Net: 935, Tax: 83, Total: 1018}))
+
+	order, err := s.OrderRepo().GetOrderByID(ctx, id)
+	requireNoError(t, err)
+	if !slices.Equal(order.Items, items) || order.Total != 1018 {
+		t.Fatalf("got items %+v with total %v", order.Items, order.Total)
+	}
+	if !slices.Equal(order.Discounts, discounts) || order.Subtotal != 1039 || order.Discount != 104 {
+		t.Fatalf("got subtotal %v with discounts %+v of %v", order.Subtotal, order.Discounts, order.Discount)
+	}
+	if !slices.Equal(order.Taxes, taxes) || order.Net != 935 || order.Tax != 83 || order.TaxMode != models.TaxExclusive {
+		t.Fatalf("got taxes %+v, net %v and tax %v in mode %q", order.Taxes, order.Net, order.Tax, order.TaxMode)
+	}
+	if order.Status != models.OrderStatusPending || order.UserID.Hex() != userID {
+		t.Fatalf("update changed status %q or user %s", order.Status, order.UserID.Hex())
+	}
+}
+
+func testDeleteOrder(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	id := createOrder(t, s, userID, createProduct(t, s, "Laptop", 999, 5), 1, 999)
+
+	requireNoError(t, s.OrderRepo().DeleteOrder(ctx, id, models.AnyVersion))
+
+	_, err := s.OrderRepo().GetOrderByID(ctx, id)
+	requireErrorIs(t, err, errs.ErrNotFound)
+	requireErrorIs(t, s.OrderRepo().DeleteOrder(ctx, id, models.AnyVersion), errs.ErrNotFound)
+}
+
+func testTransitionOrderStatus(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	id := createOrder(t, s, userID, createProduct(t, s, "Laptop", 999, 5), 1, 999)
+
+	order, err := s.OrderRepo().TransitionOrderStatus(ctx, id, models.OrderStatusPending, models.OrderStatusPaid)
+	requireNoError(t, err)
+	if order.Status != models.OrderStatusPaid || len(order.StatusHistory) != 2 {
+		t.Fatalf("got status %q with history %+v", order.Status, order.StatusHistory)
+	}
+	if change := order.StatusHistory[1]; change.From != models.OrderStatusPending || change.To != models.OrderStatusPaid {
+		t.Fatalf("got status change %+v", change)
+	}
+
+	// The order is no longer pending, so a transition expecting pending must lose
+	_, err = s.OrderRepo().TransitionOrderStatus(ctx, id, models.OrderStatusPending, models.OrderStatusCancelled)
+	requireErrorIs(t, err, errs.ErrConflict)
+
+	stored, err := s.OrderRepo().GetOrderByID(ctx, id)
+	requireNoError(t, err)
+	if stored.Status != models.OrderStatusPaid || len(stored.StatusHistory) != 2 {
+		t.Fatalf("got status %q with history %+v", stored.Status, stored.StatusHistory)
+	}
+}
+
+func testOrderInvalidIDs(t *testing.T, s storage.StorageI) {
+	repo := s.OrderRepo()
+	pagination := &models.Pagination{Page: 1, PageSize: 10}
+
+	_, err := repo.GetOrderByID(ctx, invalidID)
+	requireErrorIs(t, err, errs.ErrInvalidID)
+	requireErrorIs(t, repo.UpdateOrder(ctx, invalidID, models.AnyVersion, &models.UpdatedOrder{}), errs.ErrInvalidID)
+	requireErrorIs(t, repo.DeleteOrder(ctx, invalidID, models.AnyVersion), errs.ErrInvalidID)
+	_, err = repo.TransitionOrderStatus(ctx, invalidID, models.OrderStatusPending, models.OrderStatusPaid)
+	requireErrorIs(t, err, errs.ErrInvalidID)
+	_, err = repo.ListOrdersByUser(ctx, invalidID, pagination)
+	requireErrorIs(t, err, errs.ErrInvalidID)
+}
+
+func testOrderNotFound(t *testing.T, s storage.StorageI) {
+	repo := s.OrderRepo()
+
+	_, err := repo.GetOrderByID(ctx, missingID)
+	requireErrorIs(t, err, errs.ErrNotFound)
+	requireErrorIs(t, repo.UpdateOrder(ctx, missingID, models.AnyVersion, &models.UpdatedOrder{}), errs.ErrNotFound)
+	requireErrorIs(t, repo.DeleteOrder(ctx, missingID, models.AnyVersion), errs.ErrNotFound)
+	_, err = repo.TransitionOrderStatus(ctx, missingID, models.OrderStatusPending, models.OrderStatusPaid)
+	requireErrorIs(t, err, errs.ErrNotFound)
+}
+
+func testListOrders(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	productID := createProduct(t, s, "Laptop", 999, 5)
+
+	var ids []string
+	for range 5 {
+		ids = append(ids, createOrder(t, s, userID, productID, 1, 999))
+	}
+	slices.Reverse(ids)
+
+	got := walk(t, 2, orderIDs, func(p *models.Pagination) (*models.Page[models.Order], error) {
+		return s.OrderRepo().ListOrders(ctx, p)
+	})
+	requireIDs(t, got, ids)
+}
+
+func testListOrdersByUser(t *testing.T, s storage.StorageI) {
+	alice := createUser(t, s, "Alice", "alice@example.com")
+	bob := createUser(t, s, "Bob", "bob@example.com")
+	productID := createProduct(t, s, "Laptop", 999, 5)
+
+	first := createOrder(t, s, alice, productID, 1, 999)
+	createOrder(t, s, bob, productID, 1, 999)
+	second := createOrder(t, s, alice, productID, 1, 999)
+
+	page, err := s.OrderRepo().ListOrdersByUser(ctx, alice, &models.Pagination{Page: 1, PageSize: 10})
+	requireNoError(t, err)
+	requireIDs(t, orderIDs(page.Items), []string{second, first})
+	if page.Total != 2 {
+		t.Fatalf("got total %d, want 2", page.Total)
+	}
+
+	// A user without orders gets an empty page, not an error
+	page, err = s.OrderRepo().ListOrdersByUser(ctx, missingID, &models.Pagination{Page: 1, PageSize: 10})
+	requireNoError(t, err)
+	if len(page.Items) != 0 || page.Total != 0 {
+		t.Fatalf("got %d orders with total %d, want none", len(page.Items), page.Total)
+	}
+}
+
+func testListOrdersByDateRange(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	productID := createProduct(t, s, "Laptop", 999, 5)
+
+	var ids []string
+	for range 4 {
+		ids = append(ids, createOrder(t, s, userID, productID, 1, 999))
+	}
+
+	// Both bounds are inclusive, so the range spanning exactly the first and the last order holds all of them
+	first, err := s.OrderRepo().GetOrderByID(ctx, ids[0])
+	requireNoError(t, err)
+	last, err := s.OrderRepo().GetOrderByID(ctx, ids[len(ids)-1])
+	requireNoError(t, err)
+	start, end := first.CreatedAt.Time(), last.CreatedAt.Time()
+
+	list := func(order int8) func(*models.Pagination) (*models.Page[models.Order], error) {
+		return func(p *models.Pagination) (*models.Page[models.Order], error) {
+			return s.OrderRepo().ListOrdersByDateRange(ctx, order, p, start, end)
+		}
+	}
+	requireIDs(t, walk(t, 3, orderIDs, list(1)), ids)
+
+	newestFirst := slices.Clone(ids)
+	slices.Reverse(newestFirst)
+	requireIDs(t, walk(t, 3, orderIDs, list(-1)), newestFirst)
+
+	// An empty range is reported as not found
+	_, err = s.OrderRepo().ListOrdersByDateRange(ctx, 1, &models.Pagination{Page: 1, PageSize: 10}, start.Add(-48*time.Hour), start.Add(-24*time.Hour))
+	requireErrorIs(t, err, errs.ErrNotFound)
+}
+
+func testListOrdersWithUsers(t *testing.T, s storage.StorageI) {
+	alice := createUser(t, s, "Alice", "alice@example.com")
+	bob := createUser(t, s, "Bob", "bob@example.com")
+	productID := createProduct(t, s, "Laptop", 999, 5)
+
+	first := createOrder(t, s, alice, productID, 1, 999)
+	second := createOrder(t, s, bob, productID, 2, 999)
+	third := createOrder(t, s, alice, productID, 3, 999)
+
+	page, err := s.OrderRepo().ListOrdersWithUsers(ctx, &models.Pagination{Page: 1, PageSize: 2})
+	requireNoError(t, err)
+	if page.Total != 3 || !page.HasNext || len(page.Items) != 2 {
+		t.Fatalf("got %d orders with total %d and has_next %v", len(page.Items), page.Total, page.HasNext)
+	}
+
+	want := []struct{ orderID, userID, name string }{{third, alice, "Alice"}, {second, bob, "Bob"}}
+	for i, w := range want {
+		got := page.Items[i]
+		if got.OrderID != w.orderID || got.UserID != w.userID || got.UserName != w.name {
+			t.Fatalf("item %d: got %+v, want order %s of %s", i, got, w.orderID, w.name)
+		}
+	}
+
+	page, err = s.OrderRepo().ListOrdersWithUsers(ctx, &models.Pagination{Page: 2, PageSize: 2})
+	requireNoError(t, err)
+	if len(page.Items) != 1 || page.Items[0].OrderID != first || page.HasNext {
+		t.Fatalf("got second page %+v", page)
+	}
+}
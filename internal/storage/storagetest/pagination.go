@@ -0,0 +1,158 @@
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+)
+
+var paginationTests = []contractTest{
+	{"Empty", testPaginateEmpty},
+	{"Offset", testPaginateOffset},
+	{"PastTheEnd", testPaginatePastTheEnd},
+	{"CursorWalk", testPaginateCursorWalk},
+	{"CursorBackward", testPaginateCursorBackward},
+	{"CursorSurvivesInserts", testPaginateCursorSurvivesInserts},
+	{"InvalidCursor", testPaginateInvalidCursor},
+}
+
+func listProducts(s storage.StorageI) func(*models.Pagination) (*models.Page[models.Product], error) {
+	return func(p *models.Pagination) (*models.Page[models.Product], error) {
+		return s.ProductRepo().ListProducts(ctx, p)
+	}
+}
+
+// createProducts creates n products and returns their IDs oldest first
+func createProducts(t *testing.T, s storage.StorageI, n int) []string {
+	t.Helper()
+
+	ids := make([]string, 0, n)
+	for i := range n {
+		ids = append(ids, createProduct(t, s, "Product", models.Money(i+1), 1))
+	}
+	return ids
+}
+
+func testPaginateEmpty(t *testing.T, s storage.StorageI) {
+	page, err := s.ProductRepo().ListProducts(ctx, &models.Pagination{Page: 1, PageSize: 10})
+	requireNoError(t, err)
+
+	// An empty listing still has an items array, so it encodes as [] rather than null
+	if page.Items == nil || len(page.Items) != 0 || page.Total != 0 || page.HasNext || page.NextCursor != "" || page.PrevCursor != "" {
+		t.Fatalf("got page %+v", page)
+	}
+}
+
+func testPaginateOffset(t *testing.T, s storage.StorageI) {
+	ids := createProducts(t, s, 5)
+
+	for _, tt := range []struct {
+		page    int
+		want    []string
+		hasNext bool
+	}{
+		{1, ids[0:2], true},
+		{2, ids[2:4], true},
+		{3, ids[4:5], false},
+	} {
+		page, err := s.ProductRepo().ListProducts(ctx, &models.Pagination{Page: tt.page, PageSize: 2})
+		requireNoError(t, err)
+		requireIDs(t, productIDs(page.Items), tt.want)
+
+		if page.Page != tt.page || page.PageSize != 2 || page.Total != 5 || page.HasNext != tt.hasNext {
+			t.Fatalf("page %d: got page %d, page size %d, total %d, has_next %v", tt.page, page.Page, page.PageSize, page.Total, page.HasNext)
+		}
+		if (page.NextCursor != "") != tt.hasNext || (page.PrevCursor != "") != (tt.page > 1) {
+			t.Fatalf("page %d: got next cursor %q and prev cursor %q", tt.page, page.NextCursor, page.PrevCursor)
+		}
+	}
+}
+
+func testPaginatePastTheEnd(t *testing.T, s storage.StorageI) {
+	createProducts(t, s, 3)
+
+	page, err := s.ProductRepo().ListProducts(ctx, &models.Pagination{Page: 5, PageSize: 2})
+	requireNoError(t, err)
+	if page.Items == nil || len(page.Items) != 0 || page.Total != 3 || page.HasNext {
+		t.Fatalf("got page %+v", page)
+	}
+}
+
+func testPaginateCursorWalk(t *testing.T, s storage.StorageI) {
+	ids := createProducts(t, s, 7)
+
+	// Page sizes that divide the listing evenly and ones that don't
+	for _, size := range []int{1, 2, 3, 7, 10} {
+		requireIDs(t, walk(t, size, productIDs, listProducts(s)), ids)
+	}
+}
+
+func testPaginateCursorBackward(t *testing.T, s storage.StorageI) {
+	ids := createProducts(t, s, 5)
+	list := listProducts(s)
+
+	first, err := list(&models.Pagination{Page: 1, PageSize: 2})
+	requireNoError(t, err)
+	second, err := list(&models.Pagination{PageSize: 2, Cursor: first.NextCursor})
+	requireNoError(t, err)
+	third, err := list(&models.Pagination{PageSize: 2, Cursor: second.NextCursor})
+	requireNoError(t, err)
+	requireIDs(t, productIDs(third.Items), ids[4:5])
+
+	// Going back returns the same pages in the same order
+	back, err := list(&models.Pagination{PageSize: 2, Cursor: third.PrevCursor})
+	requireNoError(t, err)
+	requireIDs(t, productIDs(back.Items), ids[2:4])
+	if !back.HasNext || back.PrevCursor == "" || back.Page != 0 {
+		t.Fatalf("got page %+v", back)
+	}
+
+	back, err = list(&models.Pagination{PageSize: 2, Cursor: back.PrevCursor})
+	requireNoError(t, err)
+	requireIDs(t, productIDs(back.Items), ids[0:2])
+	if back.PrevCursor != "" {
+		t.Fatalf("first page has a prev cursor")
+	}
+}
+
+func testPaginateCursorSurvivesInserts(t *testing.T, s storage.StorageI) {
+	// Newest first, so new products land on the first page and would shift an offset based listing
+	list := func(p *models.Pagination) (*models.Page[models.Product], error) {
+		return s.ProductRepo().ExactSearchProductsByPrice(ctx, 10, models.DefaultCurrency, p)
+	}
+
+	var ids []string
+	for range 4 {
+		ids = append([]string{createProduct(t, s, "Product", 10, 1)}, ids...)
+	}
+
+	first, err := list(&models.Pagination{Page: 1, PageSize: 2})
+	requireNoError(t, err)
+	requireIDs(t, productIDs(first.Items), ids[0:2])
+
+	createProduct(t, s, "Product", 10, 1)
+
+	second, err := list(&models.Pagination{PageSize: 2, Cursor: first.NextCursor})
+	requireNoError(t, err)
+	requireIDs(t, productIDs(second.Items), ids[2:4])
+	if second.Total != 5 {
+		t.Fatalf("got total %d, want 5", second.Total)
+	}
+}
+
+func testPaginateInvalidCursor(t *testing.T, s storage.StorageI) {
+	createProducts(t, s, 3)
+
+	_, err := s.ProductRepo().ListProducts(ctx, &models.Pagination{PageSize: 2, Cursor: "not a cursor"})
+	requireErrorIs(t, err, errs.ErrValidation)
+
+	// A cursor only continues the listing it was issued for
+	page, err := s.ProductRepo().ListProducts(ctx, &models.Pagination{Page: 1, PageSize: 2})
+	requireNoError(t, err)
+	_, err = s.ProductRepo().SearchProductsByPriceRange(ctx, 1, 0, 100, models.DefaultCurrency, &models.Pagination{PageSize: 2, Cursor: page.NextCursor})
+	requireErrorIs(t, err, errs.ErrValidation)
+	_, err = s.OrderRepo().ListOrders(ctx, &models.Pagination{PageSize: 2, Cursor: page.NextCursor})
+	requireErrorIs(t, err, errs.ErrValidation)
+}
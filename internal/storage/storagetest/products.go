@@ -0,0 +1,192 @@
+package storagetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+)
+
+var productTests = []contractTest{
+	{"CreateAndGet", testCreateAndGetProduct},
+	{"Update", testUpdateProduct},
+	{"Patch", testPatchProduct},
+	{"Delete", testDeleteProduct},
+	{"InvalidIDs", testProductInvalidIDs},
+	{"NotFound", testProductNotFound},
+	{"ReserveAndReleaseStock", testReserveAndReleaseStock},
+	{"SearchByName", testSearchProductsByName},
+	{"ExactSearchByPrice", testExactSearchProductsByPrice},
+	{"SearchByPriceRange", testSearchProductsByPriceRange},
+}
+
+func testCreateAndGetProduct(t *testing.T, s storage.StorageI) {
+	id, err := s.ProductRepo().CreateProduct(ctx, &models.ProductCreate{Name: "Laptop", Description: "14 inch", Price: 99950, Currency: "UZS", TaxClass: "reduced", Stock: 3})
+	requireNoError(t, err)
+
+	product, err := s.ProductRepo().GetProductByID(ctx, id)
+	requireNoError(t, err)
+
+	if product.ID.Hex() != id || product.Name != "Laptop" || product.Description != "14 inch" || product.Price != 99950 || product.Currency != "UZS" || product.TaxClass != "reduced" || product.Stock != 3 {
+		t.Fatalf("got product %+v", product)
+	}
+	if product.CreatedAt == 0 || product.UpdatedAt != product.CreatedAt {
+		t.Fatalf("got timestamps %v and %v", product.CreatedAt, product.UpdatedAt)
+	}
+}
+
+func testUpdateProduct(t *testing.T, s storage.StorageI) {
+	id := createProduct(t, s, "Laptop", 999, 3)
+
+	err := s.ProductRepo().UpdateProduct(ctx, id, models.AnyVersion, &models.ProductUpdate{Name: "Notebook", Description: "13 inch", Price: 899, Currency: "UZS", TaxClass: "reduced", Stock: 7})
+	requireNoError(t, err)
+
+	product, err := s.ProductRepo().GetProductByID(ctx, id)
+	requireNoError(t, err)
+	if product.Name != "Notebook" || product.Description != "13 inch" || product.Price != 899 || product.Currency != "UZS" || product.TaxClass != "reduced" || product.Stock != 7 {
+		t.Fatalf("got product %+v", product)
+	}
+}
+
+func testPatchProduct(t *testing.T, s storage.StorageI) {
+	id, err := s.ProductRepo().CreateProduct(ctx, &models.ProductCreate{Name: "Laptop", Description: "14 inch", Price: 999, TaxClass: models.DefaultTaxClass, Stock: 3})
+	requireNoError(t, err)
+
+	// Only the fields in the patch change
+	price := models.Money(899)
+	requireNoError(t, s.ProductRepo().PatchProduct(ctx, id, models.AnyVersion, &models.ProductPatch{Price: &price}))
+
+	product, err := s.ProductRepo().GetProductByID(ctx, id)
+	requireNoError(t, err)
+	if product.Name != "Laptop" || product.Description != "14 inch" || product.Price != 899 || product.Stock != 3 || product.Version != 2 {
+		t.Fatalf("got product %+v", product)
+	}
+
+	name, currency, taxClass, stock := "Notebook", "UZS", "reduced", 0
+	requireNoError(t, s.ProductRepo().PatchProduct(ctx, id, 2, &models.ProductPatch{Name: &name, Currency: &currency, TaxClass: &taxClass, Stock: &stock}))
+	requireErrorIs(t, s.ProductRepo().PatchProduct(ctx, id, 2, &models.ProductPatch{Name: &name}), errs.ErrPreconditionFailed)
+	requireErrorIs(t, s.ProductRepo().PatchProduct(ctx, missingID, 1, &models.ProductPatch{Name: &name}), errs.ErrNotFound)
+
+	product, err = s.ProductRepo().GetProductByID(ctx, id)
+	requireNoError(t, err)
+	if product.Name != "Notebook" || product.Price != 899 || product.Currency != "UZS" || product.TaxClass != "reduced" || product.Stock != 0 || product.Version != 3 {
+		t.Fatalf("got product %+v", product)
+	}
+}
+
+func testDeleteProduct(t *testing.T, s storage.StorageI) {
+	id := createProduct(t, s, "Laptop", 999, 3)
+
+	requireNoError(t, s.ProductRepo().DeleteProduct(ctx, id, models.AnyVersion))
+
+	_, err := s.ProductRepo().GetProductByID(ctx, id)
+	requireErrorIs(t, err, errs.ErrNotFound)
+	requireErrorIs(t, s.ProductRepo().DeleteProduct(ctx, id, models.AnyVersion), errs.ErrNotFound)
+}
+
+func testProductInvalidIDs(t *testing.T, s storage.StorageI) {
+	repo := s.ProductRepo()
+
+	_, err := repo.GetProductByID(ctx, invalidID)
+	requireErrorIs(t, err, errs.ErrInvalidID)
+	requireErrorIs(t, repo.UpdateProduct(ctx, invalidID, models.AnyVersion, &models.ProductUpdate{Name: "x"}), errs.ErrInvalidID)
+	requireErrorIs(t, repo.PatchProduct(ctx, invalidID, models.AnyVersion, &models.ProductPatch{}), errs.ErrInvalidID)
+	requireErrorIs(t, repo.DeleteProduct(ctx, invalidID, models.AnyVersion), errs.ErrInvalidID)
+	requireErrorIs(t, repo.ReserveStock(ctx, invalidID, 1), errs.ErrInvalidID)
+	requireErrorIs(t, repo.ReleaseStock(ctx, invalidID, 1), errs.ErrInvalidID)
+}
+
+func testProductNotFound(t *testing.T, s storage.StorageI) {
+	repo := s.ProductRepo()
+
+	_, err := repo.GetProductByID(ctx, missingID)
+	requireErrorIs(t, err, errs.ErrNotFound)
+	requireErrorIs(t, repo.UpdateProduct(ctx, missingID, models.AnyVersion, &models.ProductUpdate{Name: "x"}), errs.ErrNotFound)
+	requireErrorIs(t, repo.DeleteProduct(ctx, missingID, models.AnyVersion), errs.ErrNotFound)
+	requireErrorIs(t, repo.ReserveStock(ctx, missingID, 1), errs.ErrNotFound)
+	requireErrorIs(t, repo.ReleaseStock(ctx, missingID, 1), errs.ErrNotFound)
+}
+
+func testReserveAndReleaseStock(t *testing.T, s storage.StorageI) {
+	repo := s.ProductRepo()
+	id := createProduct(t, s, "Laptop", 999, 5)
+
+	requireNoError(t, repo.ReserveStock(ctx, id, 3))
+
+	// Asking for more than is left must not take anything
+	err := repo.ReserveStock(ctx, id, 3)
+	var insufficient *errs.InsufficientStockError
+	if !errors.As(err, &insufficient) || insufficient.ProductID != id || insufficient.Requested != 3 {
+		t.Fatalf("got error %v, want insufficient stock for %s", err, id)
+	}
+	requireErrorIs(t, err, errs.ErrConflict)
+
+	requireNoError(t, repo.ReleaseStock(ctx, id, 1))
+
+	product, err := repo.GetProductByID(ctx, id)
+	requireNoError(t, err)
+	if product.Stock != 3 {
+		t.Fatalf("got stock %d, want 3", product.Stock)
+	}
+}
+
+func testSearchProductsByName(t *testing.T, s storage.StorageI) {
+	apple := createProduct(t, s, "Apple", 1, 1)
+	createProduct(t, s, "Banana", 1, 1)
+	pineapple := createProduct(t, s, "Pineapple", 1, 1)
+
+	page, err := s.ProductRepo().SearchProductsByName(ctx, "APPLE", &models.Pagination{Page: 1, PageSize: 10})
+	requireNoError(t, err)
+	requireIDs(t, productIDs(page.Items), []string{apple, pineapple})
+	if page.Total != 2 {
+		t.Fatalf("got total %d, want 2", page.Total)
+	}
+}
+
+func testExactSearchProductsByPrice(t *testing.T, s storage.StorageI) {
+	first := createProduct(t, s, "First", 1000, 1)
+	createProduct(t, s, "Other", 1050, 1)
+	second := createProduct(t, s, "Second", 1000, 1)
+	createProductIn(t, s, "UZS", "Som", 1000)
+
+	// Newest first, the price in another currency doesn't match
+	page, err := s.ProductRepo().ExactSearchProductsByPrice(ctx, 1000, models.DefaultCurrency, &models.Pagination{Page: 1, PageSize: 10})
+	requireNoError(t, err)
+	requireIDs(t, productIDs(page.Items), []string{second, first})
+}
+
+func testSearchProductsByPriceRange(t *testing.T, s storage.StorageI) {
+	p30 := createProduct(t, s, "30", 30, 1)
+	createProduct(t, s, "5", 5, 1)
+	p10 := createProduct(t, s, "10", 10, 1)
+	p20a := createProduct(t, s, "20a", 20, 1)
+	createProduct(t, s, "31", 31, 1)
+	p20b := createProduct(t, s, "20b", 20, 1)
+	createProductIn(t, s, "UZS", "20 som", 20)
+
+	// Both bounds are inclusive, equal prices are ordered by ID in the same direction as the price.
+	// Prices in another currency are left out.
+	ascending := []string{p10, p20a, p20b, p30}
+	descending := []string{p30, p20b, p20a, p10}
+
+	page, err := s.ProductRepo().SearchProductsByPriceRange(ctx, 1, 10, 30, models.DefaultCurrency, &models.Pagination{Page: 1, PageSize: 10})
+	requireNoError(t, err)
+	requireIDs(t, productIDs(page.Items), ascending)
+
+	page, err = s.ProductRepo().SearchProductsByPriceRange(ctx, -1, 10, 30, models.DefaultCurrency, &models.Pagination{Page: 1, PageSize: 10})
+	requireNoError(t, err)
+	requireIDs(t, productIDs(page.Items), descending)
+
+	// Walking the cursors keeps the order across pages
+	for _, tt := range []struct {
+		order int8
+		want  []string
+	}{{1, ascending}, {-1, descending}} {
+		got := walk(t, 3, productIDs, func(p *models.Pagination) (*models.Page[models.Product], error) {
+			return s.ProductRepo().SearchProductsByPriceRange(ctx, tt.order, 10, 30, models.DefaultCurrency, p)
+		})
+		requireIDs(t, got, tt.want)
+	}
+}
@@ -0,0 +1,244 @@
+package storagetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var promotionTests = []contractTest{
+	{"CreateAndGet", testCreateAndGetPromotion},
+	{"DuplicateCode", testDuplicatePromotionCode},
+	{"Delete", testDeletePromotion},
+	{"ListOldestFirst", testListPromotions},
+	{"InvalidIDs", testPromotionInvalidIDs},
+	{"UsageLimit", testPromotionUsageLimit},
+	{"PerUserLimit", testPromotionPerUserLimit},
+	{"Release", testReleasePromotion},
+	{"ConcurrentRedeem", testConcurrentRedeemPromotion},
+}
+
+func createPromotion(t *testing.T, s storage.StorageI, code string, usageLimit, perUserLimit int) string {
+	t.Helper()
+
+	id, err := s.PromotionRepo().CreatePromotion(ctx, &models.PromotionCreate{
+		Code:         code,
+		Type:         models.PromotionPercentage,
+		Percent:      10,
+		Currency:     models.DefaultCurrency,
+		UsageLimit:   usageLimit,
+		PerUserLimit: perUserLimit,
+	})
+	if err != nil {
+		t.Fatalf("CreatePromotion(%q) failed: %v", code, err)
+	}
+	return id
+}
+
+func requireUsageCount(t *testing.T, s storage.StorageI, promotionID string, want int) {
+	t.Helper()
+
+	promotion, err := s.PromotionRepo().GetPromotionByID(ctx, promotionID)
+	requireNoError(t, err)
+	if promotion.UsageCount != want {
+		t.Fatalf("got usage count %d, want %d", promotion.UsageCount, want)
+	}
+}
+
+func testCreateAndGetPromotion(t *testing.T, s storage.StorageI) {
+	productID := mustParseID(t, createProduct(t, s, "Laptop", 999, 5))
+	startsAt := primitive.NewDateTimeFromTime(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	endsAt := primitive.NewDateTimeFromTime(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC))
+
+	create := &models.PromotionCreate{
+		Code:          "LAPTOP3FOR2",
+		Description:   "Third laptop free",
+		Type:          models.PromotionBuyXGetY,
+		ProductID:     &productID,
+		BuyQuantity:   2,
+		FreeQuantity:  1,
+		MinOrderValue: 1000,
+		Currency:      "UZS",
+		StartsAt:      startsAt,
+		EndsAt:        endsAt,
+		UsageLimit:    100,
+		PerUserLimit:  1,
+	}
+	id, err := s.PromotionRepo().CreatePromotion(ctx, create)
+	requireNoError(t, err)
+
+	byID, err := s.PromotionRepo().GetPromotionByID(ctx, id)
+	requireNoError(t, err)
+	byCode, err := s.PromotionRepo().GetPromotionByCode(ctx, "LAPTOP3FOR2")
+	requireNoError(t, err)
+
+	for _, promotion := range []*models.Promotion{byID, byCode} {
+		if promotion.ID.Hex() != id || promotion.Code != create.Code || promotion.Description != create.Description || promotion.Type != create.Type {
+			t.Fatalf("got promotion %+v", promotion)
+		}
+		if promotion.ProductID == nil || *promotion.ProductID != productID || promotion.BuyQuantity != 2 || promotion.FreeQuantity != 1 {
+			t.Fatalf("got buy_x_get_y rule %+v", promotion)
+		}
+		if promotion.MinOrderValue != 1000 || promotion.Currency != "UZS" || promotion.StartsAt != startsAt || promotion.EndsAt != endsAt {
+			t.Fatalf("got conditions %+v", promotion)
+		}
+		if promotion.UsageLimit != 100 || promotion.PerUserLimit != 1 || promotion.UsageCount != 0 {
+			t.Fatalf("got limits %+v", promotion)
+		}
+	}
+
+	// A promotion without a product or a window reads them back as unset
+	id = createPromotion(t, s, "SPRING10", 0, 0)
+	promotion, err := s.PromotionRepo().GetPromotionByID(ctx, id)
+	requireNoError(t, err)
+	if promotion.ProductID != nil || promotion.StartsAt != 0 || promotion.EndsAt != 0 || promotion.Percent != 10 {
+		t.Fatalf("got promotion %+v", promotion)
+	}
+
+	_, err = s.PromotionRepo().GetPromotionByCode(ctx, "WINTER10")
+	requireErrorIs(t, err, errs.ErrNotFound)
+}
+
+func testDuplicatePromotionCode(t *testing.T, s storage.StorageI) {
+	createPromotion(t, s, "SPRING10", 0, 0)
+
+	_, err := s.PromotionRepo().CreatePromotion(ctx, &models.PromotionCreate{Code: "SPRING10", Type: models.PromotionFixedAmount, Amount: 500, Currency: models.DefaultCurrency})
+	requireErrorIs(t, err, errs.ErrConflict)
+}
+
+func testDeletePromotion(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	id := createPromotion(t, s, "SPRING10", 0, 1)
+	requireNoError(t, s.PromotionRepo().RedeemPromotion(ctx, id, userID))
+
+	requireNoError(t, s.PromotionRepo().DeletePromotion(ctx, id))
+
+	_, err := s.PromotionRepo().GetPromotionByID(ctx, id)
+	requireErrorIs(t, err, errs.ErrNotFound)
+	requireErrorIs(t, s.PromotionRepo().DeletePromotion(ctx, id), errs.ErrNotFound)
+	requireErrorIs(t, s.PromotionRepo().RedeemPromotion(ctx, id, userID), errs.ErrNotFound)
+	requireErrorIs(t, s.PromotionRepo().ReleasePromotion(ctx, id, userID), errs.ErrNotFound)
+
+	// The code is free again and the uses of the deleted promotion don't count for the new one
+	id = createPromotion(t, s, "SPRING10", 0, 1)
+	requireNoError(t, s.PromotionRepo().RedeemPromotion(ctx, id, userID))
+}
+
+func testListPromotions(t *testing.T, s storage.StorageI) {
+	var want []string
+	for _, code := range []string{"SPRING10", "SUMMER10", "AUTUMN10"} {
+		want = append(want, createPromotion(t, s, code, 0, 0))
+	}
+
+	got := walk(t, 2, promotionIDs, func(p *models.Pagination) (*models.Page[models.Promotion], error) {
+		return s.PromotionRepo().ListPromotions(ctx, p)
+	})
+	requireIDs(t, got, want)
+}
+
+func testPromotionInvalidIDs(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	id := createPromotion(t, s, "SPRING10", 0, 0)
+
+	_, err := s.PromotionRepo().GetPromotionByID(ctx, invalidID)
+	requireErrorIs(t, err, errs.ErrInvalidID)
+	requireErrorIs(t, s.PromotionRepo().DeletePromotion(ctx, invalidID), errs.ErrInvalidID)
+	requireErrorIs(t, s.PromotionRepo().RedeemPromotion(ctx, invalidID, userID), errs.ErrInvalidID)
+	requireErrorIs(t, s.PromotionRepo().RedeemPromotion(ctx, id, invalidID), errs.ErrInvalidID)
+	requireErrorIs(t, s.PromotionRepo().ReleasePromotion(ctx, invalidID, userID), errs.ErrInvalidID)
+
+	requireErrorIs(t, s.PromotionRepo().RedeemPromotion(ctx, missingID, userID), errs.ErrNotFound)
+	requireUsageCount(t, s, id, 0)
+}
+
+func testPromotionUsageLimit(t *testing.T, s storage.StorageI) {
+	id := createPromotion(t, s, "SPRING10", 2, 0)
+	alice := createUser(t, s, "Alice", "alice@example.com")
+	bob := createUser(t, s, "Bob", "bob@example.com")
+
+	requireNoError(t, s.PromotionRepo().RedeemPromotion(ctx, id, alice))
+	requireNoError(t, s.PromotionRepo().RedeemPromotion(ctx, id, bob))
+	requireErrorIs(t, s.PromotionRepo().RedeemPromotion(ctx, id, alice), errs.ErrConflict)
+	requireUsageCount(t, s, id, 2)
+
+	// Without limits a promotion can be used any number of times
+	unlimited := createPromotion(t, s, "FOREVER", 0, 0)
+	for range 5 {
+		requireNoError(t, s.PromotionRepo().RedeemPromotion(ctx, unlimited, alice))
+	}
+	requireUsageCount(t, s, unlimited, 5)
+}
+
+func testPromotionPerUserLimit(t *testing.T, s storage.StorageI) {
+	id := createPromotion(t, s, "SPRING10", 0, 2)
+	alice := createUser(t, s, "Alice", "alice@example.com")
+	bob := createUser(t, s, "Bob", "bob@example.com")
+
+	requireNoError(t, s.PromotionRepo().RedeemPromotion(ctx, id, alice))
+	requireNoError(t, s.PromotionRepo().RedeemPromotion(ctx, id, alice))
+	requireErrorIs(t, s.PromotionRepo().RedeemPromotion(ctx, id, alice), errs.ErrConflict)
+	requireNoError(t, s.PromotionRepo().RedeemPromotion(ctx, id, bob))
+
+	// The use refused to Alice isn't counted against the promotion
+	requireUsageCount(t, s, id, 3)
+}
+
+func testReleasePromotion(t *testing.T, s storage.StorageI) {
+	id := createPromotion(t, s, "SPRING10", 1, 1)
+	alice := createUser(t, s, "Alice", "alice@example.com")
+
+	requireNoError(t, s.PromotionRepo().RedeemPromotion(ctx, id, alice))
+	requireErrorIs(t, s.PromotionRepo().RedeemPromotion(ctx, id, alice), errs.ErrConflict)
+
+	// A released use can be made again, by the same user too
+	requireNoError(t, s.PromotionRepo().ReleasePromotion(ctx, id, alice))
+	requireUsageCount(t, s, id, 0)
+	requireNoError(t, s.PromotionRepo().RedeemPromotion(ctx, id, alice))
+
+	// Releasing more than was redeemed never goes below zero
+	requireNoError(t, s.PromotionRepo().ReleasePromotion(ctx, id, alice))
+	requireNoError(t, s.PromotionRepo().ReleasePromotion(ctx, id, alice))
+	requireUsageCount(t, s, id, 0)
+	requireNoError(t, s.PromotionRepo().RedeemPromotion(ctx, id, alice))
+	requireUsageCount(t, s, id, 1)
+}
+
+func testConcurrentRedeemPromotion(t *testing.T, s storage.StorageI) {
+	id := createPromotion(t, s, "SPRING10", 10, 2)
+
+	users := make([]string, 8)
+	for i := range users {
+		users[i] = createUser(t, s, "Customer", models.NewID().Hex()+"@example.com")
+	}
+
+	// Every user tries four times, only two of those may count and only ten uses in all
+	var redeemed int
+	perUser := make(map[string]int)
+	results := parallel(4*len(users), func(i int) error {
+		return s.PromotionRepo().RedeemPromotion(ctx, id, users[i%len(users)])
+	})
+	for i, err := range results {
+		switch {
+		case err == nil:
+			redeemed++
+			perUser[users[i%len(users)]]++
+		case !errors.Is(err, errs.ErrConflict):
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if redeemed != 10 {
+		t.Fatalf("redeemed %d times, want 10", redeemed)
+	}
+	for user, count := range perUser {
+		if count > 2 {
+			t.Fatalf("user %s redeemed %d times, want at most 2", user, count)
+		}
+	}
+	requireUsageCount(t, s, id, 10)
+}
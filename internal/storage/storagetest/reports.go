@@ -0,0 +1,120 @@
+package storagetest
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+)
+
+var reportTests = []contractTest{
+	{"RevenueWithTax", testReportRevenueWithTax},
+	{"RevenuePerCurrency", testReportRevenuePerCurrency},
+}
+
+func testReportRevenueWithTax(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	productID := createProduct(t, s, "Laptop", 11200, 5)
+
+	// One order with the tax included, one with the tax on top and one cancelled, which doesn't count
+	for _, order := range []models.Order{
+		{TaxMode: models.TaxInclusive, Net: 10000, Tax: 1200, Total: 11200},
+		{TaxMode: models.TaxExclusive, Net: 11200, Tax: 994, Total: 12194},
+	} {
+		order.UserID = mustParseID(t, userID)
+		order.Items = []models.OrderItem{orderItem(t, productID, 1, 11200)}
+		order.Subtotal, order.Currency = 11200, models.DefaultCurrency
+		order.Discounts = []models.AppliedDiscount{}
+		order.Taxes = []models.TaxLine{{TaxClass: models.DefaultTaxClass, Net: order.Net, Tax: order.Tax}}
+		_, err := s.OrderRepo().CreateOrder(ctx, &order)
+		requireNoError(t, err)
+	}
+	cancelled := createOrder(t, s, userID, productID, 1, 11200)
+	_, err := s.OrderRepo().TransitionOrderStatus(ctx, cancelled, models.OrderStatusPending, models.OrderStatusCancelled)
+	requireNoError(t, err)
+
+	report, err := s.ReportRepo().GetReport(ctx)
+	requireNoError(t, err)
+	want := models.Revenue{Currency: models.DefaultCurrency, TotalOrders: 2, TotalRevenue: 23394, NetRevenue: 21200, TotalTax: 2194}
+	if report.TotalOrders != 2 || len(report.Revenue) != 1 || report.Revenue[0] != want {
+		t.Fatalf("got report %+v", report)
+	}
+
+	now := time.Now().UTC()
+	days, err := s.ReportRepo().GetDailyOrderAggregates(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	requireNoError(t, err)
+	if len(days) == 0 {
+		t.Fatal("got no daily aggregates")
+	}
+
+	// The orders may straddle midnight, the days add up all the same
+	var total models.OrderAggregate
+	for _, day := range days {
+		total.TotalOrders += day.TotalOrders
+		total.TotalRevenue += day.TotalRevenue
+		total.NetRevenue += day.NetRevenue
+		total.TotalTax += day.TotalTax
+	}
+	if total.TotalOrders != 2 || total.TotalRevenue != 23394 || total.NetRevenue != 21200 || total.TotalTax != 2194 {
+		t.Fatalf("got daily aggregates %+v", days)
+	}
+}
+
+func testReportRevenuePerCurrency(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	laptopID := createProduct(t, s, "Laptop", 100000, 5)
+	mouseID := createProduct(t, s, "Mouse", 2500, 10)
+
+	// Amounts in different currencies never add up, each currency is reported on its own
+	for _, order := range []struct {
+		currency  string
+		productID string
+		quantity  int
+		unitPrice models.Money
+	}{
+		{models.DefaultCurrency, laptopID, 1, 100000},
+		{models.DefaultCurrency, mouseID, 2, 2500},
+		{"EUR", laptopID, 1, 92000},
+		{"EUR", mouseID, 3, 2300},
+	} {
+		createOrderIn(t, s, order.currency, userID, order.productID, order.quantity, order.unitPrice)
+	}
+
+	report, err := s.ReportRepo().GetReport(ctx)
+	requireNoError(t, err)
+	wantRevenue := []models.Revenue{
+		{Currency: "EUR", TotalOrders: 2, TotalRevenue: 98900, NetRevenue: 98900},
+		{Currency: models.DefaultCurrency, TotalOrders: 2, TotalRevenue: 105000, NetRevenue: 105000},
+	}
+	if report.TotalOrders != 4 || !slices.Equal(report.Revenue, wantRevenue) {
+		t.Fatalf("got report %+v, want revenue %+v", report, wantRevenue)
+	}
+
+	// The mouse sold more units in euros, the laptop made more revenue in dollars than in euros
+	products, err := s.ReportRepo().GetTopProducts(ctx, 10)
+	requireNoError(t, err)
+	wantProducts := []models.TopProduct{
+		{ProductID: mouseID, Name: "Mouse", Currency: "EUR", TotalSold: 3, TotalRevenue: 6900},
+		{ProductID: mouseID, Name: "Mouse", Currency: models.DefaultCurrency, TotalSold: 2, TotalRevenue: 5000},
+		{ProductID: laptopID, Name: "Laptop", Currency: models.DefaultCurrency, TotalSold: 1, TotalRevenue: 100000},
+		{ProductID: laptopID, Name: "Laptop", Currency: "EUR", TotalSold: 1, TotalRevenue: 92000},
+	}
+	if !slices.Equal(products, wantProducts) {
+		t.Fatalf("got top products %+v, want %+v", products, wantProducts)
+	}
+
+	now := time.Now().UTC()
+	days, err := s.ReportRepo().GetDailyOrderAggregates(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	requireNoError(t, err)
+
+	// The orders may straddle midnight, the days of each currency add up all the same
+	totals := make(map[string]models.Money)
+	for _, day := range days {
+		totals[day.Currency] += day.TotalRevenue
+	}
+	if len(totals) != 2 || totals["EUR"] != 98900 || totals[models.DefaultCurrency] != 105000 {
+		t.Fatalf("got daily aggregates %+v", days)
+	}
+}
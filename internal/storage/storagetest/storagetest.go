@@ -0,0 +1,216 @@
+// Package storagetest is the contract every storage backend has to fulfil.
+//
+// Run exercises the repositories of a storage.StorageI and is meant to be called from the tests
+// of each backend, so their behaviour can't drift apart.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+)
+
+// Factory creates an empty storage for a single test, cleaning it up is left to the factory
+type Factory func(t *testing.T) storage.StorageI
+
+type contractTest struct {
+	name string
+	run  func(t *testing.T, s storage.StorageI)
+}
+
+// Run runs the whole contract against the storages created by newStorage
+func Run(t *testing.T, newStorage Factory) {
+	groups := []struct {
+		name  string
+		tests []contractTest
+	}{
+		{"Products", productTests},
+		{"Orders", orderTests},
+		{"Promotions", promotionTests},
+		{"Reports", reportTests},
+		{"Pagination", paginationTests},
+		{"Concurrency", concurrencyTests},
+		{"Transactions", transactionTests},
+		{"Versions", versionTests},
+	}
+
+	for _, group := range groups {
+		t.Run(group.name, func(t *testing.T) {
+			for _, test := range group.tests {
+				t.Run(test.name, func(t *testing.T) {
+					test.run(t, newStorage(t))
+				})
+			}
+		})
+	}
+}
+
+// invalidID is not a hex ObjectID, missingID is a well-formed ID nothing is stored under
+const invalidID = "not-a-hex-id"
+
+var missingID = models.NewID().Hex()
+
+var ctx = context.Background()
+
+func createProduct(t *testing.T, s storage.StorageI, name string, price models.Money, stock int) string {
+	t.Helper()
+
+	id, err := s.ProductRepo().CreateProduct(ctx, &models.ProductCreate{Name: name, Price: price, Currency: models.DefaultCurrency, TaxClass: models.DefaultTaxClass, Stock: stock})
+	if err != nil {
+		t.Fatalf("CreateProduct(%q) failed: %v", name, err)
+	}
+	return id
+}
+
+// createProductIn creates a product in stock priced in currency
+func createProductIn(t *testing.T, s storage.StorageI, currency, name string, price models.Money) string {
+	t.Helper()
+
+	id, err := s.ProductRepo().CreateProduct(ctx, &models.ProductCreate{Name: name, Price: price, Currency: currency, TaxClass: models.DefaultTaxClass, Stock: 1})
+	if err != nil {
+		t.Fatalf("CreateProduct(%q) failed: %v", name, err)
+	}
+	return id
+}
+
+func createUser(t *testing.T, s storage.StorageI, name, email string) string {
+	t.Helper()
+
+	id, err := s.UserRepo().CreateUser(ctx, "hash", &models.UserCreate{Name: name, Email: email, Role: models.RoleCustomer})
+	if err != nil {
+		t.Fatalf("CreateUser(%q) failed: %v", email, err)
+	}
+	return id
+}
+
+// createOrder stores an order of quantity units of a product at the given unit price, untaxed
+func createOrder(t *testing.T, s storage.StorageI, userID, productID string, quantity int, unitPrice models.Money) string {
+	t.Helper()
+
+	return createOrderIn(t, s, models.DefaultCurrency, userID, productID, quantity, unitPrice)
+}
+
+// createOrderIn stores an order like createOrder, charged in currency
+func createOrderIn(t *testing.T, s storage.StorageI, currency, userID, productID string, quantity int, unitPrice models.Money) string {
+	t.Helper()
+
+	order := &models.Order{
+		UserID:    mustParseID(t, userID),
+		Items:     []models.OrderItem{orderItem(t, productID, quantity, unitPrice)},
+		Subtotal:  unitPrice.Mul(quantity),
+		Discounts: []models.AppliedDiscount{},
+		TaxMode:   models.TaxExclusive,
+		Taxes:     []models.TaxLine{{TaxClass: models.DefaultTaxClass, Net: unitPrice.Mul(quantity)}},
+		Net:       unitPrice.Mul(quantity),
+		Total:     unitPrice.Mul(quantity),
+		Currency:  currency,
+	}
+	id, err := s.OrderRepo().CreateOrder(ctx, order)
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	return id
+}
+
+func orderItem(t *testing.T, productID string, quantity int, unitPrice models.Money) models.OrderItem {
+	t.Helper()
+
+	return models.OrderItem{
+		ProductID:     mustParseID(t, productID),
+		Quantity:      quantity,
+		UnitPrice:     unitPrice,
+		LineTotal:     unitPrice.Mul(quantity),
+		PriceCurrency: models.DefaultCurrency,
+		ExchangeRate:  models.UnitRate,
+		TaxClass:      models.DefaultTaxClass,
+	}
+}
+
+func mustParseID(t *testing.T, id string) models.ID {
+	t.Helper()
+
+	parsed, err := models.ParseID(id)
+	if err != nil {
+		t.Fatalf("invalid ID %q: %v", id, err)
+	}
+	return parsed
+}
+
+func requireErrorIs(t *testing.T, err, target error) {
+	t.Helper()
+
+	if !errors.Is(err, target) {
+		t.Fatalf("got error %v, want %v", err, target)
+	}
+}
+
+func requireNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func requireIDs(t *testing.T, got, want []string) {
+	t.Helper()
+
+	if !slices.Equal(got, want) {
+		t.Fatalf("got IDs %v, want %v", got, want)
+	}
+}
+
+func productIDs(products []models.Product) []string {
+	ids := make([]string, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID.Hex())
+	}
+	return ids
+}
+
+func orderIDs(orders []models.Order) []string {
+	ids := make([]string, 0, len(orders))
+	for _, order := range orders {
+		ids = append(ids, order.ID.Hex())
+	}
+	return ids
+}
+
+func promotionIDs(promotions []models.Promotion) []string {
+	ids := make([]string, 0, len(promotions))
+	for _, promotion := range promotions {
+		ids = append(ids, promotion.ID.Hex())
+	}
+	return ids
+}
+
+// walk follows the next cursors of a listing from its first page and returns the IDs of every item it saw
+func walk[T any](t *testing.T, pageSize int, ids func([]T) []string, list func(*models.Pagination) (*models.Page[T], error)) []string {
+	t.Helper()
+
+	var seen []string
+	pagination := &models.Pagination{Page: 1, PageSize: pageSize}
+	for range 100 {
+		page, err := list(pagination)
+		requireNoError(t, err)
+		if len(page.Items) > pageSize {
+			t.Fatalf("page has %d items, more than the page size %d", len(page.Items), pageSize)
+		}
+
+		seen = append(seen, ids(page.Items)...)
+		if !page.HasNext {
+			if page.NextCursor != "" {
+				t.Fatalf("last page has a next cursor")
+			}
+			return seen
+		}
+		pagination = &models.Pagination{PageSize: pageSize, Cursor: page.NextCursor}
+	}
+
+	t.Fatalf("listing didn't end after 100 pages")
+	return nil
+}
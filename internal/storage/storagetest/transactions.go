@@ -0,0 +1,119 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+)
+
+var transactionTests = []contractTest{
+	{"Commit", testTransactionCommit},
+	{"Rollback", testTransactionRollback},
+	{"Nested", testTransactionNested},
+	{"ReturnsError", testTransactionReturnsError},
+}
+
+var errAbort = errors.New("abort")
+
+func testTransactionCommit(t *testing.T, s storage.StorageI) {
+	productID := createProduct(t, s, "Laptop", 999, 10)
+
+	var created string
+	err := s.Transactor().WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.ProductRepo().ReserveStock(ctx, productID, 3); err != nil {
+			return err
+		}
+
+		var err error
+		created, err = s.ProductRepo().CreateProduct(ctx, &models.ProductCreate{Name: "Mouse", Price: 25, Stock: 5})
+		return err
+	})
+	requireNoError(t, err)
+
+	product, err := s.ProductRepo().GetProductByID(ctx, productID)
+	requireNoError(t, err)
+	if product.Stock != 7 {
+		t.Fatalf("got stock %d, want 7", product.Stock)
+	}
+
+	_, err = s.ProductRepo().GetProductByID(ctx, created)
+	requireNoError(t, err)
+}
+
+func testTransactionRollback(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	productID := createProduct(t, s, "Laptop", 999, 10)
+
+	var created, orderID string
+	err := s.Transactor().WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.ProductRepo().ReserveStock(ctx, productID, 3); err != nil {
+			return err
+		}
+
+		var err error
+		if created, err = s.ProductRepo().CreateProduct(ctx, &models.ProductCreate{Name: "Mouse", Price: 25, Stock: 5}); err != nil {
+			return err
+		}
+		orderID, err = s.OrderRepo().CreateOrder(ctx, &models.Order{
+			UserID: mustParseID(t, userID),
+			Items:  []models.OrderItem{orderItem(t, productID, 3, 999)},
+			Total:  2997,
+		})
+		if err != nil {
+			return err
+		}
+		return errAbort
+	})
+	requireErrorIs(t, err, errAbort)
+
+	// None of the changes made in the unit of work may survive it
+	product, err := s.ProductRepo().GetProductByID(ctx, productID)
+	requireNoError(t, err)
+	if product.Stock != 10 {
+		t.Fatalf("got stock %d, want the reservation rolled back to 10", product.Stock)
+	}
+
+	_, err = s.ProductRepo().GetProductByID(ctx, created)
+	requireErrorIs(t, err, errs.ErrNotFound)
+	_, err = s.OrderRepo().GetOrderByID(ctx, orderID)
+	requireErrorIs(t, err, errs.ErrNotFound)
+}
+
+func testTransactionNested(t *testing.T, s storage.StorageI) {
+	productID := createProduct(t, s, "Laptop", 999, 10)
+
+	// A nested unit of work joins the outer one, so its changes go when the outer one fails
+	err := s.Transactor().WithTransaction(ctx, func(ctx context.Context) error {
+		err := s.Transactor().WithTransaction(ctx, func(ctx context.Context) error {
+			return s.ProductRepo().ReserveStock(ctx, productID, 4)
+		})
+		if err != nil {
+			return err
+		}
+		return errAbort
+	})
+	requireErrorIs(t, err, errAbort)
+
+	product, err := s.ProductRepo().GetProductByID(ctx, productID)
+	requireNoError(t, err)
+	if product.Stock != 10 {
+		t.Fatalf("got stock %d, want the nested reservation rolled back to 10", product.Stock)
+	}
+}
+
+func testTransactionReturnsError(t *testing.T, s storage.StorageI) {
+	productID := createProduct(t, s, "Laptop", 999, 2)
+
+	// Domain errors from the repositories reach the caller unchanged
+	err := s.Transactor().WithTransaction(ctx, func(ctx context.Context) error {
+		return s.ProductRepo().ReserveStock(ctx, productID, 5)
+	})
+	var insufficient *errs.InsufficientStockError
+	if !errors.As(err, &insufficient) {
+		t.Fatalf("got error %v, want InsufficientStockError", err)
+	}
+}
@@ -0,0 +1,137 @@
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/abdulazizax/udevslab-lesson3/internal/storage"
+)
+
+var versionTests = []contractTest{
+	{"ProductWrites", testProductVersions},
+	{"ProductStaleWrites", testStaleProductWrites},
+	{"ProductStockMovements", testStockMovementsKeepVersion},
+	{"OrderWrites", testOrderVersions},
+	{"OrderStaleWrites", testStaleOrderWrites},
+	{"MissingWithVersion", testMissingWithVersion},
+}
+
+func productVersion(t *testing.T, s storage.StorageI, id string) int64 {
+	t.Helper()
+
+	product, err := s.ProductRepo().GetProductByID(ctx, id)
+	requireNoError(t, err)
+	return product.Version
+}
+
+func orderVersion(t *testing.T, s storage.StorageI, id string) int64 {
+	t.Helper()
+
+	order, err := s.OrderRepo().GetOrderByID(ctx, id)
+	requireNoError(t, err)
+	return order.Version
+}
+
+func requireVersion(t *testing.T, got, want int64) {
+	t.Helper()
+
+	if got != want {
+		t.Fatalf("got version %d, want %d", got, want)
+	}
+}
+
+func testProductVersions(t *testing.T, s storage.StorageI) {
+	id := createProduct(t, s, "Laptop", 999, 10)
+	requireVersion(t, productVersion(t, s, id), 1)
+
+	requireNoError(t, s.ProductRepo().UpdateProduct(ctx, id, 1, &models.ProductUpdate{Name: "Notebook", Price: 899, Stock: 10}))
+	requireVersion(t, productVersion(t, s, id), 2)
+
+	name := "Notebook Pro"
+	requireNoError(t, s.ProductRepo().PatchProduct(ctx, id, 2, &models.ProductPatch{Name: &name}))
+	requireVersion(t, productVersion(t, s, id), 3)
+
+	requireNoError(t, s.ProductRepo().UpdateProduct(ctx, id, models.AnyVersion, &models.ProductUpdate{Name: "Notebook", Price: 849, Stock: 9}))
+	requireVersion(t, productVersion(t, s, id), 4)
+
+	requireNoError(t, s.ProductRepo().DeleteProduct(ctx, id, 4))
+}
+
+func testStaleProductWrites(t *testing.T, s storage.StorageI) {
+	id := createProduct(t, s, "Laptop", 999, 10)
+	requireNoError(t, s.ProductRepo().UpdateProduct(ctx, id, 1, &models.ProductUpdate{Name: "Notebook", Price: 899, Stock: 10}))
+
+	// Both writes expect the version the product had before the update
+	err := s.ProductRepo().UpdateProduct(ctx, id, 1, &models.ProductUpdate{Name: "Laptop Pro", Price: 1299, Stock: 5})
+	requireErrorIs(t, err, errs.ErrPreconditionFailed)
+	requireErrorIs(t, s.ProductRepo().DeleteProduct(ctx, id, 1), errs.ErrPreconditionFailed)
+
+	product, err := s.ProductRepo().GetProductByID(ctx, id)
+	requireNoError(t, err)
+	if product.Name != "Notebook" || product.Stock != 10 || product.Version != 2 {
+		t.Fatalf("got product %+v, want it untouched by the stale writes", product)
+	}
+}
+
+func testStockMovementsKeepVersion(t *testing.T, s storage.StorageI) {
+	id := createProduct(t, s, "Laptop", 999, 10)
+
+	// Orders taking and giving back stock don't count as writes, an admin holding the version
+	// read before them can still update the product
+	requireNoError(t, s.ProductRepo().ReserveStock(ctx, id, 2))
+	requireNoError(t, s.ProductRepo().ReleaseStock(ctx, id, 1))
+	requireVersion(t, productVersion(t, s, id), 1)
+
+	requireNoError(t, s.ProductRepo().UpdateProduct(ctx, id, 1, &models.ProductUpdate{Name: "Notebook", Price: 899, Stock: 20}))
+	product, err := s.ProductRepo().GetProductByID(ctx, id)
+	requireNoError(t, err)
+	if product.Stock != 20 || product.Version != 2 {
+		t.Fatalf("got product %+v, want the stock of the update at version 2", product)
+	}
+}
+
+func testOrderVersions(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	productID := createProduct(t, s, "Laptop", 999, 10)
+	id := createOrder(t, s, userID, productID, 1, 999)
+	requireVersion(t, orderVersion(t, s, id), 1)
+
+	items := []models.OrderItem{orderItem(t, productID, 2, 999)}
+	requireNoError(t, s.OrderRepo().UpdateOrder(ctx, id, 1, &models.UpdatedOrder{Items: items, Total: 1998}))
+	requireVersion(t, orderVersion(t, s, id), 2)
+
+	order, err := s.OrderRepo().TransitionOrderStatus(ctx, id, models.OrderStatusPending, models.OrderStatusPaid)
+	requireNoError(t, err)
+	requireVersion(t, order.Version, 3)
+	requireVersion(t, orderVersion(t, s, id), 3)
+
+	requireNoError(t, s.OrderRepo().DeleteOrder(ctx, id, 3))
+}
+
+func testStaleOrderWrites(t *testing.T, s storage.StorageI) {
+	userID := createUser(t, s, "Alice", "alice@example.com")
+	productID := createProduct(t, s, "Laptop", 999, 10)
+	id := createOrder(t, s, userID, productID, 1, 999)
+
+	_, err := s.OrderRepo().TransitionOrderStatus(ctx, id, models.OrderStatusPending, models.OrderStatusPaid)
+	requireNoError(t, err)
+
+	items := []models.OrderItem{orderItem(t, productID, 2, 999)}
+	requireErrorIs(t, s.OrderRepo().UpdateOrder(ctx, id, 1, &models.UpdatedOrder{Items: items, Total: 1998}), errs.ErrPreconditionFailed)
+	requireErrorIs(t, s.OrderRepo().DeleteOrder(ctx, id, 1), errs.ErrPreconditionFailed)
+
+	order, err := s.OrderRepo().GetOrderByID(ctx, id)
+	requireNoError(t, err)
+	if order.Total != 999 || len(order.Items) != 1 || order.Items[0].Quantity != 1 || order.Version != 2 {
+		t.Fatalf("got order %+v, want it untouched by the stale writes", order)
+	}
+}
+
+func testMissingWithVersion(t *testing.T, s storage.StorageI) {
+	// A missing row is reported as missing, not as a version mismatch
+	requireErrorIs(t, s.ProductRepo().UpdateProduct(ctx, missingID, 1, &models.ProductUpdate{Name: "x"}), errs.ErrNotFound)
+	requireErrorIs(t, s.ProductRepo().DeleteProduct(ctx, missingID, 1), errs.ErrNotFound)
+	requireErrorIs(t, s.OrderRepo().UpdateOrder(ctx, missingID, 1, &models.UpdatedOrder{}), errs.ErrNotFound)
+	requireErrorIs(t, s.OrderRepo().DeleteOrder(ctx, missingID, 1), errs.ErrNotFound)
+}
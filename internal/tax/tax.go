@@ -0,0 +1,148 @@
+// Package tax provides the tax rates orders are taxed with.
+//
+// Rates are read from a JSON file listing, for every region, whether its prices include the tax
+// and the rate in percent of every tax class:
+//
+//	{
+//	  "defaultRegion": "UZ",
+//	  "regions": {
+//	    "UZ": {"mode": "inclusive", "rates": {"standard": 12, "reduced": 0}},
+//	    "US-NY": {"mode": "exclusive", "rates": {"standard": 8.875, "reduced": 4}}
+//	  }
+//	}
+//
+// Regions are ISO 3166 country or subdivision codes. Every region lists the same tax classes, so any product
+// can be taxed anywhere, and the standard class products get by default is one of them.
+package tax
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+)
+
+// regionCode matches the form of an ISO 3166-1 country code or an ISO 3166-2 subdivision code
+var regionCode = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+// Region is how the orders of one region are taxed
+type Region struct {
+	Code  string
+	Mode  string // models.TaxInclusive or models.TaxExclusive
+	rates map[string]models.TaxRate
+}
+
+// Rate returns the rate of a tax class, an empty region taxes every class at 0%
+func (r Region) Rate(class string) (models.TaxRate, bool) {
+	if r.rates == nil {
+		return 0, true
+	}
+	rate, ok := r.rates[class]
+	return rate, ok
+}
+
+// Rates holds the regions orders can be taxed in
+type Rates struct {
+	defaultRegion string
+	regions       map[string]Region
+}
+
+// None taxes nothing: orders get the empty region, with prices exclusive of a 0% tax in any class
+func None() *Rates {
+	return &Rates{}
+}
+
+// LoadFile reads the rates of a JSON file in the format described in the package documentation
+func LoadFile(path string) (*Rates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tax rates: %w", err)
+	}
+
+	rates, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tax rates file %s: %w", path, err)
+	}
+	return rates, nil
+}
+
+// Parse reads rates in the JSON format described in the package documentation.
+// The rates are read as exact decimals, not as floats.
+func Parse(data []byte) (*Rates, error) {
+	var file struct {
+		DefaultRegion string `json:"defaultRegion"`
+		Regions       map[string]struct {
+			Mode  string                 `json:"mode"`
+			Rates map[string]json.Number `json:"rates"`
+		} `json:"regions"`
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+
+	if _, ok := file.Regions[file.DefaultRegion]; !ok {
+		return nil, fmt.Errorf("default region %q is not one of the regions", file.DefaultRegion)
+	}
+
+	// Every region is checked against the classes of the default one
+	classes := slices.Sorted(maps.Keys(file.Regions[file.DefaultRegion].Rates))
+	if !slices.Contains(classes, models.DefaultTaxClass) {
+		return nil, fmt.Errorf("region %s has no rate for the %s tax class", file.DefaultRegion, models.DefaultTaxClass)
+	}
+
+	regions := make(map[string]Region, len(file.Regions))
+	for code, region := range file.Regions {
+		if !regionCode.MatchString(code) {
+			return nil, fmt.Errorf("region %q is not an ISO 3166 code", code)
+		}
+		if region.Mode != models.TaxInclusive && region.Mode != models.TaxExclusive {
+			return nil, fmt.Errorf("mode %q of %s is neither %q nor %q", region.Mode, code, models.TaxInclusive, models.TaxExclusive)
+		}
+		if regionClasses := slices.Sorted(maps.Keys(region.Rates)); !slices.Equal(regionClasses, classes) {
+			return nil, fmt.Errorf("region %s has rates for %s, want %s like %s", code, strings.Join(regionClasses, ", "), strings.Join(classes, ", "), file.DefaultRegion)
+		}
+
+		rates := make(map[string]models.TaxRate, len(region.Rates))
+		for class, number := range region.Rates {
+			rate, err := models.ParseTaxRate(number.String())
+			if err != nil {
+				return nil, fmt.Errorf("rate %s of %s in %s: %w", number, class, code, err)
+			}
+			rates[class] = rate
+		}
+		regions[code] = Region{Code: code, Mode: region.Mode, rates: rates}
+	}
+
+	return &Rates{defaultRegion: file.DefaultRegion, regions: regions}, nil
+}
+
+// Region returns the region with the code, the default region when code is empty
+func (r *Rates) Region(code string) (Region, bool) {
+	if r.regions == nil {
+		return Region{Mode: models.TaxExclusive}, code == ""
+	}
+	if code == "" {
+		code = r.defaultRegion
+	}
+	region, ok := r.regions[code]
+	return region, ok
+}
+
+// HasClass reports whether products may be given a tax class, without rates any class will do
+func (r *Rates) HasClass(class string) bool {
+	if r.regions == nil {
+		return class != ""
+	}
+	_, ok := r.regions[r.defaultRegion].rates[class]
+	return ok
+}
@@ -0,0 +1,114 @@
+package tax
+
+import (
+	"testing"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+)
+
+const testRates = `{
+  "defaultRegion": "UZ",
+  "regions": {
+    "UZ": {"mode": "inclusive", "rates": {"standard": 12, "reduced": 0}},
+    "US-NY": {"mode": "exclusive", "rates": {"standard": 8.875, "reduced": 4}}
+  }
+}`
+
+func TestRegions(t *testing.T) {
+	rates, err := Parse([]byte(testRates))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		code, class string
+		wantRegion  string
+		wantMode    string
+		wantRate    string
+	}{
+		{"", "standard", "UZ", models.TaxInclusive, "12"}, // The default region
+		{"UZ", "reduced", "UZ", models.TaxInclusive, "0"},
+		{"US-NY", "standard", "US-NY", models.TaxExclusive, "8.875"},
+	}
+	for _, tt := range tests {
+		region, ok := rates.Region(tt.code)
+		if !ok {
+			t.Fatalf("region %q not found", tt.code)
+		}
+		rate, ok := region.Rate(tt.class)
+		if !ok {
+			t.Fatalf("%s has no rate for %s", region.Code, tt.class)
+		}
+		if region.Code != tt.wantRegion || region.Mode != tt.wantMode || rate.String() != tt.wantRate {
+			t.Errorf("%q %s: got %s %s %v%%, want %s %s %s%%", tt.code, tt.class, region.Code, region.Mode, rate, tt.wantRegion, tt.wantMode, tt.wantRate)
+		}
+	}
+
+	if _, ok := rates.Region("DE"); ok {
+		t.Error("found a region that isn't in the file")
+	}
+	if !rates.HasClass("reduced") || rates.HasClass("luxury") {
+		t.Error("got the wrong tax classes")
+	}
+}
+
+func TestNoneTaxesNothing(t *testing.T) {
+	region, ok := None().Region("")
+	if !ok {
+		t.Fatal("no default region")
+	}
+	if rate, ok := region.Rate("anything"); !ok || rate != 0 || region.Mode != models.TaxExclusive {
+		t.Fatalf("got %s %v%%, want exclusive 0%%", region.Mode, rate)
+	}
+	if _, ok := None().Region("UZ"); ok {
+		t.Fatal("found a region without rates")
+	}
+}
+
+func TestParseRejectsInvalidRates(t *testing.T) {
+	for _, data := range []string{
+		`{"defaultRegion": "DE", "regions": {"UZ": {"mode": "inclusive", "rates": {"standard": 12}}}}`,
+		`{"defaultRegion": "UZ", "regions": {"UZ": {"mode": "inclusive", "rates": {"reduced": 0}}}}`,
+		`{"defaultRegion": "UZ", "regions": {"UZ": {"mode": "included", "rates": {"standard": 12}}}}`,
+		`{"defaultRegion": "UZ", "regions": {"UZ": {"mode": "inclusive", "rates": {"standard": -12}}}}`,
+		`{"defaultRegion": "UZ", "regions": {"UZ": {"mode": "inclusive", "rates": {"standard": 12.00001}}}}`,
+		`{"defaultRegion": "UZ", "regions": {"UZ": {"mode": "inclusive", "rates": {"standard": 1e1}}}}`,
+		`{"defaultRegion": "uz", "regions": {"uz": {"mode": "inclusive", "rates": {"standard": 12}}}}`,
+		`{"defaultRegion": "UZ", "regions": {"UZ": {"mode": "inclusive", "rates": {"standard": 12}}, "KZ": {"mode": "inclusive", "rates": {"standard": 12, "reduced": 0}}}}`,
+		`{"defaultRegion": "UZ", "regions": {"UZ": {"mode": "inclusive", "rate": {"standard": 12}}}}`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("parsed %s", data)
+		}
+	}
+}
+
+func TestTaxAmounts(t *testing.T) {
+	tests := []struct {
+		rate         string
+		amount       models.Money
+		added, inner models.Money
+	}{
+		{"12", 11200, 1344, 1200},  // 112.00 holds 12.00 of tax at 12%
+		{"8.875", 10000, 888, 815}, // 8.875 rounds up, 10000 - 10000 / 1.08875 is 815.15
+		{"20", 999, 200, 167},      // 199.8 and 166.5 round half away from zero
+		{"0", 5000, 0, 0},
+	}
+	for _, tt := range tests {
+		rate, err := models.ParseTaxRate(tt.rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		added, err := rate.AddedTo(tt.amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inner, err := rate.IncludedIn(tt.amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != tt.added || inner != tt.inner {
+			t.Errorf("%s%% of %v: got %v added and %v included, want %v and %v", tt.rate, tt.amount, added, inner, tt.added, tt.inner)
+		}
+	}
+}
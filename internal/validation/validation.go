@@ -0,0 +1,117 @@
+// Package validation checks the request models against the rules declared in their validate struct tags.
+//
+// The same rules run in the HTTP handlers, so bad requests are turned away before any work is done,
+// and in the services, so every other caller is held to them too. A failed check is an errs.ErrValidation
+// error that lists each invalid field under its JSON name, see errs.FieldErrors.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/abdulazizax/udevslab-lesson3/internal/errs"
+	"github.com/abdulazizax/udevslab-lesson3/internal/models"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Fields are reported under the names clients send them with
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// notblank is required for strings that must hold more than whitespace
+	if err := v.RegisterValidation("notblank", validators.NotBlank); err != nil {
+		panic(err)
+	}
+
+	// currency only accepts the currencies amounts can be held in
+	if err := v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return models.IsSupportedCurrency(fl.Field().String())
+	}); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Struct checks v, a pointer to a request model, against the rules in its validate tags
+func Struct(v any) error {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	var failed validator.ValidationErrors
+	if !errors.As(err, &failed) {
+		return fmt.Errorf("failed to validate %T: %w", v, err)
+	}
+
+	fields := make([]errs.FieldError, len(failed))
+	for i, fe := range failed {
+		field := fieldPath(fe)
+		fields[i] = errs.FieldError{Field: field, Rule: fe.Tag(), Message: message(field, fe)}
+	}
+	return errs.InvalidFields(fields)
+}
+
+// fieldPath returns the path of the field below the validated struct, such as items[0].quantity
+func fieldPath(fe validator.FieldError) string {
+	_, path, _ := strings.Cut(fe.Namespace(), ".")
+	return path
+}
+
+// message describes the rule field broke in words fit for API clients
+func message(field string, fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required", "required_if", "notblank":
+		return field + " is required"
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, param)
+	case "gte":
+		if param == "0" {
+			return field + " must not be negative"
+		}
+		return fmt.Sprintf("%s must be at least %s", field, param)
+	case "lte":
+		return fmt.Sprintf("%s must be at most %s", field, param)
+	case "min":
+		return bound(field, "at least", param, fe.Kind())
+	case "max":
+		return bound(field, "at most", param, fe.Kind())
+	case "email":
+		return field + " must be a valid email address"
+	case "currency":
+		return field + " must be the ISO 4217 code of a supported currency"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field, strings.Join(strings.Fields(param), ", "))
+	default:
+		return fmt.Sprintf("%s breaks the %s rule", field, fe.Tag())
+	}
+}
+
+// bound phrases min and max, which limit the length of strings and collections but the value of numbers
+func bound(field, limit, param string, kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return fmt.Sprintf("%s must be %s %s characters long", field, limit, param)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if param == "1" {
+			return fmt.Sprintf("%s must contain %s 1 item", field, limit)
+		}
+		return fmt.Sprintf("%s must contain %s %s items", field, limit, param)
+	default:
+		return fmt.Sprintf("%s must be %s %s", field, limit, param)
+	}
+}